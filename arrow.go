@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"strconv"
+	"strings"
+)
+
+var arrowFile = flag.String("arrow", "", "Also write a Go file with the Apache Arrow schema of each table, for Parquet exports")
+
+// arrowImport is the Arrow package the schemas use.
+const arrowImport = "github.com/apache/arrow-go/v18/arrow"
+
+// arrowType returns the Go expression of a column's Arrow data type: integers
+// keep their size and sign, TINYINT(1) is boolean, DECIMAL a 128 or 256 bit
+// decimal of its precision and scale, DATETIME a timestamp in microseconds,
+// TIMESTAMP one in UTC, TIME a duration and blobs binary.
+func arrowType(cs ColumnSchema) string {
+	unsigned := strings.Contains(cs.ColumnType, "unsigned")
+	integer := func(bits string) string {
+		if unsigned {
+			return "arrow.PrimitiveTypes.Uint" + bits
+		}
+		return "arrow.PrimitiveTypes.Int" + bits
+	}
+	switch cs.DataType {
+	case "tinyint":
+		if cs.ColumnType == "tinyint(1)" {
+			return "arrow.FixedWidthTypes.Boolean"
+		}
+		return integer("8")
+	case "smallint", "year":
+		return integer("16")
+	case "mediumint", "int":
+		return integer("32")
+	case "bigint":
+		return integer("64")
+	case "float":
+		return "arrow.PrimitiveTypes.Float32"
+	case "double":
+		return "arrow.PrimitiveTypes.Float64"
+	case "decimal":
+		precision, scale := cs.NumericPrecision.Int64, cs.NumericScale.Int64
+		decimal := "arrow.Decimal128Type"
+		if precision > 38 {
+			decimal = "arrow.Decimal256Type"
+		}
+		return "&" + decimal + "{Precision: " + strconv.FormatInt(precision, 10) + ", Scale: " + strconv.FormatInt(scale, 10) + "}"
+	case "date":
+		return "arrow.FixedWidthTypes.Date32"
+	case "datetime":
+		return "&arrow.TimestampType{Unit: arrow.Microsecond}"
+	case "timestamp":
+		return "&arrow.TimestampType{Unit: arrow.Microsecond, TimeZone: \"UTC\"}"
+	case "time":
+		return "arrow.FixedWidthTypes.Duration_us"
+	}
+	if strings.Contains(cs.DataType, "blob") || strings.Contains(cs.DataType, "binary") || isSpatial(cs) {
+		return "arrow.BinaryTypes.Binary"
+	}
+	return "arrow.BinaryTypes.String"
+}
+
+// writeArrow writes a Go file, in the package of the structs, with a
+// XArrowSchema variable per table holding its Apache Arrow schema, fields
+// named after the columns, for jobs writing the tables to Parquet with the
+// Arrow Parquet writer.
+func writeArrow(path string, schemas []ColumnSchema) error {
+	buffer := bytes.NewBufferString(buildConstraint() + "package " + config.PkgName + "\n\n")
+	buffer.WriteString("import \"" + arrowImport + "\"\n")
+	for _, table := range groupTables(schemas) {
+		name := typeName(table.Name) + "ArrowSchema"
+		buffer.WriteString("\n// " + name + " is the Arrow schema of " + table.Name + ".\n")
+		buffer.WriteString("var " + name + " = arrow.NewSchema([]arrow.Field{\n")
+		for _, cs := range table.Columns {
+			buffer.WriteString("\t{Name: " + strconv.Quote(cs.ColumnName) + ", Type: " + arrowType(cs) + ", Nullable: " + strconv.FormatBool(cs.IsNullable == "YES") + "},\n")
+		}
+		buffer.WriteString("}, nil)\n")
+	}
+	return writeOutput(path, buffer.Bytes())
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var avroDir = flag.String("avro", "", "Also write an Avro schema (.avsc) per table to this directory")
+
+// avroName matches the names Avro takes for records, fields and enum
+// symbols.
+var avroName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// avroRecord is the Avro schema of a table's rows.
+type avroRecord struct {
+	Type      string      `json:"type"`
+	Name      string      `json:"name"`
+	Namespace string      `json:"namespace,omitempty"`
+	Fields    []avroField `json:"fields"`
+}
+
+type avroField struct {
+	Name string      `json:"name"`
+	Type interface{} `json:"type"`
+	Doc  string      `json:"doc,omitempty"`
+	// nullable fields default to null.
+	nullable bool
+}
+
+// MarshalJSON adds the null default of nullable fields.
+func (f avroField) MarshalJSON() ([]byte, error) {
+	type field avroField
+	if !f.nullable {
+		return json.Marshal(field(f))
+	}
+	return json.Marshal(struct {
+		field
+		Default *struct{} `json:"default"`
+	}{field: field(f)})
+}
+
+// avroLogical is a primitive type annotated with a logical type.
+type avroLogical struct {
+	Type        string `json:"type"`
+	LogicalType string `json:"logicalType"`
+	Precision   int64  `json:"precision,omitempty"`
+	Scale       int64  `json:"scale,omitempty"`
+}
+
+type avroEnum struct {
+	Type    string   `json:"type"`
+	Name    string   `json:"name"`
+	Symbols []string `json:"symbols"`
+}
+
+// plainIdent returns a name as an identifier of Avro, Thrift and the like,
+// its characters other than letters, digits and underscores replaced with
+// underscores.
+func plainIdent(column string) string {
+	if avroName.MatchString(column) {
+		return column
+	}
+	b := []byte(column)
+	for i, c := range b {
+		if !(c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || i > 0 && '0' <= c && c <= '9') {
+			b[i] = '_'
+		}
+	}
+	return string(b)
+}
+
+// avroType returns the Avro type of a column: DECIMAL ones are bytes with the
+// decimal logical type, temporal ones carry the date, time-micros or
+// timestamp-micros logical types and enums whose values are valid symbols
+// are Avro enums named after the struct and field. Nullable columns are
+// unions with null.
+func avroType(structName string, cs ColumnSchema) interface{} {
+	var t interface{}
+	switch cs.DataType {
+	case "tinyint", "smallint", "mediumint", "year":
+		t = "int"
+	case "int":
+		if strings.Contains(cs.ColumnType, "unsigned") {
+			t = "long"
+		} else {
+			t = "int"
+		}
+	case "bigint":
+		t = "long"
+	case "float":
+		t = "float"
+	case "double":
+		t = "double"
+	case "decimal":
+		t = avroLogical{"bytes", "decimal", cs.NumericPrecision.Int64, cs.NumericScale.Int64}
+	case "date":
+		t = avroLogical{Type: "int", LogicalType: "date"}
+	case "time":
+		t = avroLogical{Type: "long", LogicalType: "time-micros"}
+	case "datetime", "timestamp":
+		t = avroLogical{Type: "long", LogicalType: "timestamp-micros"}
+	case "enum":
+		symbols := enumValues(cs)
+		t = avroEnum{"enum", structName + fieldName(cs), symbols}
+		for _, symbol := range symbols {
+			if !avroName.MatchString(symbol) {
+				t = "string"
+			}
+		}
+	default:
+		if strings.Contains(cs.DataType, "blob") || strings.Contains(cs.DataType, "binary") || isSpatial(cs) {
+			t = "bytes"
+		} else {
+			t = "string"
+		}
+	}
+	if cs.IsNullable == "YES" {
+		return []interface{}{"null", t}
+	}
+	return t
+}
+
+// writeAvroSchemas writes an Avro record schema per table to dir, named after
+// the table, e.g. users.avsc, in the namespace of pkg_name.
+func writeAvroSchemas(dir string, schemas []ColumnSchema) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for _, table := range groupTables(schemas) {
+		structName := typeName(table.Name)
+		record := avroRecord{Type: "record", Name: plainIdent(structName), Namespace: config.PkgName}
+		for _, cs := range table.Columns {
+			record.Fields = append(record.Fields, avroField{
+				Name:     plainIdent(cs.ColumnName),
+				Type:     avroType(structName, cs),
+				Doc:      cs.ColumnComment,
+				nullable: cs.IsNullable == "YES",
+			})
+		}
+		data, err := json.MarshalIndent(record, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := writeOutput(filepath.Join(dir, table.Name+".avsc"), append(data, '\n')); err != nil {
+			return err
+		}
+	}
+	return nil
+}
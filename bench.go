@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"log/slog"
+	"strconv"
+	"strings"
+)
+
+var benchmark = flag.String("benchmark", "", "Also write a test file benchmarking the scan of rows into each struct: positional Scan, ScanXs with -scanners and sqlx with -sqlx")
+
+// benchValue returns a Go expression for a driver.Value the column of type
+// goType scans from as go-sql-driver/mysql would return it, and whether the
+// benchmarks know one.
+func benchValue(cs ColumnSchema, goType string) (string, bool) {
+	switch sqlType, _ := sqlNullType(goType); sqlType {
+	case "string", "[]byte", "int64", "float64", "time.Time", "interface{}",
+		"sql.NullString", "sql.NullInt64", "sql.NullFloat64", "sql.NullTime":
+	default:
+		return "", false
+	}
+	switch {
+	case integerBits[cs.DataType] > 0:
+		return "int64(1)", true
+	case cs.DataType == "float", cs.DataType == "double":
+		return "float64(1.5)", true
+	case cs.DataType == "decimal":
+		return "[]byte(\"1.5\")", true
+	case cs.DataType == "date", cs.DataType == "time", cs.DataType == "datetime", cs.DataType == "timestamp":
+		return "time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)", true
+	case cs.DataType == "enum" && len(enumValues(cs)) > 0:
+		return "[]byte(" + strconv.Quote(enumValues(cs)[0]) + ")", true
+	}
+	return "[]byte(" + strconv.Quote(cs.ColumnName) + ")", true
+}
+
+// writeBenchmarkDriver emits the database/sql driver the benchmarks read
+// from: every query returns benchRows copies of the row of its table, so
+// that they measure the scan alone.
+func writeBenchmarkDriver(buffer *bytes.Buffer) {
+	buffer.WriteString("// benchRows is the number of rows each benchmark iteration scans.\n")
+	buffer.WriteString("const benchRows = 100\n\n")
+
+	buffer.WriteString("// benchTable is the columns and the row a query of the benchmarks returns.\n")
+	buffer.WriteString("type benchTable struct {\n")
+	buffer.WriteString("\tcolumns []string\n")
+	buffer.WriteString("\tvalues  []driver.Value\n}\n\n")
+
+	buffer.WriteString("type benchDriver struct{}\n\n")
+	buffer.WriteString("func (benchDriver) Open(name string) (driver.Conn, error) {\n\treturn benchConn{}, nil\n}\n\n")
+
+	buffer.WriteString("type benchConn struct{}\n\n")
+	buffer.WriteString("func (benchConn) Prepare(query string) (driver.Stmt, error) {\n")
+	buffer.WriteString("\ttable, ok := benchTables[query]\n")
+	buffer.WriteString("\tif !ok {\n\t\treturn nil, errors.New(\"unknown benchmark query \" + query)\n\t}\n")
+	buffer.WriteString("\treturn benchStmt{table}, nil\n}\n\n")
+	buffer.WriteString("func (benchConn) Close() error {\n\treturn nil\n}\n\n")
+	buffer.WriteString("func (benchConn) Begin() (driver.Tx, error) {\n")
+	buffer.WriteString("\treturn nil, errors.New(\"the benchmark driver has no transactions\")\n}\n\n")
+
+	buffer.WriteString("type benchStmt struct {\n\ttable benchTable\n}\n\n")
+	buffer.WriteString("func (s benchStmt) Close() error {\n\treturn nil\n}\n\n")
+	buffer.WriteString("func (s benchStmt) NumInput() int {\n\treturn 0\n}\n\n")
+	buffer.WriteString("func (s benchStmt) Exec(args []driver.Value) (driver.Result, error) {\n")
+	buffer.WriteString("\treturn nil, errors.New(\"the benchmark driver only queries\")\n}\n\n")
+	buffer.WriteString("func (s benchStmt) Query(args []driver.Value) (driver.Rows, error) {\n")
+	buffer.WriteString("\treturn &benchResult{table: s.table}, nil\n}\n\n")
+
+	buffer.WriteString("type benchResult struct {\n")
+	buffer.WriteString("\ttable benchTable\n")
+	buffer.WriteString("\tn     int\n}\n\n")
+	buffer.WriteString("func (r *benchResult) Columns() []string {\n\treturn r.table.columns\n}\n\n")
+	buffer.WriteString("func (r *benchResult) Close() error {\n\treturn nil\n}\n\n")
+	buffer.WriteString("func (r *benchResult) Next(dest []driver.Value) error {\n")
+	buffer.WriteString("\tif r.n == benchRows {\n\t\treturn io.EOF\n\t}\n")
+	buffer.WriteString("\tr.n++\n")
+	buffer.WriteString("\tcopy(dest, r.table.values)\n")
+	buffer.WriteString("\treturn nil\n}\n\n")
+
+	buffer.WriteString("func init() {\n\tsql.Register(\"struct-create-bench\", benchDriver{})\n}\n\n")
+
+	buffer.WriteString("func openBench(b *testing.B) *sql.DB {\n")
+	buffer.WriteString("\tdb, err := sql.Open(\"struct-create-bench\", \"\")\n")
+	buffer.WriteString("\tif err != nil {\n\t\tb.Fatal(err)\n\t}\n")
+	buffer.WriteString("\tb.Cleanup(func() { db.Close() })\n")
+	buffer.WriteString("\treturn db\n}\n")
+}
+
+// writeBenchmark writes a test file with benchmarks scanning benchRows rows
+// into each struct on an in-memory driver: with rows.Scan on the fields, with
+// the ScanXs function of -scanners and with sqlx, by reflection, with -sqlx.
+// Tables with columns of types the driver can't produce are left out.
+func writeBenchmark(path string, schemas []ColumnSchema) error {
+	useSqlx := *sqlxHelpers && config.TagLabel == "db" && !*accessors
+	tables, body := &bytes.Buffer{}, &bytes.Buffer{}
+	usesTime := false
+
+	for _, table := range groupTables(schemas) {
+		structName := typeName(table.Name)
+		columns, values := []string{}, []string{}
+		skipped := ""
+		for _, cs := range table.Columns {
+			gt, _, err := goType(&cs)
+			if err != nil {
+				return err
+			}
+			value, ok := benchValue(cs, gt)
+			if !ok {
+				skipped = cs.ColumnName + " (" + gt + ")"
+				break
+			}
+			usesTime = usesTime || strings.HasPrefix(value, "time.")
+			columns = append(columns, strconv.Quote(cs.ColumnName))
+			values = append(values, value)
+		}
+		if skipped != "" {
+			slog.Warn("Not benchmarking a table with a column of a custom type", "table", table.Name, "column", skipped)
+			continue
+		}
+
+		query := strconv.Quote("SELECT " + selectList(table.Columns) + " FROM " + tableIdent(table.Name))
+		tables.WriteString("\t" + query + ": {\n")
+		tables.WriteString("\t\t[]string{" + strings.Join(columns, ", ") + "},\n")
+		tables.WriteString("\t\t[]driver.Value{" + strings.Join(values, ", ") + "},\n\t},\n")
+
+		name := "BenchmarkScan" + tableGoName(table.Name)
+		body.WriteString("\nfunc " + name + "Positional(b *testing.B) {\n")
+		body.WriteString("\tdb := openBench(b)\n")
+		body.WriteString("\tb.ReportAllocs()\n")
+		body.WriteString("\tfor i := 0; i < b.N; i++ {\n")
+		body.WriteString("\t\trows, err := db.Query(" + query + ")\n")
+		body.WriteString("\t\tif err != nil {\n\t\t\tb.Fatal(err)\n\t\t}\n")
+		body.WriteString("\t\tresult := []" + structName + "{}\n")
+		body.WriteString("\t\tfor rows.Next() {\n")
+		body.WriteString("\t\t\tvar row " + structName + "\n")
+		body.WriteString("\t\t\tif err := rows.Scan(" + fieldRefs("row", table.Columns, true) + "); err != nil {\n")
+		body.WriteString("\t\t\t\tb.Fatal(err)\n\t\t\t}\n")
+		body.WriteString("\t\t\tresult = append(result, row)\n")
+		body.WriteString("\t\t}\n")
+		body.WriteString("\t\tif err := rows.Close(); err != nil {\n\t\t\tb.Fatal(err)\n\t\t}\n")
+		body.WriteString("\t}\n}\n")
+
+		if *scanners {
+			body.WriteString("\nfunc " + name + "Scanner(b *testing.B) {\n")
+			body.WriteString("\tdb := openBench(b)\n")
+			body.WriteString("\tb.ReportAllocs()\n")
+			body.WriteString("\tfor i := 0; i < b.N; i++ {\n")
+			body.WriteString("\t\trows, err := db.Query(\"SELECT \" + " + structName + "Columns + \" FROM " + strings.Trim(strconv.Quote(tableIdent(table.Name)), `"`) + "\")\n")
+			body.WriteString("\t\tif err != nil {\n\t\t\tb.Fatal(err)\n\t\t}\n")
+			body.WriteString("\t\tif _, err := " + collectionName(funcName("Scan", structName)) + "(rows); err != nil {\n")
+			body.WriteString("\t\t\tb.Fatal(err)\n\t\t}\n")
+			body.WriteString("\t}\n}\n")
+		}
+
+		if useSqlx {
+			body.WriteString("\nfunc " + name + "Sqlx(b *testing.B) {\n")
+			body.WriteString("\tdb := sqlx.NewDb(openBench(b), \"mysql\")\n")
+			body.WriteString("\tb.ReportAllocs()\n")
+			body.WriteString("\tfor i := 0; i < b.N; i++ {\n")
+			body.WriteString("\t\tresult := []" + structName + "{}\n")
+			body.WriteString("\t\tif err := db.Select(&result, " + query + "); err != nil {\n")
+			body.WriteString("\t\t\tb.Fatal(err)\n\t\t}\n")
+			body.WriteString("\t}\n}\n")
+		}
+	}
+
+	buffer := bytes.NewBufferString(buildConstraint() + "package " + config.PkgName + "\n\n")
+	buffer.WriteString("import (\n\t\"database/sql\"\n\t\"database/sql/driver\"\n\t\"errors\"\n\t\"io\"\n\t\"testing\"\n")
+	if usesTime {
+		buffer.WriteString("\t\"time\"\n")
+	}
+	if useSqlx {
+		buffer.WriteString("\n\t\"github.com/jmoiron/sqlx\"\n")
+	}
+	buffer.WriteString(")\n\n")
+
+	writeBenchmarkDriver(buffer)
+	buffer.WriteString("\nvar benchTables = map[string]benchTable{\n")
+	buffer.Write(tables.Bytes())
+	buffer.WriteString("}\n")
+	buffer.Write(body.Bytes())
+
+	return writeOutput(path, buffer.Bytes())
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var cacheIntrospection = flag.Bool("cache", false, "Cache what is read of the schema between runs, reading it again only when the schema version or the settings changed")
+
+// schemaVersionQueries return a count and a checksum, or latest time, of
+// what changes with the schema: its columns, indexes, constraints, tables
+// and routines.
+var schemaVersionQueries = []string{
+	"SELECT COUNT(*), COALESCE(SUM(CRC32(CONCAT_WS('|', TABLE_NAME, COLUMN_NAME, COLUMN_TYPE, IS_NULLABLE, " +
+		"COALESCE(COLUMN_DEFAULT, ''), EXTRA, COLUMN_KEY, COLUMN_COMMENT))), 0) FROM COLUMNS WHERE TABLE_SCHEMA = ?",
+	"SELECT COUNT(*), COALESCE(SUM(CRC32(CONCAT_WS('|', TABLE_NAME, INDEX_NAME, COLUMN_NAME, SEQ_IN_INDEX, NON_UNIQUE))), 0) " +
+		"FROM STATISTICS WHERE TABLE_SCHEMA = ?",
+	"SELECT COUNT(*), COALESCE(SUM(CRC32(CONCAT_WS('|', TABLE_NAME, CONSTRAINT_NAME, CONSTRAINT_TYPE))), 0) " +
+		"FROM TABLE_CONSTRAINTS WHERE TABLE_SCHEMA = ?",
+	"SELECT COUNT(*), MAX(CREATE_TIME) FROM TABLES WHERE TABLE_SCHEMA = ?",
+	"SELECT COUNT(*), MAX(LAST_ALTERED) FROM ROUTINES WHERE ROUTINE_SCHEMA = ?",
+}
+
+// cachedIntrospection is a cache file: a schema and the version it was read
+// at.
+type cachedIntrospection struct {
+	Version string `json:"version"`
+	Schema  Schema `json:"schema"`
+}
+
+// cachedSchema returns the schema cached for the settings when its version
+// is still the one of the database, or else reads it and caches it. The
+// version is computed by the server from information_schema, which is much
+// cheaper than reading it all; privilege changes don't show in it. Failing
+// to read or write the cache only loses its benefit.
+func cachedSchema(ctx context.Context, conn *sql.DB, dbName string) (Schema, error) {
+	version, err := schemaVersion(ctx, conn, dbName)
+	if err != nil {
+		return Schema{}, classify(exitConnection, err)
+	}
+	path, err := introspectionCachePath(dbName)
+	if err != nil {
+		slog.Warn("Can't use the introspection cache", "error", err)
+		return querySchema(ctx, conn, dbName)
+	}
+
+	if data, err := os.ReadFile(path); err == nil {
+		var cached cachedIntrospection
+		if err := json.Unmarshal(data, &cached); err == nil && cached.Version == version {
+			slog.Debug("Using the cached schema", "schema", dbName, "file", path)
+			return cached.Schema, nil
+		}
+	}
+
+	schema, err := querySchema(ctx, conn, dbName)
+	if err != nil {
+		return schema, err
+	}
+	data, err := json.Marshal(cachedIntrospection{version, schema})
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(path), 0755)
+	}
+	if err == nil {
+		err = os.WriteFile(path, data, 0644)
+	}
+	if err != nil {
+		slog.Warn("Can't cache the schema", "error", err)
+	}
+	return schema, nil
+}
+
+// schemaVersion returns a version of the schema, changing with it.
+func schemaVersion(ctx context.Context, conn *sql.DB, dbName string) (string, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+	parts := []string{}
+	for _, q := range schemaVersionQueries {
+		var count, sum sql.NullString
+		if err := conn.QueryRowContext(ctx, q, dbName).Scan(&count, &sum); err != nil {
+			return "", err
+		}
+		parts = append(parts, count.String+":"+sum.String)
+	}
+	return strings.Join(parts, ","), nil
+}
+
+// introspectionCachePath returns the cache file of a schema, named after a
+// hash of the connection, the config and the flags, as any of them may
+// change what is read.
+func introspectionCachePath(dbName string) (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	settings, err := json.Marshal(config)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	h.Write([]byte(dbName + "\n"))
+	h.Write(settings)
+	flag.CommandLine.VisitAll(func(f *flag.Flag) {
+		h.Write([]byte("\n-" + f.Name + "=" + f.Value.String()))
+	})
+	return filepath.Join(dir, "struct-create", "introspection", hex.EncodeToString(h.Sum(nil))+".json"), nil
+}
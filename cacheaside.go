@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"strconv"
+	"strings"
+)
+
+var cached = flag.Bool("cached", false, "Generate a CachedX type per table reading rows by primary key through a pluggable Cache, invalidated by its Update and Delete (implies -crud)")
+
+// writeCache emits the Cache interface of the CachedX types and MemoryCache,
+// an in-process implementation.
+func writeCache(buffer *bytes.Buffer) {
+	buffer.WriteString("// Cache stores the rows read by the Cached types, encoded, by key: Redis,\n")
+	buffer.WriteString("// memcached or MemoryCache. Get reports whether the key was found. Failing\n")
+	buffer.WriteString("// to read or fill the cache only makes the Cached types read the database.\n")
+	buffer.WriteString("type Cache interface {\n")
+	buffer.WriteString("\tGet(ctx context.Context, key string) ([]byte, bool, error)\n")
+	buffer.WriteString("\tSet(ctx context.Context, key string, value []byte) error\n")
+	buffer.WriteString("\tDelete(ctx context.Context, key string) error\n}\n\n")
+
+	buffer.WriteString("// MemoryCache is a Cache keeping the rows in memory, without expiry.\n")
+	buffer.WriteString("type MemoryCache struct {\n")
+	buffer.WriteString("\tmu     sync.RWMutex\n")
+	buffer.WriteString("\tvalues map[string][]byte\n}\n\n")
+
+	buffer.WriteString("func NewMemoryCache() *MemoryCache {\n")
+	buffer.WriteString("\treturn &MemoryCache{values: map[string][]byte{}}\n}\n\n")
+
+	buffer.WriteString("func (m *MemoryCache) Get(ctx context.Context, key string) ([]byte, bool, error) {\n")
+	buffer.WriteString("\tm.mu.RLock()\n")
+	buffer.WriteString("\tdefer m.mu.RUnlock()\n")
+	buffer.WriteString("\tvalue, ok := m.values[key]\n")
+	buffer.WriteString("\treturn value, ok, nil\n}\n\n")
+
+	buffer.WriteString("func (m *MemoryCache) Set(ctx context.Context, key string, value []byte) error {\n")
+	buffer.WriteString("\tm.mu.Lock()\n")
+	buffer.WriteString("\tdefer m.mu.Unlock()\n")
+	buffer.WriteString("\tm.values[key] = value\n")
+	buffer.WriteString("\treturn nil\n}\n\n")
+
+	buffer.WriteString("func (m *MemoryCache) Delete(ctx context.Context, key string) error {\n")
+	buffer.WriteString("\tm.mu.Lock()\n")
+	buffer.WriteString("\tdefer m.mu.Unlock()\n")
+	buffer.WriteString("\tdelete(m.values, key)\n")
+	buffer.WriteString("\treturn nil\n}")
+}
+
+// cachedLocals are the variables of the CachedX methods, which column
+// parameters get an Arg suffix to not shadow.
+var cachedLocals = map[string]bool{"c": true, "cacheKey": true, "cached": true, "encoded": true, "row": true, "found": true}
+
+// writeCachedTable emits CachedX, running the CRUD functions of a table with
+// a primary key on a database handle and a Cache: GetByID, or GetByKey for
+// composite keys, reads the row gob-encoded under "table:key" in the cache
+// before the database, filling the cache on a miss, and Update and Delete
+// remove it from the cache once they succeeded.
+func writeCachedTable(buffer *bytes.Buffer, structName string, table Table) {
+	cachedName := funcName("Cached", structName)
+	keyFunc := unexport(cachedName) + "Key"
+	pk := primaryKey(table)
+
+	params, args, verbs := []string{}, []string{}, []string{}
+	for _, cs := range pk {
+		gt, _, err := goType(&cs)
+		if err != nil {
+			return
+		}
+		param := paramName(cs)
+		if cachedLocals[param] {
+			param += "Arg"
+		}
+		params = append(params, param+" "+gt)
+		args = append(args, param)
+		verbs = append(verbs, "%#v")
+	}
+
+	// The methods take the key as the Get and Delete functions do.
+	get, keyParam, keyArgs, cacheKeyArgs := funcName("Get", structName)+"ByKey", "key "+structName+"Key", "key", fieldRefs("key", pk, false)
+	if len(pk) == 1 {
+		get, keyParam, keyArgs, cacheKeyArgs = funcName("Get", structName)+"ByID", params[0], args[0], args[0]
+	}
+	method := strings.TrimPrefix(get, funcName("Get", structName))
+
+	buffer.WriteString("// " + cachedName + " runs the " + table.Name + " data access functions on DB, reading\n")
+	buffer.WriteString("// " + get + " through Cache and removing the rows Update and Delete change\n")
+	buffer.WriteString("// from it.\n")
+	buffer.WriteString("type " + cachedName + " struct {\n")
+	buffer.WriteString("\tDB    " + strings.TrimPrefix(dbParam(), "db ") + "\n")
+	buffer.WriteString("\tCache Cache\n}\n\n")
+
+	buffer.WriteString("func " + keyFunc + "(" + strings.Join(params, ", ") + ") string {\n")
+	buffer.WriteString("\treturn fmt.Sprintf(" + strconv.Quote(table.Name+":"+strings.Join(verbs, ":")) + ", " + strings.Join(args, ", ") + ")\n}\n\n")
+
+	buffer.WriteString("func (c " + cachedName + ") Get" + method + "(ctx context.Context, " + keyParam + ") (*" + structName + ", error) {\n")
+	buffer.WriteString("\tcacheKey := " + keyFunc + "(" + cacheKeyArgs + ")\n")
+	buffer.WriteString("\tif cached, found, err := c.Cache.Get(ctx, cacheKey); err == nil && found {\n")
+	buffer.WriteString("\t\tvar row " + structName + "\n")
+	buffer.WriteString("\t\tif gob.NewDecoder(bytes.NewReader(cached)).Decode(&row) == nil {\n")
+	buffer.WriteString("\t\t\treturn &row, nil\n\t\t}\n\t}\n")
+	buffer.WriteString("\trow, err := " + get + "(ctx, c.DB, " + keyArgs + ")\n")
+	buffer.WriteString("\tif err != nil {\n\t\treturn nil, err\n\t}\n")
+	buffer.WriteString("\tvar encoded bytes.Buffer\n")
+	buffer.WriteString("\tif gob.NewEncoder(&encoded).Encode(row) == nil {\n")
+	buffer.WriteString("\t\tc.Cache.Set(ctx, cacheKey, encoded.Bytes())\n\t}\n")
+	buffer.WriteString("\treturn row, nil\n}\n\n")
+
+	buffer.WriteString("func (c " + cachedName + ") Insert(ctx context.Context, row *" + structName + ") error {\n")
+	buffer.WriteString("\treturn " + funcName("Insert", structName) + "(ctx, c.DB, row)\n}")
+
+	if len(updateColumns(table)) > 0 {
+		buffer.WriteString("\n\nfunc (c " + cachedName + ") Update(ctx context.Context, row *" + structName + ") error {\n")
+		buffer.WriteString("\tif err := " + funcName("Update", structName) + "(ctx, c.DB, row); err != nil {\n")
+		buffer.WriteString("\t\treturn err\n\t}\n")
+		buffer.WriteString("\treturn c.Cache.Delete(ctx, " + keyFunc + "(" + fieldRefs("row", pk, false) + "))\n}")
+	}
+
+	buffer.WriteString("\n\nfunc (c " + cachedName + ") Delete(ctx context.Context, " + keyParam + ") error {\n")
+	buffer.WriteString("\tif err := " + funcName("Delete", structName) + "(ctx, c.DB, " + keyArgs + "); err != nil {\n")
+	buffer.WriteString("\t\treturn err\n\t}\n")
+	buffer.WriteString("\treturn c.Cache.Delete(ctx, " + keyFunc + "(" + cacheKeyArgs + "))\n}")
+}
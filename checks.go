@@ -0,0 +1,430 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Check is a CHECK constraint of a table.
+type Check struct {
+	TableName      string
+	ConstraintName string
+	Clause         string
+}
+
+func getChecks(ctx context.Context, conn *sql.DB, dbName string) ([]Check, error) {
+	q := "SELECT t.TABLE_NAME, c.CONSTRAINT_NAME, c.CHECK_CLAUSE FROM CHECK_CONSTRAINTS c " +
+		"JOIN TABLE_CONSTRAINTS t ON t.CONSTRAINT_SCHEMA = c.CONSTRAINT_SCHEMA " +
+		"AND t.CONSTRAINT_NAME = c.CONSTRAINT_NAME AND t.CONSTRAINT_TYPE = 'CHECK' " +
+		"WHERE c.CONSTRAINT_SCHEMA = ? ORDER BY t.TABLE_NAME, c.CONSTRAINT_NAME"
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+	rows, err := conn.QueryContext(ctx, q, dbName)
+	if err != nil {
+		return nil, err
+	}
+	checks := []Check{}
+	for rows.Next() {
+		c := Check{}
+		if err := rows.Scan(&c.TableName, &c.ConstraintName, &c.Clause); err != nil {
+			return nil, err
+		}
+		checks = append(checks, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return checks, nil
+}
+
+// checkToken is a token of a CHECK clause: an identifier, a number, a string,
+// a keyword or an operator.
+type checkToken struct {
+	kind  string // ident, number, string, word or op
+	value string
+}
+
+// tokenizeCheck splits a CHECK clause as reported by information_schema,
+// e.g. "((`score` >= 0) and (`status` in (_utf8mb4\'a\',_utf8mb4\'b\')))".
+func tokenizeCheck(clause string) ([]checkToken, bool) {
+	tokens := []checkToken{}
+	for i := 0; i < len(clause); {
+		c := clause[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+		case c == '`':
+			end := i + 1
+			name := ""
+			for {
+				next := strings.IndexByte(clause[end:], '`')
+				if next < 0 {
+					return nil, false
+				}
+				name += clause[end : end+next]
+				end += next + 1
+				if end < len(clause) && clause[end] == '`' {
+					name += "`"
+					end++
+					continue
+				}
+				break
+			}
+			tokens = append(tokens, checkToken{"ident", name})
+			i = end
+		case c == '\'' || c == '\\' && i+1 < len(clause) && clause[i+1] == '\'':
+			if c == '\\' {
+				i++
+			}
+			i++
+			value := ""
+			for {
+				if i >= len(clause) {
+					return nil, false
+				}
+				if clause[i] == '\\' && i+1 < len(clause) && clause[i+1] == '\'' {
+					i += 2
+					break
+				}
+				if clause[i] == '\'' {
+					if i+1 < len(clause) && clause[i+1] == '\'' {
+						value += "'"
+						i += 2
+						continue
+					}
+					i++
+					break
+				}
+				value += string(clause[i])
+				i++
+			}
+			tokens = append(tokens, checkToken{"string", value})
+		case c >= '0' && c <= '9' || c == '.':
+			end := i
+			for end < len(clause) && (clause[end] >= '0' && clause[end] <= '9' || strings.IndexByte(".eE", clause[end]) >= 0 ||
+				(clause[end] == '-' || clause[end] == '+') && strings.IndexByte("eE", clause[end-1]) >= 0) {
+				end++
+			}
+			if _, err := strconv.ParseFloat(clause[i:end], 64); err != nil {
+				return nil, false
+			}
+			tokens = append(tokens, checkToken{"number", clause[i:end]})
+			i = end
+		case c == '_' || unicode.IsLetter(rune(c)):
+			end := i
+			for end < len(clause) && (clause[end] == '_' || unicode.IsLetter(rune(clause[end])) || unicode.IsDigit(rune(clause[end]))) {
+				end++
+			}
+			// A charset introducer such as _utf8mb4 only qualifies the string after it.
+			if c != '_' || end >= len(clause) || (clause[end] != '\'' && clause[end] != '\\') {
+				tokens = append(tokens, checkToken{"word", clause[i:end]})
+			}
+			i = end
+		default:
+			op := string(c)
+			if i+1 < len(clause) {
+				switch clause[i : i+2] {
+				case ">=", "<=", "<>", "!=":
+					op = clause[i : i+2]
+				}
+			}
+			if strings.Index("()<>=,-", op) < 0 && len(op) == 1 {
+				return nil, false
+			}
+			tokens = append(tokens, checkToken{"op", op})
+			i += len(op)
+		}
+	}
+	return tokens, true
+}
+
+// checkCondition is a simple condition of a CHECK clause on one column:
+// a comparison against a literal, a BETWEEN range or an IN list.
+type checkCondition struct {
+	column   string
+	operator string // a comparison operator, "between" or "in"
+	values   []checkToken
+}
+
+// checkKeywords are the words that can't be unquoted column names in the
+// clauses parseCheck understands.
+var checkKeywords = map[string]bool{"and": true, "or": true, "not": true, "between": true, "in": true, "is": true, "null": true}
+
+// isColumn reports whether t names a column, quoted or not as some servers
+// report them.
+func (t checkToken) isColumn() bool {
+	return t.kind == "ident" || t.kind == "word" && !checkKeywords[strings.ToLower(t.value)]
+}
+
+// checkParser parses the conjunctions of simple conditions a CHECK clause is
+// usually made of. Anything else, e.g. OR or function calls, fails the parse.
+type checkParser struct {
+	tokens []checkToken
+	pos    int
+}
+
+func (p *checkParser) peek() checkToken {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return checkToken{}
+}
+
+func (p *checkParser) next() checkToken {
+	t := p.peek()
+	p.pos++
+	return t
+}
+
+func (p *checkParser) accept(kind, value string) bool {
+	if t := p.peek(); t.kind == kind && strings.EqualFold(t.value, value) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *checkParser) literal() (checkToken, bool) {
+	if p.accept("op", "-") {
+		parens := p.accept("op", "(")
+		t := p.next()
+		if t.kind != "number" || parens && !p.accept("op", ")") {
+			return t, false
+		}
+		t.value = "-" + t.value
+		return t, true
+	}
+	t := p.next()
+	return t, t.kind == "number" || t.kind == "string"
+}
+
+func (p *checkParser) conjunction() ([]checkCondition, bool) {
+	conditions := []checkCondition{}
+	for {
+		parsed, ok := p.term()
+		if !ok {
+			return nil, false
+		}
+		conditions = append(conditions, parsed...)
+		if !p.accept("word", "and") {
+			return conditions, true
+		}
+	}
+}
+
+func (p *checkParser) term() ([]checkCondition, bool) {
+	if p.accept("op", "(") {
+		conditions, ok := p.conjunction()
+		return conditions, ok && p.accept("op", ")")
+	}
+
+	flipped := map[string]string{">": "<", ">=": "<=", "<": ">", "<=": ">=", "=": "=", "<>": "<>", "!=": "!="}
+	if !p.peek().isColumn() {
+		value, ok := p.literal()
+		if !ok {
+			return nil, false
+		}
+		op, column := p.next(), p.next()
+		if _, comparison := flipped[op.value]; op.kind != "op" || !comparison || !column.isColumn() {
+			return nil, false
+		}
+		return []checkCondition{{column.value, flipped[op.value], []checkToken{value}}}, true
+	}
+
+	column := p.next().value
+	switch t := p.next(); {
+	case t.kind == "op" && flipped[t.value] != "":
+		value, ok := p.literal()
+		return []checkCondition{{column, t.value, []checkToken{value}}}, ok
+	case t.kind == "word" && strings.EqualFold(t.value, "between"):
+		low, ok := p.literal()
+		if !ok || !p.accept("word", "and") {
+			return nil, false
+		}
+		high, ok := p.literal()
+		return []checkCondition{{column, "between", []checkToken{low, high}}}, ok
+	case t.kind == "word" && strings.EqualFold(t.value, "in"):
+		if !p.accept("op", "(") {
+			return nil, false
+		}
+		values := []checkToken{}
+		for {
+			value, ok := p.literal()
+			if !ok {
+				return nil, false
+			}
+			values = append(values, value)
+			if p.accept("op", ")") {
+				return []checkCondition{{column, "in", values}}, true
+			}
+			if !p.accept("op", ",") {
+				return nil, false
+			}
+		}
+	}
+	return nil, false
+}
+
+// parseCheck parses a CHECK clause made of comparisons, BETWEEN ranges and IN
+// lists joined by AND.
+func parseCheck(clause string) ([]checkCondition, bool) {
+	tokens, ok := tokenizeCheck(clause)
+	if !ok {
+		return nil, false
+	}
+	p := &checkParser{tokens: tokens}
+	conditions, ok := p.conjunction()
+	return conditions, ok && p.pos == len(tokens)
+}
+
+// checkOperand returns the expressions reading the value of a field of type
+// goType and reporting whether it is set (empty if never NULL), and whether
+// the value is an "int", a "float" or a "string".
+func checkOperand(expr, goType string) (string, string, string, bool) {
+	goType, _ = sqlNullType(goType)
+	switch goType {
+	case "int64":
+		return expr, "", "int", true
+	case "float64":
+		return expr, "", "float", true
+	case "string":
+		return expr, "", "string", true
+	case "sql.NullInt64":
+		return expr + ".Int64", expr + ".Valid", "int", true
+	case "sql.NullFloat64":
+		return expr + ".Float64", expr + ".Valid", "float", true
+	case "sql.NullString":
+		return expr + ".String", expr + ".Valid", "string", true
+	}
+	return "", "", "", false
+}
+
+// checkViolation returns the Go expression reporting whether the field
+// violates the condition. A NULL never violates a CHECK constraint.
+func checkViolation(condition checkCondition, expr, goType string) (string, bool) {
+	value, valid, kind, ok := checkOperand(expr, goType)
+	if !ok {
+		return "", false
+	}
+
+	literals := []string{}
+	for _, v := range condition.values {
+		switch {
+		case v.kind == "string" && kind == "string":
+			literals = append(literals, strconv.Quote(v.value))
+		case v.kind == "number" && kind != "string":
+			if kind == "int" && strings.ContainsAny(v.value, ".eE") {
+				value = "float64(" + value + ")"
+				kind = "float"
+			}
+			literals = append(literals, v.value)
+		default:
+			return "", false
+		}
+	}
+
+	negated := map[string]string{">": "<=", ">=": "<", "<": ">=", "<=": ">", "=": "!=", "<>": "==", "!=": "=="}
+	var violation string
+	switch condition.operator {
+	case "between":
+		violation = value + " < " + literals[0] + " || " + value + " > " + literals[1]
+	case "in":
+		differs := []string{}
+		for _, literal := range literals {
+			differs = append(differs, value+" != "+literal)
+		}
+		violation = strings.Join(differs, " && ")
+	default:
+		violation = value + " " + negated[condition.operator] + " " + literals[0]
+	}
+	if valid != "" {
+		if strings.Contains(violation, "||") {
+			violation = "(" + violation + ")"
+		}
+		violation = valid + " && " + violation
+	}
+	return violation, true
+}
+
+// writeValidate emits a Validate() method returning an error for the first
+// column definition (with -validate) or CHECK constraint (with -checks) of
+// the table the row violates. Only constraints made of comparisons, BETWEEN
+// ranges and IN lists on number and string columns are enforced; the others
+// are listed in the method comment.
+func writeValidate(buffer *bytes.Buffer, structName string, table Table, constraints []Check, neededImports map[string]bool) {
+	r := receiverName(structName)
+	var body bytes.Buffer
+	skipped := []Check{}
+
+	for _, cs := range table.Columns {
+		gt, _, err := goType(&cs)
+		if err != nil || !*validate {
+			continue
+		}
+		for _, check := range columnChecks(cs, r+"."+fieldName(cs), gt) {
+			if strings.Contains(check.violation, "utf8.") {
+				neededImports["unicode/utf8"] = true
+			}
+			body.WriteString("\tif " + check.violation + " {\n")
+			body.WriteString("\t\treturn errors.New(" + strconv.Quote(check.message) + ")\n")
+			body.WriteString("\t}\n")
+		}
+	}
+
+	for _, check := range constraints {
+		if check.TableName != table.Name || !*checks {
+			continue
+		}
+		conditions, ok := parseCheck(check.Clause)
+		violations := []string{}
+		for _, condition := range conditions {
+			var violation string
+			for _, cs := range table.Columns {
+				gt, _, err := goType(&cs)
+				if cs.ColumnName == condition.column && err == nil {
+					violation, _ = checkViolation(condition, r+"."+fieldName(cs), gt)
+				}
+			}
+			if violation == "" {
+				ok = false
+			}
+			violations = append(violations, violation)
+		}
+		if !ok {
+			skipped = append(skipped, check)
+			continue
+		}
+
+		message := strconv.Quote(table.Name + ": check constraint " + check.ConstraintName + " violated: " + check.Clause)
+		for _, violation := range violations {
+			body.WriteString("\tif " + violation + " {\n")
+			body.WriteString("\t\treturn errors.New(" + message + ")\n")
+			body.WriteString("\t}\n")
+		}
+	}
+	if body.Len() > 0 {
+		neededImports["errors"] = true
+	}
+
+	switch {
+	case *validate && *checks:
+		buffer.WriteString("// Validate checks the row against the column definitions and CHECK constraints of the " + table.Name + " table.\n")
+	case *validate:
+		buffer.WriteString("// Validate checks the row against the column definitions of the " + table.Name + " table.\n")
+	default:
+		buffer.WriteString("// Validate checks the row against the CHECK constraints of the " + table.Name + " table.\n")
+	}
+	if len(skipped) > 0 {
+		buffer.WriteString("// Constraints too complex to check here:\n")
+		for _, check := range skipped {
+			buffer.WriteString("//   - " + check.ConstraintName + ": " + check.Clause + "\n")
+		}
+	}
+	buffer.WriteString("func (" + r + " " + structName + ") Validate() error {\n")
+	body.WriteTo(buffer)
+	buffer.WriteString("\treturn nil\n}")
+}
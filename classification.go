@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+var classificationFile = flag.String("classification", "", "Also write a JSON manifest of the classified columns (pii, secret...) to this file, for compliance tooling")
+
+// classifiedColumn is an entry of the -classification manifest.
+type classifiedColumn struct {
+	Table          string `json:"table"`
+	Column         string `json:"column"`
+	Struct         string `json:"struct"`
+	Field          string `json:"field"`
+	Type           string `json:"type"`
+	Classification string `json:"classification"`
+	Source         string `json:"source"`
+}
+
+// columnClassification returns the classification of a column and where it
+// comes from: classified_columns by "table.column" then by "column",
+// sensitive_columns classifying them "sensitive", or the word following the
+// classification_marker in the column comment, in lower case.
+func columnClassification(cs ColumnSchema) (string, string) {
+	if class, ok := config.ClassifiedColumns[cs.TableName+"."+cs.ColumnName]; ok {
+		return class, "classified_columns"
+	}
+	if class, ok := config.ClassifiedColumns[cs.ColumnName]; ok {
+		return class, "classified_columns"
+	}
+	for _, name := range config.SensitiveColumns {
+		if name == cs.ColumnName || name == cs.TableName+"."+cs.ColumnName {
+			return "sensitive", "sensitive_columns"
+		}
+	}
+	if i := strings.Index(cs.ColumnComment, config.ClassificationMarker); config.ClassificationMarker != "" && i >= 0 {
+		word := strings.FieldsFunc(cs.ColumnComment[i+len(config.ClassificationMarker):], func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '-'
+		})
+		if len(word) > 0 {
+			return strings.ToLower(word[0]), "comment"
+		}
+	}
+	return "", ""
+}
+
+// writeClassification writes the classified columns to path, sorted by table
+// and column.
+func writeClassification(path string, schemas []ColumnSchema) error {
+	classified := []classifiedColumn{}
+	for _, cs := range schemas {
+		class, source := columnClassification(cs)
+		if class == "" {
+			continue
+		}
+		classified = append(classified, classifiedColumn{cs.TableName, cs.ColumnName, typeName(cs.TableName), fieldName(cs), cs.ColumnType, class, source})
+	}
+	sort.SliceStable(classified, func(i, j int) bool {
+		if classified[i].Table != classified[j].Table {
+			return classified[i].Table < classified[j].Table
+		}
+		return classified[i].Column < classified[j].Column
+	})
+	data, err := json.MarshalIndent(map[string]interface{}{"schema": config.DbName, "columns": classified}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeOutput(path, append(data, '\n'))
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"runtime/debug"
+)
+
+// version is set at build time with -ldflags "-X main.version=v1.2.3".
+var version = "dev"
+
+// The flags overriding the config file, taken by every command reading it.
+var (
+	configFile   = new(string)
+	configAny    = new(string)
+	hostFlag     = new(string)
+	portFlag     = new(int)
+	userFlag     = new(string)
+	passwordFlag = new(string)
+	dbFlag       = new(string)
+	pkgFlag      = new(string)
+	dsnFlag      = new(string)
+	tagFlag      = new(string)
+	tablesFlag   = new(string)
+)
+
+// commandFlags are the flags of the running command, the ones of the flag
+// package for generate.
+var commandFlags = flag.CommandLine
+
+func init() {
+	addSettingsFlags(flag.CommandLine)
+	addLogFlags(flag.CommandLine)
+	flag.Usage = usage
+}
+
+// addSettingsFlags registers the config file and the flags overriding it.
+func addSettingsFlags(flags *flag.FlagSet) {
+	flags.StringVar(configFile, "json", "", "Config file")
+	flags.StringVar(configAny, "config", "", "Config file in JSON, YAML (.yaml, .yml) or TOML (.toml)")
+	flags.StringVar(hostFlag, "host", "", "Database host, overriding the config file")
+	flags.IntVar(portFlag, "port", 0, "Database port, overriding the config file")
+	flags.StringVar(userFlag, "user", "", "Database user, overriding the config file")
+	flags.StringVar(passwordFlag, "password", "", "Database password, overriding the config file")
+	flags.StringVar(dbFlag, "db", "", "Database name, overriding the config file")
+	flags.StringVar(pkgFlag, "pkg", "", "Package name of the generated code, overriding the config file")
+	flags.StringVar(dsnFlag, "dsn", "", "MySQL data source name, used instead of the connection settings")
+	flags.StringVar(tagFlag, "tag", "", "Struct tag label, overriding the config file")
+	flags.StringVar(tablesFlag, "tables", "", "Comma-separated tables, or glob patterns, to generate, overriding the config file")
+}
+
+func usage() {
+	fmt.Fprint(flag.CommandLine.Output(), `Usage: struct-create [command] [flags]
+
+Commands:
+  generate     Generate the structs (the default command)
+  check        Fail with a diff when the outputs differ from their files
+  diff         Report how the schema differs from a snapshot or structs
+  reverse      Write CREATE TABLE statements for the structs of Go files
+  select       Pick the tables and columns to generate on the terminal
+  serve        Preview the structs for the options picked on a local web page
+  regenerate   Run generate with the //go:generate line of a Go file
+  dump-schema  Write the introspected schema as JSON
+  init         Write a commented sample config
+  completion   Print the bash, zsh or fish completion script
+  version      Print the version
+  help         Print this help
+
+Run struct-create <command> -h for the flags of a command. The flags of
+generate are:
+`)
+	flag.PrintDefaults()
+}
+
+// flagSet reports whether the flag was given on the command line.
+func flagSet(name string) bool {
+	set := false
+	commandFlags.Visit(func(f *flag.Flag) {
+		set = set || f.Name == name
+	})
+	return set
+}
+
+// readConfig loads the config file given by -json or -config, or the
+// defaults without one.
+func readConfig() error {
+	switch {
+	case len(*configFile) > 0:
+		return loadConfig(*configFile)
+	case len(*configAny) > 0:
+		return loadConfig(*configAny)
+	default:
+		config = defaults
+		return nil
+	}
+}
+
+// applySettings overrides the configuration with the option file, the
+// environment and the flags, in increasing order of precedence.
+func applySettings() error {
+	if err := applyOptionFile(len(*configFile) > 0 || len(*configAny) > 0); err != nil {
+		return err
+	}
+	if err := applyEnv(); err != nil {
+		return err
+	}
+	applyFlags()
+	return nil
+}
+
+// versionCommand implements the version command, printing the version set at
+// build time or else the one of the module when installed with go install.
+func versionCommand() {
+	v := version
+	if info, ok := debug.ReadBuildInfo(); ok && v == "dev" && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		v = info.Main.Version
+	}
+	fmt.Println("struct-create " + v)
+}
+
+// dumpOut is the -out flag of dump-schema.
+var dumpOut = new(string)
+
+// dumpSchemaFlags returns the flags of the dump-schema command.
+func dumpSchemaFlags() *flag.FlagSet {
+	flags := flag.NewFlagSet("dump-schema", flag.ExitOnError)
+	addSettingsFlags(flags)
+	addLogFlags(flags)
+	flags.StringVar(dumpOut, "out", "-", "Output, \"-\" for stdout")
+	return flags
+}
+
+// dumpSchemaCommand implements the dump-schema command, writing everything
+// read from information_schema for the tables to generate as JSON.
+func dumpSchemaCommand(ctx context.Context, args []string) error {
+	flags := dumpSchemaFlags()
+	flags.Parse(args)
+	commandFlags = flags
+	if err := setupLogging(); err != nil {
+		return err
+	}
+
+	if err := readConfig(); err != nil {
+		return err
+	}
+	if err := applySettings(); err != nil {
+		return err
+	}
+	if err := checkPatterns(); err != nil {
+		return err
+	}
+	conn, err := connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	schema, err := readSchema(ctx, conn)
+	if err != nil {
+		return classify(exitConnection, err)
+	}
+	schema = filterColumns(filterTables(schema))
+
+	data, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	cacheSchema(data)
+	if *dumpOut == "-" {
+		_, err := os.Stdout.Write(data)
+		return classify(exitWrite, err)
+	}
+	return classify(exitWrite, os.WriteFile(*dumpOut, data, 0644))
+}
+
+// readSchema reads everything information_schema has on the schema, for
+// dump-schema.
+func readSchema(ctx context.Context, conn *sql.DB) (Schema, error) {
+	var schema Schema
+	var err error
+	if schema.Columns, err = getColumns(ctx, conn, config.DbName); err != nil {
+		return schema, err
+	}
+	if err = getSRIDs(ctx, conn, config.DbName, schema.Columns); err != nil {
+		return schema, err
+	}
+	schema.ForeignKeys, err = getForeignKeys(ctx, conn, config.DbName)
+	if err := optionalMetadata("foreign keys", err); err != nil {
+		return schema, err
+	}
+	schema.Indexes, err = getIndexes(ctx, conn, config.DbName)
+	if err := optionalMetadata("indexes", err); err != nil {
+		return schema, err
+	}
+	schema.Routines, err = getRoutines(ctx, conn, config.DbName)
+	if err := optionalMetadata("routines", err); err != nil {
+		return schema, err
+	}
+	schema.Checks, err = getChecks(ctx, conn, config.DbName)
+	if err := optionalMetadata("CHECK constraints", err); err != nil {
+		return schema, err
+	}
+	schema.Partitions, err = getPartitions(ctx, conn, config.DbName)
+	if err := optionalMetadata("partitions", err); err != nil {
+		return schema, err
+	}
+	schema.Stats, err = getTableStats(ctx, conn, config.DbName)
+	if err := optionalMetadata("table statistics", err); err != nil {
+		return schema, err
+	}
+	schema.Versioned, err = getVersionedTables(ctx, conn, config.DbName)
+	return schema, optionalMetadata("system-versioned tables", err)
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+	"testing"
+)
+
+// TestMain runs the command instead of the tests when runCommand re-executes
+// the test binary, so that every run starts from fresh flags and globals.
+func TestMain(m *testing.M) {
+	if os.Getenv("STRUCT_CREATE_MAIN") == "1" {
+		os.Args = append([]string{"struct-create"}, os.Args[1:]...)
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// runCommand runs struct-create with args in dir, failing with its output.
+func runCommand(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "STRUCT_CREATE_MAIN=1")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("struct-create %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+}
+
+// compileTables are the tables of the compile snapshot, one starting with
+// each letter so that every one-letter receiver meets the generated locals.
+var compileTables = []string{
+	"accounts", "brands", "customers", "deliveries", "events", "files", "groups",
+	"hosts", "items", "jobs", "kits", "logs", "members", "notes", "orders",
+	"payments", "quotes", "roles", "sessions", "tags", "units", "vendors",
+	"widgets", "xrefs", "years", "zones",
+}
+
+// compileSchema returns the snapshot the generated code is compiled from:
+// the compileTables with columns of every kind, a table with a composite
+// primary key, one with only an auto_increment column and one with only
+// its primary key and a generated column. With metadata it also has
+// indexes, foreign keys, CHECK constraints, partitions, statistics, a
+// system-versioned table and a routine.
+func compileSchema(metadata bool) Schema {
+	column := func(table, name, dataType, columnType, nullable string) ColumnSchema {
+		return ColumnSchema{TableName: table, ColumnName: name, DataType: dataType, ColumnType: columnType, IsNullable: nullable}
+	}
+	length := func(cs ColumnSchema, n int64) ColumnSchema {
+		cs.CharacterMaximumLength = sql.NullInt64{Int64: n, Valid: true}
+		return cs
+	}
+	key := func(cs ColumnSchema, columnKey, extra string) ColumnSchema {
+		cs.ColumnKey, cs.Extra = columnKey, extra
+		return cs
+	}
+	defaulted := func(cs ColumnSchema, value string) ColumnSchema {
+		cs.ColumnDefault = sql.NullString{String: value, Valid: true}
+		return cs
+	}
+
+	var schema Schema
+	for _, table := range compileTables {
+		schema.Columns = append(schema.Columns,
+			key(column(table, "id", "int", "int", "NO"), "PRI", "auto_increment"),
+			length(column(table, "name", "varchar", "varchar(50)", "NO"), 50),
+			column(table, "price", "decimal", "decimal(10,2)", "YES"),
+			defaulted(column(table, "created", "datetime", "datetime", "NO"), "CURRENT_TIMESTAMP"),
+			column(table, "updated", "datetime", "datetime", "YES"),
+			column(table, "data", "blob", "blob", "YES"),
+			defaulted(column(table, "status", "enum", "enum('new','done')", "NO"), "new"),
+			column(table, "enabled", "tinyint", "tinyint(1)", "NO"),
+			column(table, "count", "int", "int", "YES"),
+		)
+	}
+	schema.Columns = append(schema.Columns,
+		key(column("order_lines", "order_id", "int", "int", "NO"), "PRI", ""),
+		key(column("order_lines", "line", "int", "int", "NO"), "PRI", ""),
+		column("order_lines", "qty", "int", "int", "NO"),
+		key(column("serials", "id", "bigint", "bigint", "NO"), "PRI", "auto_increment"),
+		key(column("zlog", "id", "int", "int", "NO"), "PRI", "auto_increment"),
+		key(column("zlog", "total", "int", "int", "YES"), "", "VIRTUAL GENERATED"),
+	)
+	if !metadata {
+		return schema
+	}
+
+	for _, table := range compileTables {
+		schema.Indexes = append(schema.Indexes,
+			Index{TableName: table, IndexName: "PRIMARY", Columns: []string{"id"}, Type: "BTREE"},
+			Index{TableName: table, IndexName: "uniq_name", Columns: []string{"name"}, Type: "BTREE"},
+			Index{TableName: table, IndexName: "idx_created", NonUnique: true, Columns: []string{"created"}, Type: "BTREE"},
+			Index{TableName: table, IndexName: "ft_name", NonUnique: true, Columns: []string{"name"}, Type: "FULLTEXT"},
+		)
+		schema.Checks = append(schema.Checks, Check{TableName: table, ConstraintName: table + "_count", Clause: "(`count` >= 0)"})
+		schema.Stats = append(schema.Stats, TableStats{TableName: table, Rows: 10, DataLength: 16384})
+	}
+	schema.ForeignKeys = []ForeignKey{{
+		ConstraintName: "fk_order_lines_order", TableName: "order_lines", ColumnName: "order_id",
+		ReferencedTableName: "orders", ReferencedColumnName: "id", UpdateRule: "NO ACTION", DeleteRule: "CASCADE",
+	}}
+	schema.Partitions = []Partitioning{{TableName: "logs", Method: "HASH", Expression: "`id`", Partitions: []Partition{{Name: "p0"}, {Name: "p1"}}}}
+	schema.Versioned = []string{"accounts"}
+	schema.Routines = []Routine{{Name: "close_order", Type: "PROCEDURE", Parameters: []Parameter{
+		{ColumnSchema: column("close_order", "order_id", "int", "int", "YES"), Mode: "IN"},
+	}}}
+	return schema
+}
+
+// compileCases are the flags of the generators producing Go code, each run
+// alone, and as a sequence of runs where a later one depends on an earlier
+// output. Generators importing modules other than go-sql-driver/mysql
+// (-sqlx, -metrics, -tracing, -arrow, -proto) are left out as the test
+// module can't download them.
+var compileCases = []struct {
+	name string
+	runs [][]string
+	// want is a declaration the last output must contain
+	want string
+}{
+	{"plain", [][]string{{}}, ""},
+	{"accessors", [][]string{{"-accessors"}}, "SetName("},
+	{"as-of", [][]string{{"-as-of"}}, ""},
+	{"batch-insert", [][]string{{"-batch-insert"}}, ""},
+	{"builders", [][]string{{"-builders"}}, ""},
+	{"cached", [][]string{{"-cached"}}, ""},
+	{"changed", [][]string{{"-changed"}}, ""},
+	{"checks", [][]string{{"-checks"}}, ""},
+	{"clone", [][]string{{"-clone"}}, "Clone()"},
+	{"constructors", [][]string{{"-constructors"}}, ""},
+	{"count", [][]string{{"-count"}}, ""},
+	{"crud", [][]string{{"-crud"}}, ""},
+	{"diffs", [][]string{{"-diffs"}}, ""},
+	{"filters", [][]string{{"-filters"}}, ""},
+	{"find-by", [][]string{{"-find-by"}}, ""},
+	{"fingerprint", [][]string{{"-fingerprint"}}, ""},
+	{"fulltext", [][]string{{"-fulltext"}}, ""},
+	{"gorm", [][]string{{"-gorm"}}, ""},
+	{"handlers", [][]string{{"-handlers"}}, ""},
+	{"is-zero", [][]string{{"-is-zero"}}, ""},
+	{"keys", [][]string{{"-keys"}}, ""},
+	{"models", [][]string{{"-models"}}, ""},
+	{"null-wrappers", [][]string{{"-null-wrappers"}}, ""},
+	{"optimize-alignment", [][]string{{"-optimize-alignment"}}, ""},
+	{"options", [][]string{{"-options"}}, ""},
+	{"pagination", [][]string{{"-pagination"}}, ""},
+	{"prepared", [][]string{{"-crud", "-prepared"}}, ""},
+	{"relations", [][]string{{"-relations"}}, ""},
+	{"routines", [][]string{{"-routines"}}, ""},
+	{"scanners", [][]string{{"-scanners"}}, ""},
+	{"singular", [][]string{{"-singular", "-crud", "-slices"}}, ""},
+	{"slices", [][]string{{"-slices"}}, "ByID()"},
+	{"sql-comments", [][]string{{"-sql-comments"}}, ""},
+	{"stats", [][]string{{"-stats"}}, ""},
+	{"store", [][]string{{"-crud", "-store"}}, ""},
+	{"stringer", [][]string{{"-stringer"}}, ""},
+	{"validate", [][]string{{"-validate"}}, ""},
+	{"defaults comments", [][]string{{"-defaults", "comments"}}, ""},
+	{"defaults map", [][]string{{"-defaults", "map"}}, ""},
+	{"indexes var", [][]string{{"-indexes", "var"}}, ""},
+	{"indexes tags", [][]string{{"-indexes", "tags"}}, ""},
+	{"unique helpers", [][]string{{"-unique", "helpers"}}, ""},
+	{"lengths tags", [][]string{{"-lengths", "tags"}}, ""},
+	{"partitions var", [][]string{{"-partitions", "var"}}, ""},
+	{"unexported all", [][]string{{"-unexported", "all", "-crud", "-accessors"}}, ""},
+	{"null-package", [][]string{{"-null-package", "null"}}, ""},
+	{"example", [][]string{{"-example", "example_test.go"}}, ""},
+	{"benchmark", [][]string{{"-scanners", "-benchmark", "bench_test.go"}}, ""},
+	{"golden-test", [][]string{{"-golden-test", "golden_test.go"}}, ""},
+	{"factory", [][]string{{"-factory", "factory"}}, ""},
+	{"seed", [][]string{{"-seed", "seed"}}, ""},
+	{"merge", [][]string{{}, {"-merge", "-crud", "-validate"}}, ") Validate() error"},
+}
+
+// TestGeneratedCodeCompiles generates code for each of compileCases from a
+// -from-schema snapshot, with and without the metadata beyond the columns,
+// and vets the package it writes.
+func TestGeneratedCodeCompiles(t *testing.T) {
+	if testing.Short() {
+		t.Skip("compiling the generated code is slow")
+	}
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("no go command to compile the generated code with")
+	}
+
+	// the generated code may import the driver, required at the version this
+	// module builds with so that go vet finds it in the module cache
+	mysqlVersion := ""
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, dep := range info.Deps {
+			if dep.Path == "github.com/go-sql-driver/mysql" {
+				mysqlVersion = dep.Version
+			}
+		}
+	}
+	if mysqlVersion == "" {
+		t.Skip("no go-sql-driver/mysql version in the build info")
+	}
+	goMod := "module example.com/models\n\ngo 1.22\n\nrequire github.com/go-sql-driver/mysql " + mysqlVersion + "\n"
+
+	snapshots := t.TempDir()
+	config := filepath.Join(snapshots, "config.json")
+	if err := os.WriteFile(config, []byte(`{"pkg_name": "models", "tag_label": "db"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, metadata := range []bool{true, false} {
+		data, err := json.Marshal(compileSchema(metadata))
+		if err != nil {
+			t.Fatal(err)
+		}
+		snapshot := filepath.Join(snapshots, "columns.json")
+		if metadata {
+			snapshot = filepath.Join(snapshots, "metadata.json")
+		}
+		if err := os.WriteFile(snapshot, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, tc := range compileCases {
+			t.Run(filepath.Base(strings.TrimSuffix(snapshot, ".json"))+"/"+tc.name, func(t *testing.T) {
+				t.Parallel()
+				dir := t.TempDir()
+				if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte(goMod), 0644); err != nil {
+					t.Fatal(err)
+				}
+				for _, args := range tc.runs {
+					runCommand(t, dir, append([]string{"-q", "-json", config, "-from-schema", snapshot, "-out", "models.go"}, args...)...)
+				}
+
+				vet := exec.Command("go", "vet", "./...")
+				vet.Dir = dir
+				vet.Env = append(os.Environ(), "GOFLAGS=-mod=mod", "GOPROXY=off", "GOSUMDB=off", "GOWORK=off")
+				if out, err := vet.CombinedOutput(); err != nil {
+					src, _ := os.ReadFile(filepath.Join(dir, "models.go"))
+					t.Fatalf("go vet: %v\n%s\n%s", err, out, numbered(src))
+				}
+				if tc.want != "" {
+					src, err := os.ReadFile(filepath.Join(dir, "models.go"))
+					if err != nil {
+						t.Fatal(err)
+					}
+					if !strings.Contains(string(src), tc.want) {
+						t.Errorf("models.go has no %q", tc.want)
+					}
+				}
+			})
+		}
+	}
+}
+
+// numbered returns src with line numbers, to find the lines go vet reports.
+func numbered(src []byte) string {
+	lines := strings.Split(string(src), "\n")
+	for i, line := range lines {
+		lines[i] = fmt.Sprintf("%5d  %s", i+1, line)
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// commands are the commands offered by completion.
+var commands = []string{"generate", "check", "diff", "reverse", "select", "serve", "regenerate", "dump-schema", "init", "completion", "version", "help"}
+
+// fileFlags are the flags completed with file names.
+var fileFlags = []string{"json", "config", "out", "against", "migrations", "from-schema", "manifest", "doc", "example", "benchmark", "golden-test", "proto", "typescript", "json-schema", "openapi", "avro", "er", "thrift", "flatbuffers", "debezium", "arrow", "classification", "sqlc", "factory", "seed", "null-package"}
+
+// schemaCacheDir returns the directory dump-schema caches schema snapshots
+// in, read to complete table names.
+func schemaCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "struct-create", "schemas"), nil
+}
+
+// cacheSchema keeps a copy of a schema snapshot written by dump-schema in
+// the cache, named after the schema.
+func cacheSchema(data []byte) {
+	dir, err := schemaCacheDir()
+	if err != nil {
+		slog.Warn("Can't cache the schema", "error", err)
+		return
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		slog.Warn("Can't cache the schema", "error", err)
+		return
+	}
+	if err := os.WriteFile(filepath.Join(dir, schemaPackage(config.DbName)+".json"), data, 0644); err != nil {
+		slog.Warn("Can't cache the schema", "error", err)
+	}
+}
+
+// commandFlagSets returns the flags of the commands taking any.
+func commandFlagSets() map[string]*flag.FlagSet {
+	return map[string]*flag.FlagSet{"generate": flag.CommandLine, "check": flag.CommandLine, "diff": diffFlags(), "reverse": reverseFlags(), "select": selectFlags(), "serve": serveFlags(), "dump-schema": dumpSchemaFlags(), "init": initFlags()}
+}
+
+// flagNames returns the names of the flags, prefixed with a dash.
+func flagNames(flags *flag.FlagSet) []string {
+	names := []string{}
+	flags.VisitAll(func(f *flag.Flag) {
+		names = append(names, "-"+f.Name)
+	})
+	return names
+}
+
+// isBoolFlag reports whether the flag takes no value.
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+// completionCommand implements the completion command, printing the
+// completion script of the shell.
+func completionCommand(args []string) error {
+	if len(args) != 1 {
+		return configError("Usage: struct-create completion bash|zsh|fish")
+	}
+	switch args[0] {
+	case "bash":
+		fmt.Print(bashCompletion())
+	case "zsh":
+		fmt.Print("autoload -U +X bashcompinit && bashcompinit\n" + bashCompletion())
+	case "fish":
+		fmt.Print(fishCompletion())
+	default:
+		return configError("Invalid shell " + args[0] + ", expected bash, zsh or fish")
+	}
+	return nil
+}
+
+func bashCompletion() string {
+	var b strings.Builder
+	b.WriteString(`_struct_create() {
+	local cur="${COMP_WORDS[COMP_CWORD]}" prev="${COMP_WORDS[COMP_CWORD-1]}"
+	local command=generate config="" i
+	if [[ $COMP_CWORD -gt 1 && "${COMP_WORDS[1]}" != -* ]]; then
+		command="${COMP_WORDS[1]}"
+	fi
+	for ((i = 1; i < COMP_CWORD; i++)); do
+		case "${COMP_WORDS[i]}" in
+		-json | --json | -config | --config) config="${COMP_WORDS[i+1]}" ;;
+		esac
+	done
+	case "${prev#-}" in
+	-profile | profile)
+		COMPREPLY=($(compgen -W "$(struct-create __complete profiles "$config" 2>/dev/null)" -- "$cur"))
+		return ;;
+	-tables | tables)
+		COMPREPLY=($(compgen -P "${cur%"${cur##*,}"}" -W "$(struct-create __complete tables 2>/dev/null)" -- "${cur##*,}"))
+		return ;;
+`)
+	b.WriteString("\t" + strings.Join(append(fileFlags, prefixAll("-", fileFlags)...), " | ") + ")\n")
+	b.WriteString("\t\tCOMPREPLY=($(compgen -f -- \"$cur\"))\n\t\treturn ;;\n\tesac\n")
+	b.WriteString(`	if [[ $COMP_CWORD -eq 1 && "$cur" != -* ]]; then
+		COMPREPLY=($(compgen -W "` + strings.Join(commands, " ") + `" -- "$cur"))
+		return
+	fi
+	case "$command" in
+`)
+	sets := commandFlagSets()
+	for _, command := range sortedKeys(sets) {
+		b.WriteString("\t" + command + ") COMPREPLY=($(compgen -W \"" + strings.Join(flagNames(sets[command]), " ") + "\" -- \"$cur\")) ;;\n")
+	}
+	b.WriteString("\tcompletion) COMPREPLY=($(compgen -W \"bash zsh fish\" -- \"$cur\")) ;;\n\tesac\n}\ncomplete -o default -F _struct_create struct-create\n")
+	return b.String()
+}
+
+func fishCompletion() string {
+	var b strings.Builder
+	b.WriteString(`function __struct_create_config
+	set -l tokens (commandline -opc)
+	for i in (seq (count $tokens))
+		if contains -- $tokens[$i] -json --json -config --config
+			echo $tokens[(math $i + 1)]
+		end
+	end
+end
+
+complete -c struct-create -f
+complete -c struct-create -n __fish_use_subcommand -a '` + strings.Join(commands, " ") + `'
+complete -c struct-create -n '__fish_seen_subcommand_from completion' -a 'bash zsh fish'
+`)
+	sets := commandFlagSets()
+	for _, command := range sortedKeys(sets) {
+		condition := "__fish_seen_subcommand_from " + command
+		if command == "generate" {
+			condition = "not __fish_seen_subcommand_from " + strings.Join(commands[1:], " ")
+		}
+		sets[command].VisitAll(func(f *flag.Flag) {
+			line := "complete -c struct-create -n '" + condition + "' -o " + f.Name + " -d " + fishQuote(f.Usage)
+			switch {
+			case f.Name == "profile":
+				line += " -x -a '(struct-create __complete profiles (__struct_create_config) 2>/dev/null)'"
+			case f.Name == "tables":
+				line += " -x -a '(struct-create __complete tables 2>/dev/null)'"
+			case isFileFlag(f.Name):
+				line += " -r -F"
+			case !isBoolFlag(f):
+				line += " -x"
+			}
+			b.WriteString(line + "\n")
+		})
+	}
+	return b.String()
+}
+
+// fishQuote quotes s as a fish single-quoted string.
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(s) + "'"
+}
+
+func isFileFlag(name string) bool {
+	for _, fileFlag := range fileFlags {
+		if fileFlag == name {
+			return true
+		}
+	}
+	return false
+}
+
+func prefixAll(prefix string, values []string) []string {
+	prefixed := []string{}
+	for _, value := range values {
+		prefixed = append(prefixed, prefix+value)
+	}
+	return prefixed
+}
+
+func sortedKeys(sets map[string]*flag.FlagSet) []string {
+	keys := []string{}
+	for key := range sets {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// completeCommand implements the hidden __complete command the completion
+// scripts call for dynamic candidates: the profiles of a config file, or the
+// tables of the schemas cached by dump-schema.
+func completeCommand(args []string) error {
+	if len(args) == 0 {
+		return nil
+	}
+	names := []string{}
+	switch args[0] {
+	case "profiles":
+		if len(args) < 2 || args[1] == "" {
+			return nil
+		}
+		if err := loadConfig(args[1]); err != nil {
+			return err
+		}
+		for name := range config.Profiles {
+			names = append(names, name)
+		}
+	case "tables":
+		dir, err := schemaCacheDir()
+		if err != nil {
+			return err
+		}
+		files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+		seen := map[string]bool{}
+		for _, file := range files {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				continue
+			}
+			var schema Schema
+			if json.Unmarshal(data, &schema) != nil {
+				continue
+			}
+			for _, cs := range schema.Columns {
+				if !seen[cs.TableName] {
+					seen[cs.TableName] = true
+					names = append(names, cs.TableName)
+				}
+			}
+		}
+	}
+	sort.Strings(names)
+	fmt.Println(strings.Join(names, "\n"))
+	return nil
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/go-sql-driver/mysql"
+	"gopkg.in/yaml.v3"
+)
+
+// loadConfig reads the configuration from a JSON file or, going by its
+// extension, a YAML (.yaml, .yml) or TOML (.toml) one. YAML and TOML files use
+// the keys of the JSON one, and are converted to JSON before being decoded.
+func loadConfig(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return classify(exitConfig, err)
+	}
+
+	var settings map[string]interface{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &settings)
+	case ".toml":
+		err = toml.Unmarshal(data, &settings)
+	}
+	if err != nil {
+		return configError("Invalid config file " + path + ": " + err.Error())
+	}
+	if settings != nil {
+		if data, err = json.Marshal(settings); err != nil {
+			return configError("Invalid config file " + path + ": " + err.Error())
+		}
+	}
+
+	if err := json.Unmarshal(data, &config); err != nil {
+		return configError("Invalid config file " + path + ": " + err.Error())
+	}
+	return nil
+}
+
+// applyEnv overrides the configuration with the STRUCT_CREATE_HOST, _PORT,
+// _USER, _PASSWORD, _DB and _DSN environment variables that are set, so that
+// credentials don't need to live in the config file. Flags take precedence
+// over them.
+func applyEnv() error {
+	if host, ok := os.LookupEnv("STRUCT_CREATE_HOST"); ok {
+		config.Host = host
+	}
+	if port, ok := os.LookupEnv("STRUCT_CREATE_PORT"); ok {
+		p, err := strconv.Atoi(port)
+		if err != nil {
+			return configError("Invalid STRUCT_CREATE_PORT " + port + ", expected a number")
+		}
+		config.Port = p
+	}
+	if user, ok := os.LookupEnv("STRUCT_CREATE_USER"); ok {
+		config.DbUser = user
+	}
+	if password, ok := os.LookupEnv("STRUCT_CREATE_PASSWORD"); ok {
+		config.DbPassword = password
+	}
+	if db, ok := os.LookupEnv("STRUCT_CREATE_DB"); ok {
+		config.DbName = db
+	}
+	if dsn, ok := os.LookupEnv("STRUCT_CREATE_DSN"); ok {
+		config.Dsn = dsn
+	}
+	return nil
+}
+
+// parseDSN parses the dsn setting. The database of the DSN, when it has one,
+// is the schema to generate, and its user the one named in messages.
+func parseDSN() (*mysql.Config, error) {
+	cfg, err := mysql.ParseDSN(config.Dsn)
+	if err != nil {
+		return nil, configError("Invalid dsn: " + err.Error())
+	}
+	if cfg.DBName != "" {
+		config.DbName = cfg.DBName
+	}
+	config.DbUser = cfg.User
+	return cfg, nil
+}
+
+// applyFlags overrides the configuration with the connection and package
+// flags set on the command line, even to an empty value (-tag "" drops the
+// tags), which take precedence over the environment and the config file.
+func applyFlags() {
+	commandFlags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "host":
+			config.Host = *hostFlag
+		case "port":
+			config.Port = *portFlag
+		case "user":
+			config.DbUser = *userFlag
+		case "password":
+			config.DbPassword = *passwordFlag
+		case "db":
+			config.DbName = *dbFlag
+		case "pkg":
+			config.PkgName = *pkgFlag
+		case "tag":
+			config.TagLabel = *tagFlag
+		case "dsn":
+			config.Dsn = *dsnFlag
+		case "tables":
+			config.Tables = strings.Split(*tablesFlag, ",")
+		}
+	})
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// configureCharset applies the charset, collation, loc and parse_time
+// settings to a connection configured without a dsn, which holds its own.
+func configureCharset(cfg *mysql.Config) error {
+	if config.Charset != "" {
+		if cfg.Params == nil {
+			cfg.Params = map[string]string{}
+		}
+		cfg.Params["charset"] = config.Charset
+	}
+	if config.Collation != "" {
+		cfg.Collation = config.Collation
+	}
+	if config.Loc != "" {
+		loc, err := time.LoadLocation(config.Loc)
+		if err != nil {
+			return configError("Invalid loc " + config.Loc + ": " + err.Error())
+		}
+		cfg.Loc = loc
+	}
+	cfg.ParseTime = config.ParseTime
+	return nil
+}
+
+// configureParams applies the compress, interpolate_params and params
+// settings to the connection, on top of the parameters of the dsn.
+func configureParams(cfg *mysql.Config) error {
+	if config.Compress {
+		if err := cfg.Apply(mysql.EnableCompression(true)); err != nil {
+			return configError("Invalid compress: " + err.Error())
+		}
+	}
+	if config.InterpolateParams {
+		cfg.InterpolateParams = true
+	}
+	if len(config.Params) > 0 && cfg.Params == nil {
+		cfg.Params = map[string]string{}
+	}
+	for name, value := range config.Params {
+		cfg.Params[name] = value
+	}
+	return nil
+}
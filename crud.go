@@ -0,0 +1,270 @@
+package main
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+)
+
+// columnList returns the quoted, comma separated names of the columns.
+func columnList(columns []ColumnSchema) string {
+	names := []string{}
+	for _, cs := range columns {
+		names = append(names, quoteIdent(cs.ColumnName))
+	}
+	return strings.Join(names, ", ")
+}
+
+// selectList returns the select list of the columns, reading geometries as
+// WKB under their column name.
+func selectList(columns []ColumnSchema) string {
+	exprs := []string{}
+	for _, cs := range columns {
+		if isSpatial(cs) {
+			exprs = append(exprs, "ST_AsBinary("+quoteIdent(cs.ColumnName)+") AS "+quoteIdent(cs.ColumnName))
+		} else {
+			exprs = append(exprs, quoteIdent(cs.ColumnName))
+		}
+	}
+	return strings.Join(exprs, ", ")
+}
+
+// valueExpr returns the expression binding the parameter for a column,
+// converting WKB to a geometry of the column's SRID for spatial columns.
+func valueExpr(cs ColumnSchema, param string) string {
+	if !isSpatial(cs) {
+		return param
+	}
+	if cs.SrsID.Valid {
+		return "ST_GeomFromWKB(" + param + ", " + strconv.FormatInt(cs.SrsID.Int64, 10) + ")"
+	}
+	return "ST_GeomFromWKB(" + param + ")"
+}
+
+// valueList returns the comma separated bind parameters for the columns.
+func valueList(columns []ColumnSchema) string {
+	values := []string{}
+	for _, cs := range columns {
+		values = append(values, valueExpr(cs, "?"))
+	}
+	return strings.Join(values, ", ")
+}
+
+// placeholders returns n comma separated bind parameters.
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+// fieldRefs returns the comma separated fields of v for the columns, prefixed
+// by & when addr is set so they can be passed to Scan.
+func fieldRefs(v string, columns []ColumnSchema, addr bool) string {
+	refs := []string{}
+	for _, cs := range columns {
+		ref := v + "." + fieldName(cs)
+		if addr {
+			ref = "&" + ref
+		}
+		refs = append(refs, ref)
+	}
+	return strings.Join(refs, ", ")
+}
+
+// softDeleteColumn returns the table's soft_delete_column, if it has one.
+func softDeleteColumn(table Table) (ColumnSchema, bool) {
+	for _, cs := range table.Columns {
+		if config.SoftDeleteColumn != "" && cs.ColumnName == config.SoftDeleteColumn && cs.IsNullable == "YES" {
+			return cs, true
+		}
+	}
+	return ColumnSchema{}, false
+}
+
+// notDeleted returns the condition excluding soft deleted rows, to append to
+// a WHERE clause, or "" if the table has no soft_delete_column.
+func notDeleted(table Table) string {
+	if cs, ok := softDeleteColumn(table); ok {
+		return " AND " + quoteIdent(cs.ColumnName) + " IS NULL"
+	}
+	return ""
+}
+
+// insertColumns returns the columns written by INSERT statements, leaving
+// out read-only and auto increment columns.
+func insertColumns(table Table) []ColumnSchema {
+	columns := []ColumnSchema{}
+	for _, cs := range table.Columns {
+		if !isReadOnly(cs) && !hasExtra(cs, "auto_increment") {
+			columns = append(columns, cs)
+		}
+	}
+	return columns
+}
+
+// updateColumns returns the columns written by the UPDATE statement of
+// UpdateX, leaving out the primary key and read-only columns. Tables without
+// any get no UpdateX.
+func updateColumns(table Table) []ColumnSchema {
+	columns := []ColumnSchema{}
+	for _, cs := range table.Columns {
+		if cs.ColumnKey != "PRI" && !isReadOnly(cs) {
+			columns = append(columns, cs)
+		}
+	}
+	return columns
+}
+
+// autoIncrementColumn returns the auto increment column of the table, if any.
+func autoIncrementColumn(table Table) (ColumnSchema, bool) {
+	for _, cs := range table.Columns {
+		if hasExtra(cs, "auto_increment") {
+			return cs, true
+		}
+	}
+	return ColumnSchema{}, false
+}
+
+// writeQueryOne emits a function named name taking params and returning the
+// row selected by query with args, preceded by a blank line.
+func writeQueryOne(buffer *bytes.Buffer, name, params, structName string, table Table, query, args string) {
+	r := receiverName(structName)
+	buffer.WriteString("\n\nfunc " + name + "(ctx context.Context, " + dbParam() + ", " + params + ") (*" + structName + ", error) {\n")
+	writeQueryLabel(buffer, name, table.Name)
+	buffer.WriteString("\t" + r + " := &" + structName + "{}\n")
+	buffer.WriteString("\terr := db.QueryRowContext(ctx, " + strconv.Quote(query) + ", " + args + ").Scan(\n")
+	buffer.WriteString("\t\t" + fieldRefs(r, table.Columns, true) + ")\n")
+	buffer.WriteString("\tif err != nil {\n\t\treturn nil, err\n\t}\n")
+	buffer.WriteString("\treturn " + r + ", nil\n}")
+}
+
+// writeQueryMany emits a function named name taking params and returning the
+// rows selected by query with args, preceded by a blank line.
+func writeQueryMany(buffer *bytes.Buffer, name, params, structName string, table Table, query, args string) {
+	r := receiverName(structName)
+	buffer.WriteString("\n\nfunc " + name + "(ctx context.Context, " + dbParam() + ", " + params + ") ([]" + structName + ", error) {\n")
+	writeQueryLabel(buffer, name, table.Name)
+	buffer.WriteString("\trows, err := db.QueryContext(ctx, " + strconv.Quote(query) + ", " + args + ")\n")
+	buffer.WriteString("\tif err != nil {\n\t\treturn nil, err\n\t}\n")
+	buffer.WriteString("\tdefer rows.Close()\n\n")
+	buffer.WriteString("\tresult := []" + structName + "{}\n")
+	buffer.WriteString("\tfor rows.Next() {\n")
+	buffer.WriteString("\t\tvar " + r + " " + structName + "\n")
+	buffer.WriteString("\t\tif err := rows.Scan(" + fieldRefs(r, table.Columns, true) + "); err != nil {\n")
+	buffer.WriteString("\t\t\treturn nil, err\n\t\t}\n")
+	buffer.WriteString("\t\tresult = append(result, " + r + ")\n")
+	buffer.WriteString("\t}\n")
+	buffer.WriteString("\treturn result, rows.Err()\n}")
+}
+
+// writeCRUD emits context-aware Insert, Get, Update and Delete functions for
+// the table. Insert lets the database assign the auto increment column and
+// reads it back into the struct. Get, Update and Delete require a primary key: Get and Delete take
+// the value of a single column one (GetXByID) or an XKey (GetXByKey). For
+// tables with a soft_delete_column, Get skips deleted rows, its WithDeleted
+// variant doesn't, and Delete sets the column instead of deleting the row.
+func writeCRUD(buffer *bytes.Buffer, structName string, table Table) {
+	r := receiverName(structName)
+	tableName := tableIdent(table.Name)
+
+	columns := insertColumns(table)
+	insert := "INSERT INTO " + tableName + " (" + columnList(columns) + ") VALUES (" + valueList(columns) + ")"
+	buffer.WriteString("func " + funcName("Insert", structName) + "(ctx context.Context, " + dbParam() + ", " + r + " *" + structName + ") error {\n")
+	writeQueryLabel(buffer, funcName("Insert", structName), table.Name)
+	if cs, ok := autoIncrementColumn(table); ok {
+		id := "id"
+		if gt, _, _ := goType(&cs); gt != "int64" {
+			id = gt + "(id)"
+		}
+		buffer.WriteString("\tres, err := db.ExecContext(ctx, " + strconv.Quote(insert) + ",\n")
+		buffer.WriteString("\t\t" + fieldRefs(r, columns, false) + ")\n")
+		buffer.WriteString("\tif err != nil {\n\t\treturn err\n\t}\n")
+		buffer.WriteString("\tid, err := res.LastInsertId()\n")
+		buffer.WriteString("\tif err != nil {\n\t\treturn err\n\t}\n")
+		buffer.WriteString("\t" + r + "." + fieldName(cs) + " = " + id + "\n")
+		buffer.WriteString("\treturn nil\n}")
+	} else {
+		buffer.WriteString("\t_, err := db.ExecContext(ctx, " + strconv.Quote(insert) + ",\n")
+		buffer.WriteString("\t\t" + fieldRefs(r, columns, false) + ")\n")
+		buffer.WriteString("\treturn err\n}")
+	}
+
+	pk := primaryKey(table)
+	if len(pk) == 0 {
+		return
+	}
+	wheres := []string{}
+	for _, cs := range pk {
+		wheres = append(wheres, quoteIdent(cs.ColumnName)+" = ?")
+	}
+	where := " WHERE " + strings.Join(wheres, " AND ")
+
+	// single column keys are passed as is, composite ones as an XKey
+	get, keyParam, keyArgs := funcName("Get", structName)+"ByKey", "key "+structName+"Key", fieldRefs("key", pk, false)
+	if len(pk) == 1 {
+		pkType, _, err := goType(&pk[0])
+		if err != nil {
+			return
+		}
+		pkParam := paramName(pk[0])
+		get, keyParam, keyArgs = funcName("Get", structName)+"ByID", pkParam+" "+pkType, pkParam
+	}
+
+	query := "SELECT " + selectList(table.Columns) + " FROM " + tableName + where
+	writeQueryOne(buffer, get, keyParam, structName, table, query+notDeleted(table), keyArgs)
+	if notDeleted(table) != "" {
+		writeQueryOne(buffer, get+"WithDeleted", keyParam, structName, table, query, keyArgs)
+	}
+
+	values := updateColumns(table)
+	sets := []string{}
+	for _, cs := range values {
+		sets = append(sets, quoteIdent(cs.ColumnName)+" = "+valueExpr(cs, "?"))
+	}
+	if len(values) > 0 {
+		update := "UPDATE " + tableName + " SET " + strings.Join(sets, ", ") + where
+		buffer.WriteString("\n\nfunc " + funcName("Update", structName) + "(ctx context.Context, " + dbParam() + ", " + r + " *" + structName + ") error {\n")
+		writeQueryLabel(buffer, funcName("Update", structName), table.Name)
+		buffer.WriteString("\t_, err := db.ExecContext(ctx, " + strconv.Quote(update) + ",\n")
+		buffer.WriteString("\t\t" + fieldRefs(r, values, false) + ", " + fieldRefs(r, pk, false) + ")\n")
+		buffer.WriteString("\treturn err\n}")
+	}
+
+	del := "DELETE FROM " + tableName + where
+	if cs, ok := softDeleteColumn(table); ok {
+		del = "UPDATE " + tableName + " SET " + quoteIdent(cs.ColumnName) + " = NOW()" + where + notDeleted(table)
+	}
+	buffer.WriteString("\n\nfunc " + funcName("Delete", structName) + "(ctx context.Context, " + dbParam() + ", " + keyParam + ") error {\n")
+	writeQueryLabel(buffer, funcName("Delete", structName), table.Name)
+	buffer.WriteString("\t_, err := db.ExecContext(ctx, " + strconv.Quote(del) + ", " + keyArgs + ")\n")
+	buffer.WriteString("\treturn err\n}")
+}
+
+// writeBatchInsert emits an InsertXBatch function inserting rows with
+// multi-row INSERT statements of at most batchSize rows, staying under the
+// 65535 placeholders MySQL accepts per statement. Tables with no column to
+// insert, only auto_increment or generated ones, have nothing to batch and
+// mustn't be passed.
+func writeBatchInsert(buffer *bytes.Buffer, structName string, table Table) {
+	columns := insertColumns(table)
+	n := len(columns)
+	maxBatch := strconv.Itoa(65535 / n)
+	insert := "INSERT INTO " + tableIdent(table.Name) + " (" + columnList(columns) + ") VALUES "
+
+	buffer.WriteString("func " + funcName("Insert", structName) + "Batch(ctx context.Context, " + dbParam() + ", rows []" + structName + ", batchSize int) error {\n")
+	writeQueryLabel(buffer, funcName("Insert", structName)+"Batch", table.Name)
+	buffer.WriteString("\tif batchSize <= 0 || batchSize > " + maxBatch + " {\n")
+	buffer.WriteString("\t\tbatchSize = " + maxBatch + "\n\t}\n")
+	buffer.WriteString("\tfor start := 0; start < len(rows); start += batchSize {\n")
+	buffer.WriteString("\t\tend := start + batchSize\n")
+	buffer.WriteString("\t\tif end > len(rows) {\n\t\t\tend = len(rows)\n\t\t}\n")
+	buffer.WriteString("\t\tbatch := rows[start:end]\n\n")
+	buffer.WriteString("\t\tquery := " + strconv.Quote(insert) + " +\n")
+	buffer.WriteString("\t\t\tstrings.TrimSuffix(strings.Repeat(\"(" + valueList(columns) + "), \", len(batch)), \", \")\n")
+	buffer.WriteString("\t\targs := make([]interface{}, 0, len(batch)*" + strconv.Itoa(n) + ")\n")
+	buffer.WriteString("\t\tfor _, row := range batch {\n")
+	buffer.WriteString("\t\t\targs = append(args, " + fieldRefs("row", columns, false) + ")\n")
+	buffer.WriteString("\t\t}\n")
+	buffer.WriteString("\t\tif _, err := db.ExecContext(ctx, query, args...); err != nil {\n")
+	buffer.WriteString("\t\t\treturn err\n\t\t}\n")
+	buffer.WriteString("\t}\n")
+	buffer.WriteString("\treturn nil\n}")
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+)
+
+// writeColumnsCSV writes the -format csv output in place of the structs: a
+// row per column with its table, name, type, nullability, key, default and
+// comment, for spreadsheets.
+func writeColumnsCSV(schema Schema) error {
+	var buffer bytes.Buffer
+	w := csv.NewWriter(&buffer)
+	w.Write([]string{"table", "column", "type", "nullable", "key", "default", "comment"})
+	for _, table := range groupTables(schema.Columns) {
+		for _, cs := range table.Columns {
+			d, _ := columnDefault(cs)
+			w.Write([]string{table.Name, cs.ColumnName, cs.ColumnType, cs.IsNullable, cs.ColumnKey, d, cs.ColumnComment})
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return err
+	}
+
+	if *output == "-" {
+		_, err := buffer.WriteTo(structsOut)
+		return err
+	}
+	return writeOutput(*output, buffer.Bytes())
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"strings"
+)
+
+var debezium = flag.String("debezium", "", "Also write a Go file with the Debezium change event structs of each table")
+
+// debeziumSource is the source block of the MySQL connector's events.
+const debeziumSource = `// DebeziumSource is the source metadata of a Debezium MySQL change event.
+type DebeziumSource struct {
+	Version   string  ` + "`json:\"version\"`" + `
+	Connector string  ` + "`json:\"connector\"`" + `
+	Name      string  ` + "`json:\"name\"`" + `
+	TsMs      int64   ` + "`json:\"ts_ms\"`" + `
+	Snapshot  string  ` + "`json:\"snapshot\"`" + `
+	Db        string  ` + "`json:\"db\"`" + `
+	Table     string  ` + "`json:\"table\"`" + `
+	ServerID  int64   ` + "`json:\"server_id\"`" + `
+	GTID      *string ` + "`json:\"gtid\"`" + `
+	File      string  ` + "`json:\"file\"`" + `
+	Pos       int64   ` + "`json:\"pos\"`" + `
+	Row       int32   ` + "`json:\"row\"`" + `
+	Thread    *int64  ` + "`json:\"thread\"`" + `
+	Query     *string ` + "`json:\"query\"`" + `
+}
+`
+
+// debeziumType returns the Go type of a column in the events of the MySQL
+// connector with its default settings, except for decimal.handling.mode
+// string, and a comment on its encoding when it isn't the obvious one.
+func debeziumType(cs ColumnSchema) (string, string) {
+	switch cs.DataType {
+	case "tinyint", "smallint", "mediumint", "int", "bigint":
+		return "int64", ""
+	case "year":
+		return "int32", ""
+	case "float", "double":
+		return "float64", ""
+	case "decimal":
+		return "string", "Needs decimal.handling.mode=string."
+	case "date":
+		return "int32", "Days since 1970-01-01."
+	case "datetime":
+		if precision := strings.TrimSuffix(strings.TrimPrefix(cs.ColumnType, "datetime("), ")"); precision > "3" && precision <= "6" {
+			return "int64", "Microseconds since 1970-01-01 00:00:00."
+		}
+		return "int64", "Milliseconds since 1970-01-01 00:00:00."
+	case "timestamp":
+		return "string", "ISO 8601 in UTC."
+	case "time":
+		return "int64", "Microseconds."
+	case "json":
+		return "string", "JSON document as text."
+	}
+	if strings.Contains(cs.DataType, "blob") || strings.Contains(cs.DataType, "binary") {
+		return "[]byte", ""
+	}
+	if isSpatial(cs) {
+		return "json.RawMessage", "Struct with the WKB and SRID."
+	}
+	return "string", ""
+}
+
+// writeDebezium writes a Go file with, for each table, a XChangeRow struct
+// holding a row as Debezium's MySQL connector encodes it, a XChangeEvent
+// struct for its change events with the row before and after the change,
+// and an UnmarshalXChangeEvent function decoding them with or without the
+// schema envelope of schemas.enable.
+func writeDebezium(path string, schemas []ColumnSchema) error {
+	buffer := bytes.NewBufferString(buildConstraint() + "package " + config.PkgName + "\n\n")
+	buffer.WriteString("import \"encoding/json\"\n\n")
+	buffer.WriteString(debeziumSource)
+
+	for _, table := range groupTables(schemas) {
+		structName := typeName(table.Name)
+		row, event := structName+"ChangeRow", structName+"ChangeEvent"
+
+		buffer.WriteString("\n// " + row + " is a row of " + table.Name + " in a Debezium change event.\n")
+		buffer.WriteString("type " + row + " struct {\n")
+		for _, cs := range table.Columns {
+			goType, comment := debeziumType(cs)
+			if cs.IsNullable == "YES" && goType != "[]byte" && goType != "json.RawMessage" {
+				goType = "*" + goType
+			}
+			if comment != "" {
+				buffer.WriteString("\t// " + comment + "\n")
+			}
+			buffer.WriteString("\t" + fieldName(cs) + " " + goType + "\t" + structTag([]string{tag("json", cs.ColumnName)}) + "\n")
+		}
+		buffer.WriteString("}\n\n")
+
+		buffer.WriteString("// " + event + " is a Debezium change event of " + table.Name + ". Op is c for\n")
+		buffer.WriteString("// creates, u for updates, d for deletes, r for snapshot reads and t for\n")
+		buffer.WriteString("// truncates; Before is nil for creates and reads, After for deletes.\n")
+		buffer.WriteString("type " + event + " struct {\n")
+		buffer.WriteString("\tBefore *" + row + "\t`json:\"before\"`\n")
+		buffer.WriteString("\tAfter *" + row + "\t`json:\"after\"`\n")
+		buffer.WriteString("\tSource DebeziumSource\t`json:\"source\"`\n")
+		buffer.WriteString("\tOp string\t`json:\"op\"`\n")
+		buffer.WriteString("\tTsMs int64\t`json:\"ts_ms\"`\n")
+		buffer.WriteString("}\n\n")
+
+		buffer.WriteString("// Unmarshal" + event + " decodes a change event of " + table.Name + ", with or\n")
+		buffer.WriteString("// without the schema and payload envelope of schemas.enable.\n")
+		buffer.WriteString("func Unmarshal" + event + "(data []byte) (*" + event + ", error) {\n")
+		buffer.WriteString("\tvar envelope struct {\n\t\tPayload json.RawMessage `json:\"payload\"`\n\t}\n")
+		buffer.WriteString("\tif err := json.Unmarshal(data, &envelope); err == nil && envelope.Payload != nil {\n")
+		buffer.WriteString("\t\tdata = envelope.Payload\n\t}\n")
+		buffer.WriteString("\tevent := &" + event + "{}\n")
+		buffer.WriteString("\tif err := json.Unmarshal(data, event); err != nil {\n\t\treturn nil, err\n\t}\n")
+		buffer.WriteString("\treturn event, nil\n}\n")
+	}
+
+	return writeOutput(path, buffer.Bytes())
+}
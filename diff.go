@@ -0,0 +1,322 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The flags of the diff command.
+var (
+	diffAgainst    = new(string)
+	diffMigrations = new(string)
+	diffName       = new(string)
+)
+
+// diffFlags returns the flags of the diff command.
+func diffFlags() *flag.FlagSet {
+	flags := flag.NewFlagSet("diff", flag.ExitOnError)
+	addSettingsFlags(flags)
+	addLogFlags(flags)
+	flags.StringVar(diffAgainst, "against", "", "Schema snapshot written by dump-schema (.json) or generated structs (.go) to compare the schema with")
+	flags.StringVar(diffMigrations, "migrations", "", "Directory to write golang-migrate up and down files to, turning the snapshot into the schema")
+	flags.StringVar(diffName, "name", "schema_changes", "Title of the -migrations files")
+	return flags
+}
+
+// diffCommand implements the diff command, printing how the live schema
+// differs from a dump-schema snapshot, table by table and column by column,
+// or from previously generated structs, struct by struct and field by field.
+func diffCommand(ctx context.Context, args []string) error {
+	flags := diffFlags()
+	flags.Parse(args)
+	commandFlags = flags
+	if err := setupLogging(); err != nil {
+		return err
+	}
+	if *diffAgainst == "" {
+		return configError("diff needs -against, a schema snapshot or generated structs")
+	}
+	if *diffMigrations != "" && strings.HasSuffix(*diffAgainst, ".go") {
+		return configError("-migrations needs a schema snapshot to compare with, not structs")
+	}
+
+	if err := readConfig(); err != nil {
+		return err
+	}
+	if err := applySettings(); err != nil {
+		return err
+	}
+	if err := checkPatterns(); err != nil {
+		return err
+	}
+	if err := compileRenameRules(); err != nil {
+		return err
+	}
+	conn, err := connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	columns, err := getColumns(ctx, conn, config.DbName)
+	if err != nil {
+		return classify(exitConnection, err)
+	}
+	schema := filterColumns(filterTables(Schema{Columns: columns}))
+
+	var changes []string
+	if strings.HasSuffix(*diffAgainst, ".go") {
+		changes, err = diffStructs(*diffAgainst, schema)
+		if err != nil {
+			return err
+		}
+	} else {
+		snapshot, err := readSnapshot(*diffAgainst)
+		if err != nil {
+			return err
+		}
+		changes = diffSnapshot(snapshot, schema)
+		if *diffMigrations != "" && len(changes) > 0 {
+			if err := writeMigration(ctx, conn, *diffMigrations, snapshot, schema); err != nil {
+				return err
+			}
+		}
+	}
+	if len(changes) == 0 {
+		fmt.Println("No differences")
+		return nil
+	}
+	fmt.Println(strings.Join(changes, "\n"))
+	return nil
+}
+
+// readSnapshot reads a schema snapshot written by dump-schema, from stdin
+// when path is "-".
+func readSnapshot(path string) (Schema, error) {
+	var snapshot Schema
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return snapshot, classify(exitConfig, err)
+	}
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return snapshot, configError("Invalid schema snapshot " + path + ": " + err.Error())
+	}
+	return snapshot, nil
+}
+
+// diffSnapshot lists the tables and columns added to or removed from the
+// schema since the snapshot, and the columns whose type or nullability
+// changed.
+func diffSnapshot(snapshot, schema Schema) []string {
+	columnType := func(cs ColumnSchema) string {
+		if cs.IsNullable == "YES" {
+			return cs.ColumnType + " NULL"
+		}
+		return cs.ColumnType + " NOT NULL"
+	}
+	before, after := tableColumns(snapshot.Columns, columnType), tableColumns(schema.Columns, columnType)
+	return diffTables(before, after, "table", func(table, column string) string {
+		return table + "." + column
+	})
+}
+
+// diffStructs lists the structs and fields generated from the schema that
+// were added to or removed from the structs of the Go file, and the fields
+// whose type changed, going by their tag_label tag, or their name without
+// one.
+func diffStructs(path string, schema Schema) ([]string, error) {
+	if err := resolveCollisions(schema.Columns); err != nil {
+		return nil, err
+	}
+	before, err := parseStructs(path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	after := map[string]map[string]string{}
+	for _, table := range groupTables(schema.Columns) {
+		fields := map[string]string{}
+		for _, cs := range table.Columns {
+			gt, _, err := goType(&cs)
+			if err != nil {
+				return nil, err
+			}
+			key := fieldName(cs)
+			if config.TagLabel != "" {
+				key = cs.ColumnName
+			}
+			fields[key] = gt
+		}
+		after[typeName(table.Name)] = fields
+	}
+	return diffTables(before, after, "struct", func(structName, field string) string {
+		return structName + "." + field
+	}), nil
+}
+
+// parseStructs returns the fields of the structs of a Go file with their
+// type, keyed by their tag_label tag or else their name, merging in the
+// fields of embedded structs of the file. Embedded structs, and with a
+// tag_label the structs without any tagged field, don't map tables and are
+// left out. The source is read from path when src is nil.
+func parseStructs(path string, src interface{}) (map[string]map[string]string, error) {
+	file, err := parser.ParseFile(token.NewFileSet(), path, src, 0)
+	if err != nil {
+		return nil, configError("Invalid Go file " + path + ": " + err.Error())
+	}
+	structs := map[string]*ast.StructType{}
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			ts := spec.(*ast.TypeSpec)
+			if st, ok := ts.Type.(*ast.StructType); ok {
+				structs[ts.Name.Name] = st
+			}
+		}
+	}
+
+	embedded := map[string]bool{}
+	var fieldsOf func(st *ast.StructType, fields map[string]string)
+	fieldsOf = func(st *ast.StructType, fields map[string]string) {
+		for _, f := range st.Fields.List {
+			if len(f.Names) == 0 {
+				if ident, ok := f.Type.(*ast.Ident); ok && structs[ident.Name] != nil {
+					embedded[ident.Name] = true
+					fieldsOf(structs[ident.Name], fields)
+				}
+				continue
+			}
+			key := f.Names[0].Name
+			if config.TagLabel != "" {
+				if f.Tag == nil {
+					continue
+				}
+				tag, _ := strconv.Unquote(f.Tag.Value)
+				name, _, _ := strings.Cut(reflect.StructTag(tag).Get(config.TagLabel), ",")
+				if name == "" || name == "-" {
+					continue
+				}
+				key = name
+			}
+			fields[key] = exprString(f.Type)
+		}
+	}
+	parsed := map[string]map[string]string{}
+	for name, st := range structs {
+		fields := map[string]string{}
+		fieldsOf(st, fields)
+		if len(fields) > 0 || config.TagLabel == "" {
+			parsed[name] = fields
+		}
+	}
+	for name := range embedded {
+		delete(parsed, name)
+	}
+	return parsed, nil
+}
+
+// exprString formats a field type as written in Go.
+func exprString(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		return t.Name
+	case *ast.SelectorExpr:
+		return exprString(t.X) + "." + t.Sel.Name
+	case *ast.StarExpr:
+		return "*" + exprString(t.X)
+	case *ast.ArrayType:
+		return "[]" + exprString(t.Elt)
+	case *ast.MapType:
+		return "map[" + exprString(t.Key) + "]" + exprString(t.Value)
+	}
+	return fmt.Sprintf("%T", expr)
+}
+
+// tableColumns returns the columns of each table with their description.
+func tableColumns(columns []ColumnSchema, describe func(ColumnSchema) string) map[string]map[string]string {
+	tables := map[string]map[string]string{}
+	for _, cs := range columns {
+		if tables[cs.TableName] == nil {
+			tables[cs.TableName] = map[string]string{}
+		}
+		tables[cs.TableName][cs.ColumnName] = describe(cs)
+	}
+	return tables
+}
+
+// diffTables lists, in name order, the tables (or structs) of after missing
+// from before as "+ kind name" and the other way round as "- kind name", and
+// for the ones in both the columns (or fields) added, removed or whose
+// description changed, as "+ name type", "- name type" and
+// "~ name: old -> new".
+func diffTables(before, after map[string]map[string]string, kind string, qualify func(table, column string) string) []string {
+	tables := map[string]bool{}
+	for table := range before {
+		tables[table] = true
+	}
+	for table := range after {
+		tables[table] = true
+	}
+
+	changes := []string{}
+	for _, table := range sortedSet(tables) {
+		was, now := before[table], after[table]
+		switch {
+		case was == nil:
+			changes = append(changes, "+ "+kind+" "+table)
+			continue
+		case now == nil:
+			changes = append(changes, "- "+kind+" "+table)
+			continue
+		}
+		columns := map[string]bool{}
+		for column := range was {
+			columns[column] = true
+		}
+		for column := range now {
+			columns[column] = true
+		}
+		for _, column := range sortedSet(columns) {
+			o, inWas := was[column]
+			n, inNow := now[column]
+			switch {
+			case !inWas:
+				changes = append(changes, "+ "+qualify(table, column)+" "+n)
+			case !inNow:
+				changes = append(changes, "- "+qualify(table, column)+" "+o)
+			case o != n:
+				changes = append(changes, "~ "+qualify(table, column)+": "+o+" -> "+n)
+			}
+		}
+	}
+	return changes
+}
+
+// sortedSet returns the keys of the set in order.
+func sortedSet(set map[string]bool) []string {
+	keys := []string{}
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+)
+
+// writeDoc writes a doc.go file holding the package comment: where and when
+// the structs were generated from, and the list of generated types.
+func writeDoc(path string, schemas []ColumnSchema) error {
+	buffer := bytes.NewBufferString(buildConstraint())
+
+	fmt.Fprintf(buffer, "// Package %s contains structs generated by struct-create from the\n", config.PkgName)
+	fmt.Fprintf(buffer, "// %s MySQL schema on %s:%d at %s.\n", config.DbName, config.Host, config.Port,
+		time.Now().UTC().Format(time.RFC3339))
+	buffer.WriteString("//\n// Generated types:\n//\n")
+	for _, table := range groupTables(schemas) {
+		fmt.Fprintf(buffer, "//   - %s (table %s)\n", typeName(table.Name), table.Name)
+	}
+	buffer.WriteString("package " + config.PkgName + "\n")
+
+	return writeOutput(path, buffer.Bytes())
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// checkOutputs makes writeOutput compare the outputs with the files on disk
+// instead of writing them, recording the ones that differ in drifted.
+var (
+	checkOutputs bool
+	drifted      []string
+)
+
+// checkCommand implements the check command, taking the flags of generate:
+// it regenerates every output in memory and fails printing a diff of each
+// one that differs from its file, as when the schema drifted from the
+// committed structs.
+func checkCommand(ctx context.Context, args []string) error {
+	checkOutputs = true
+	if err := generateCommand(ctx, args); err != nil {
+		return err
+	}
+	if len(drifted) > 0 {
+		return &exitError{exitDrift, fmt.Errorf("The schema drifted from %s, run struct-create to regenerate", strings.Join(drifted, ", "))}
+	}
+	return nil
+}
+
+// checkOutput compares an output with its file, printing a diff when they
+// differ.
+func checkOutput(path string, data []byte) error {
+	current, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if bytes.Equal(current, data) {
+		return nil
+	}
+	drifted = append(drifted, path)
+	fmt.Print(unifiedDiff(path, string(current), string(data)))
+	return nil
+}
+
+// unifiedDiff returns the lines of want missing from got and the other way
+// round as a unified diff with three lines of context, found through their
+// longest common subsequence.
+func unifiedDiff(path, got, want string) string {
+	a, b := splitLines(got), splitLines(want)
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	// Every line of the edit script: ' ' kept, '-' only in got, '+' only in
+	// want, with its line numbers in both.
+	type edit struct {
+		op   byte
+		line string
+		i, j int
+	}
+	edits := []edit{}
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			edits = append(edits, edit{' ', a[i], i, j})
+			i++
+			j++
+		case j < len(b) && (i == len(a) || lcs[i][j+1] >= lcs[i+1][j]):
+			edits = append(edits, edit{'+', b[j], i, j})
+			j++
+		default:
+			edits = append(edits, edit{'-', a[i], i, j})
+			i++
+		}
+	}
+
+	var out strings.Builder
+	out.WriteString("--- " + path + "\n+++ " + path + " (generated)\n")
+	const context = 3
+	for start := 0; start < len(edits); {
+		if edits[start].op == ' ' {
+			start++
+			continue
+		}
+		first := max(start-context, 0)
+		end := start
+		for k := start; k < len(edits) && k <= end+2*context; k++ {
+			if edits[k].op != ' ' {
+				end = k
+			}
+		}
+		last := min(end+context, len(edits)-1)
+		gotLines, wantLines := 0, 0
+		for _, e := range edits[first : last+1] {
+			if e.op != '+' {
+				gotLines++
+			}
+			if e.op != '-' {
+				wantLines++
+			}
+		}
+		fmt.Fprintf(&out, "@@ -%d,%d +%d,%d @@\n", edits[first].i+1, gotLines, edits[first].j+1, wantLines)
+		for _, e := range edits[first : last+1] {
+			out.WriteString(string(e.op) + e.line + "\n")
+		}
+		start = last + 1
+	}
+	return out.String()
+}
+
+// splitLines splits s into lines without their newline.
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+var dryRun = flag.Bool("dry-run", false, "Introspect the schema and map its types, printing the structs and files that would be generated without writing anything")
+
+// plannedFiles returns the files generatePackage writes with the flags set.
+func plannedFiles() []string {
+	files := []string{}
+	if *output != "-" {
+		files = append(files, *output)
+	}
+	if *sqlc != "" {
+		for _, file := range []string{"queries.sql", "schema.sql", "sqlc.yaml"} {
+			files = append(files, filepath.Join(*sqlc, file))
+		}
+	}
+	for _, file := range []string{*doc, *example, *benchmark, *protoFile} {
+		if file != "" {
+			files = append(files, file)
+		}
+	}
+	if *factory != "" {
+		files = append(files, filepath.Join(*factory, "factory.go"))
+	}
+	if *seed != "" {
+		files = append(files, filepath.Join(*seed, "seed.go"))
+	}
+	if *nullPackage != "" {
+		files = append(files, filepath.Join(*nullPackage, "null.go"))
+	}
+	return files
+}
+
+// printDryRun prints the structs and fields the tables map to, the files
+// that would be written and the columns whose type can't be mapped, in place
+// of generating them.
+func printDryRun(schema Schema) {
+	tables := groupTables(schema.Columns)
+	fmt.Printf("Package %s from %s: %d tables, %d columns\n", config.PkgName, config.DbName, len(tables), len(schema.Columns))
+
+	warnings := []string{}
+	for _, table := range tables {
+		fmt.Printf("\n%s -> %s\n", table.Name, typeName(table.Name))
+		for _, cs := range table.Columns {
+			gt, _, err := goType(&cs)
+			if err != nil {
+				warnings = append(warnings, err.Error())
+				gt = "?"
+			}
+			fmt.Printf("  %s %s -> %s %s\n", cs.ColumnName, cs.ColumnType, fieldName(cs), gt)
+		}
+	}
+
+	files := plannedFiles()
+	if len(files) == 0 {
+		files = []string{"(stdout)"}
+	}
+	fmt.Printf("\nWould write:\n  %s\n", strings.Join(files, "\n  "))
+	if len(warnings) > 0 {
+		fmt.Printf("\nWarnings:\n  %s\n", strings.Join(warnings, "\n  "))
+	}
+}
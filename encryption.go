@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"sort"
+)
+
+// encryptedTypeNames are the wrapper types of the encrypted columns, by the
+// type of their plaintext in encrypted_columns.
+var encryptedTypeNames = map[string]string{"string": "EncryptedString", "bytes": "EncryptedBytes"}
+
+// encryptedType returns the wrapper type of a column listed in
+// encrypted_columns, a pointer to it when the column is nullable.
+func encryptedType(cs ColumnSchema) (string, bool) {
+	plaintext, ok := config.EncryptedColumns[cs.TableName+"."+cs.ColumnName]
+	if !ok {
+		return "", false
+	}
+	gt := encryptedTypeNames[plaintext]
+	if cs.IsNullable == "YES" {
+		gt = "*" + gt
+	}
+	return gt, true
+}
+
+// checkEncryptedColumns fails on an encrypted_columns entry whose plaintext
+// is neither a string nor bytes.
+func checkEncryptedColumns() error {
+	for column, plaintext := range config.EncryptedColumns {
+		if encryptedTypeNames[plaintext] == "" {
+			return configError("Invalid encrypted_columns type " + plaintext + " of " + column + ", expected string or bytes")
+		}
+	}
+	return nil
+}
+
+// encryptedTypes returns the wrapper types the encrypted columns use, in a
+// stable order.
+func encryptedTypes(schemas []ColumnSchema) []string {
+	seen := map[string]bool{}
+	for _, cs := range schemas {
+		if plaintext, ok := config.EncryptedColumns[cs.TableName+"."+cs.ColumnName]; ok {
+			seen[encryptedTypeNames[plaintext]] = true
+		}
+	}
+	types := []string{}
+	for t := range seen {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// writeCipher emits the Cipher interface the application implements to
+// encrypt the encrypted columns, and the FieldCipher variable it sets.
+func writeCipher(buffer *bytes.Buffer) {
+	buffer.WriteString("// Cipher encrypts the values of the encrypted columns before they are stored\n")
+	buffer.WriteString("// and decrypts them when they are scanned.\n")
+	buffer.WriteString("type Cipher interface {\n")
+	buffer.WriteString("\tEncrypt(plaintext []byte) ([]byte, error)\n")
+	buffer.WriteString("\tDecrypt(ciphertext []byte) ([]byte, error)\n")
+	buffer.WriteString("}\n\n")
+	buffer.WriteString("// FieldCipher is the Cipher of the encrypted columns, to set before any of\n")
+	buffer.WriteString("// them is scanned or stored.\n")
+	buffer.WriteString("var FieldCipher Cipher\n\n")
+	buffer.WriteString("var errNoFieldCipher = errors.New(\"FieldCipher is not set\")")
+}
+
+// writeEncryptedType emits a wrapper type holding the plaintext of an
+// encrypted column, with the sql.Scanner and driver.Valuer methods
+// decrypting and encrypting it through FieldCipher.
+func writeEncryptedType(buffer *bytes.Buffer, typeName string) {
+	plaintext := "string"
+	if typeName == encryptedTypeNames["bytes"] {
+		plaintext = "[]byte"
+	}
+	r := receiverName(typeName)
+	buffer.WriteString("// " + typeName + " is a " + plaintext + " stored encrypted by FieldCipher.\n")
+	buffer.WriteString("type " + typeName + " " + plaintext + "\n\n")
+
+	buffer.WriteString("func (" + r + " *" + typeName + ") Scan(src interface{}) error {\n")
+	buffer.WriteString("\tif FieldCipher == nil {\n")
+	buffer.WriteString("\t\treturn errNoFieldCipher\n")
+	buffer.WriteString("\t}\n")
+	buffer.WriteString("\tvar ciphertext []byte\n")
+	buffer.WriteString("\tswitch data := src.(type) {\n")
+	buffer.WriteString("\tcase []byte:\n")
+	buffer.WriteString("\t\tciphertext = data\n")
+	buffer.WriteString("\tcase string:\n")
+	buffer.WriteString("\t\tciphertext = []byte(data)\n")
+	buffer.WriteString("\tdefault:\n")
+	buffer.WriteString("\t\treturn fmt.Errorf(\"" + typeName + ": cannot scan %T\", src)\n")
+	buffer.WriteString("\t}\n")
+	buffer.WriteString("\tdecrypted, err := FieldCipher.Decrypt(ciphertext)\n")
+	buffer.WriteString("\tif err != nil {\n")
+	buffer.WriteString("\t\treturn err\n")
+	buffer.WriteString("\t}\n")
+	buffer.WriteString("\t*" + r + " = " + typeName + "(decrypted)\n")
+	buffer.WriteString("\treturn nil\n}\n\n")
+
+	buffer.WriteString("func (" + r + " " + typeName + ") Value() (driver.Value, error) {\n")
+	buffer.WriteString("\tif FieldCipher == nil {\n")
+	buffer.WriteString("\t\treturn nil, errNoFieldCipher\n")
+	buffer.WriteString("\t}\n")
+	buffer.WriteString("\treturn FieldCipher.Encrypt([]byte(" + r + "))\n}")
+}
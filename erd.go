@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var erDiagram = flag.String("er", "", "Also write an entity-relationship diagram of the tables and their foreign keys: Graphviz DOT for .dot and .gv files, else Mermaid")
+
+// mermaidName matches the entity and attribute names Mermaid takes without
+// quotes.
+var mermaidName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+
+// erKeys returns the keys of a column in an ER diagram: PK, FK and UK.
+func erKeys(cs ColumnSchema, foreignKeys map[string]bool) []string {
+	keys := []string{}
+	if cs.ColumnKey == "PRI" {
+		keys = append(keys, "PK")
+	}
+	if foreignKeys[cs.TableName+"."+cs.ColumnName] {
+		keys = append(keys, "FK")
+	}
+	if cs.ColumnKey == "UNI" {
+		keys = append(keys, "UK")
+	}
+	return keys
+}
+
+// erTarget returns the table a foreign key references, qualified when it is
+// in another schema.
+func erTarget(fk ForeignKey) string {
+	if fk.ReferencedSchema != "" {
+		return fk.ReferencedSchema + "." + fk.ReferencedTableName
+	}
+	return fk.ReferencedTableName
+}
+
+// writeERDiagram writes a diagram of the tables, their columns and keys, and
+// a relationship per foreign key, as a Mermaid erDiagram or, for .dot and .gv
+// files, a Graphviz digraph. In Mermaid, a child row has zero or one parent
+// when its foreign key columns are nullable, else exactly one.
+func writeERDiagram(path string, schema Schema) error {
+	foreignKeys := map[string]bool{}
+	nullable := map[string]bool{}
+	for _, cs := range schema.Columns {
+		nullable[cs.TableName+"."+cs.ColumnName] = cs.IsNullable == "YES"
+	}
+	// A constraint per relationship, with all its columns.
+	constraints := []ForeignKey{}
+	columns := map[string][]string{}
+	for _, fk := range schema.ForeignKeys {
+		foreignKeys[fk.TableName+"."+fk.ColumnName] = true
+		key := fk.TableName + "." + fk.ConstraintName
+		if _, ok := columns[key]; !ok {
+			constraints = append(constraints, fk)
+		}
+		columns[key] = append(columns[key], fk.ColumnName)
+	}
+
+	var buffer bytes.Buffer
+	if strings.HasSuffix(path, ".dot") || strings.HasSuffix(path, ".gv") {
+		buffer.WriteString("digraph " + strconv.Quote(config.DbName) + " {\n")
+		buffer.WriteString("  rankdir=LR;\n  node [shape=plaintext];\n")
+		for _, table := range groupTables(schema.Columns) {
+			buffer.WriteString("  " + strconv.Quote(table.Name) + " [label=<<table border=\"0\" cellborder=\"1\" cellspacing=\"0\">\n")
+			buffer.WriteString("    <tr><td colspan=\"2\"><b>" + html.EscapeString(table.Name) + "</b></td></tr>\n")
+			for _, cs := range table.Columns {
+				name := html.EscapeString(cs.ColumnName)
+				if keys := erKeys(cs, foreignKeys); len(keys) > 0 {
+					name += " (" + strings.Join(keys, ", ") + ")"
+				}
+				buffer.WriteString("    <tr><td align=\"left\">" + name + "</td><td align=\"left\">" + html.EscapeString(cs.ColumnType) + "</td></tr>\n")
+			}
+			buffer.WriteString("  </table>>];\n")
+		}
+		for _, fk := range constraints {
+			label := strings.Join(columns[fk.TableName+"."+fk.ConstraintName], ", ")
+			buffer.WriteString("  " + strconv.Quote(fk.TableName) + " -> " + strconv.Quote(erTarget(fk)) + " [label=" + strconv.Quote(label) + "];\n")
+		}
+		buffer.WriteString("}\n")
+		return writeOutput(path, buffer.Bytes())
+	}
+
+	quote := func(name string) string {
+		if mermaidName.MatchString(name) {
+			return name
+		}
+		return strconv.Quote(name)
+	}
+	buffer.WriteString("erDiagram\n")
+	for _, table := range groupTables(schema.Columns) {
+		buffer.WriteString("  " + quote(table.Name) + " {\n")
+		for _, cs := range table.Columns {
+			line := "    " + cs.DataType + " " + quote(cs.ColumnName)
+			if keys := erKeys(cs, foreignKeys); len(keys) > 0 {
+				line += " " + strings.Join(keys, ", ")
+			}
+			if cs.ColumnComment != "" {
+				// Mermaid comments can't hold double quotes nor line breaks.
+				line += " \"" + strings.Join(strings.Fields(strings.Replace(cs.ColumnComment, "\"", "'", -1)), " ") + "\""
+			}
+			buffer.WriteString(line + "\n")
+		}
+		buffer.WriteString("  }\n")
+	}
+	for _, fk := range constraints {
+		fkColumns := columns[fk.TableName+"."+fk.ConstraintName]
+		parent := "||"
+		for _, column := range fkColumns {
+			if nullable[fk.TableName+"."+column] {
+				parent = "|o"
+			}
+		}
+		buffer.WriteString("  " + quote(erTarget(fk)) + " " + parent + "--o{ " + quote(fk.TableName) + " : " + strconv.Quote(strings.Join(fkColumns, ", ")) + "\n")
+	}
+	return writeOutput(path, buffer.Bytes())
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+)
+
+// Exit codes, telling the class of failure apart for scripts.
+const (
+	exitFailure     = 1   // any other failure
+	exitConfig      = 2   // invalid settings or flags
+	exitConnection  = 3   // can't connect to or query the database
+	exitUnknownType = 4   // a column type without a Go type
+	exitWrite       = 5   // can't write an output file
+	exitDrift       = 6   // check found outputs differing from their files
+	exitInterrupted = 130 // interrupted by SIGINT or SIGTERM
+)
+
+// exitError gives an error the exit code of its class.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string { return e.err.Error() }
+
+func (e *exitError) Unwrap() error { return e.err }
+
+// classify returns err with the exit code of its class, keeping the class it
+// already has; nil stays nil.
+func classify(code int, err error) error {
+	var classified *exitError
+	if err == nil || errors.As(err, &classified) {
+		return err
+	}
+	return &exitError{code, err}
+}
+
+// configError returns an error in the settings or flags.
+func configError(message string) error {
+	return &exitError{exitConfig, errors.New(message)}
+}
+
+// exitCode returns the exit code for err.
+func exitCode(err error) int {
+	if errors.Is(err, context.Canceled) {
+		return exitInterrupted
+	}
+	var classified *exitError
+	if errors.As(err, &classified) {
+		return classified.code
+	}
+	return exitFailure
+}
+
+// exit logs err, when not nil, and exits with the exit code of its class.
+func exit(err error) {
+	if err == nil {
+		return
+	}
+	code := exitCode(err)
+	if code == exitInterrupted {
+		slog.Error("Interrupted")
+	} else {
+		slog.Error(err.Error())
+	}
+	os.Exit(code)
+}
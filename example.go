@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bytes"
+	"strconv"
+	"unicode"
+	"unicode/utf8"
+)
+
+// writeExample writes a test file with one Example function per struct
+// scanning a row into it, which also checks at compile time that every field
+// can be scanned.
+func writeExample(path string, schemas []ColumnSchema) error {
+	buffer := bytes.NewBufferString(buildConstraint() + "package " + config.PkgName + "\n\n")
+	buffer.WriteString("import (\n\t\"database/sql\"\n\t\"fmt\"\n)\n")
+
+	for _, table := range groupTables(schemas) {
+		structName := typeName(table.Name)
+		r := receiverName(structName)
+		columns, targets := "", ""
+		for i, cs := range table.Columns {
+			if i > 0 {
+				columns += ", "
+				targets += ", "
+			}
+			columns += selectList([]ColumnSchema{cs})
+			targets += "&" + r + "." + fieldName(cs)
+		}
+
+		buffer.WriteString("\nfunc Example" + exampleSuffix(structName) + "() {\n")
+		buffer.WriteString("\tvar db *sql.DB // opened with sql.Open(\"mysql\", dsn)\n\n")
+		buffer.WriteString("\trow := db.QueryRow(" + strconv.Quote("SELECT "+columns+" FROM "+tableIdent(table.Name)+" LIMIT 1") + ")\n\n")
+		buffer.WriteString("\tvar " + r + " " + structName + "\n")
+		buffer.WriteString("\tif err := row.Scan(" + targets + "); err != nil {\n")
+		buffer.WriteString("\t\tfmt.Println(err)\n")
+		buffer.WriteString("\t\treturn\n")
+		buffer.WriteString("\t}\n")
+		buffer.WriteString("\tfmt.Println(" + r + ")\n}\n")
+	}
+
+	return writeOutput(path, buffer.Bytes())
+}
+
+// exampleSuffix returns the part of an Example function name referring to
+// structName; go vet expects examples of unexported types to use an
+// underscore suffix instead.
+func exampleSuffix(structName string) string {
+	if r, _ := utf8.DecodeRuneInString(structName); unicode.IsLower(r) {
+		return "_" + structName
+	}
+	return structName
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// integerMax holds the largest value of each signed MySQL integer type.
+var integerMax = map[string]string{
+	"tinyint":   "127",
+	"smallint":  "32767",
+	"mediumint": "8388607",
+	"int":       "2147483647",
+	"bigint":    "9223372036854775807",
+}
+
+// packageImportPath returns the import path of the package in dir, found by
+// looking for the go.mod of the enclosing module, or in the -init-module one.
+func packageImportPath(dir string) (string, error) {
+	if *initModule != "" {
+		if rel, ok := moduleDir(dir); ok {
+			return path.Join(*initModule, rel), nil
+		}
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for root := dir; ; root = filepath.Dir(root) {
+		data, err := os.ReadFile(filepath.Join(root, "go.mod"))
+		if err == nil {
+			for _, line := range strings.Split(string(data), "\n") {
+				if fields := strings.Fields(line); len(fields) == 2 && fields[0] == "module" {
+					rel, err := filepath.Rel(root, dir)
+					if err != nil {
+						return "", err
+					}
+					return path.Join(strings.Trim(fields[1], `"`), filepath.ToSlash(rel)), nil
+				}
+			}
+			return "", errors.New("No module line in " + filepath.Join(root, "go.mod"))
+		}
+		if filepath.Dir(root) == root {
+			return "", errors.New("No go.mod found above " + dir)
+		}
+	}
+}
+
+// factoryValue returns a Go expression producing a valid value for a NOT NULL
+// column, using the factory package helpers, and the import it requires.
+func factoryValue(cs ColumnSchema, goType string) (string, string, bool) {
+	if isSpatial(cs) {
+		return spatialValue(cs)
+	}
+	switch goType {
+	case "string", "[]byte":
+		value := ""
+		if values := enumValues(cs); cs.DataType == "enum" && len(values) > 0 {
+			value = strconv.Quote(values[0])
+		} else {
+			// text is ASCII, one byte per character
+			max := "65535"
+			if chars, bytes, ok := maxLength(cs); ok && chars > 0 {
+				max = strconv.FormatInt(chars, 10)
+			} else if ok {
+				max = strconv.FormatInt(bytes, 10)
+			}
+			value = "text(" + strconv.Quote(cs.ColumnName) + ", " + max + ")"
+		}
+		if goType == "[]byte" {
+			value = "[]byte(" + value + ")"
+		}
+		return value, "", true
+	case "int64":
+		max, ok := integerMax[cs.DataType]
+		if !ok {
+			return "", "", false
+		}
+		return "number(" + max + ")", "", true
+	case "float64":
+		// keep decimals within their integer digits
+		max := "1000000"
+		if cs.DataType == "decimal" && cs.NumericPrecision.Valid && cs.NumericScale.Valid {
+			digits := cs.NumericPrecision.Int64 - cs.NumericScale.Int64
+			if digits < 1 {
+				return "0", "", true
+			}
+			if digits < 6 {
+				max = strings.Repeat("9", int(digits))
+			}
+		}
+		return "float64(number(" + max + "))", "", true
+	case "time.Time":
+		return "time.Now().UTC().Truncate(time.Second)", "time", true
+	}
+	return "", "", false
+}
+
+// writeValueHelpers emits the functions used by the factoryValue expressions;
+// they need the strconv and sync/atomic imports.
+func writeValueHelpers(buffer *bytes.Buffer) {
+	buffer.WriteString("var sequence int64\n\n")
+	buffer.WriteString("func next() int64 {\n\treturn atomic.AddInt64(&sequence, 1)\n}\n\n")
+	buffer.WriteString("// text returns a unique string starting with prefix, keeping its last max\n")
+	buffer.WriteString("// bytes when too long.\n")
+	buffer.WriteString("func text(prefix string, max int) string {\n")
+	buffer.WriteString("\ts := prefix + \"_\" + strconv.FormatInt(next(), 10)\n")
+	buffer.WriteString("\tif len(s) > max {\n\t\ts = s[len(s)-max:]\n\t}\n")
+	buffer.WriteString("\treturn s\n}\n\n")
+	buffer.WriteString("// number returns a number between 1 and max, unique until it wraps around.\n")
+	buffer.WriteString("func number(max int64) int64 {\n\treturn (next()-1)%max + 1\n}\n")
+}
+
+// writeFactory writes a factory package to dir with a function per struct
+// returning an instance with every NOT NULL column set to a valid value:
+// the first enum value, unique strings cut to the column length and numbers
+// within the column type. Nullable, auto increment and generated columns are
+// left unset.
+func writeFactory(dir string, schemas []ColumnSchema) error {
+	if *unexported != "" {
+		return errors.New("-factory can't reference unexported types or fields")
+	}
+	modelsDir := "."
+	if *output != "-" {
+		modelsDir = filepath.Dir(*output)
+	}
+	modelsPath, err := packageImportPath(modelsDir)
+	if err != nil {
+		return err
+	}
+
+	body := &bytes.Buffer{}
+	usesTime := false
+	for _, table := range groupTables(schemas) {
+		structName := typeName(table.Name)
+		r := receiverName(structName)
+		model := config.PkgName + "." + structName
+
+		body.WriteString("\n// " + structName + " returns a " + model + " with valid values for its NOT NULL\n")
+		body.WriteString("// columns, applying overrides in order.\n")
+		body.WriteString("func " + structName + "(overrides ...func(*" + model + ")) *" + model + " {\n")
+		body.WriteString("\t" + r + " := &" + model + "{}\n")
+		for _, cs := range table.Columns {
+			if cs.IsNullable == "YES" || hasExtra(cs, "auto_increment") || isReadOnly(cs) {
+				continue
+			}
+			if _, overridden := config.TypeOverrides[cs.TableName+"."+cs.ColumnName]; overridden {
+				continue
+			}
+			gt, _, err := goType(&cs)
+			if err != nil {
+				return err
+			}
+			value, requiredImport, ok := factoryValue(cs, gt)
+			if !ok {
+				continue
+			}
+			usesTime = usesTime || requiredImport == "time"
+			if *accessors {
+				body.WriteString("\t" + r + ".Set" + columnGoName(cs.TableName, cs.ColumnName) + "(" + value + ")\n")
+			} else {
+				body.WriteString("\t" + r + "." + fieldName(cs) + " = " + value + "\n")
+			}
+		}
+		body.WriteString("\tfor _, override := range overrides {\n")
+		body.WriteString("\t\toverride(" + r + ")\n\t}\n")
+		body.WriteString("\treturn " + r + "\n}\n")
+	}
+
+	buffer := bytes.NewBufferString(buildConstraint())
+	buffer.WriteString("// Package factory builds valid " + config.PkgName + " structs for tests.\n")
+	buffer.WriteString("package factory\n\n")
+	buffer.WriteString("import (\n\t\"strconv\"\n\t\"sync/atomic\"\n")
+	if usesTime {
+		buffer.WriteString("\t\"time\"\n")
+	}
+	if path.Base(modelsPath) == config.PkgName {
+		buffer.WriteString("\n\t" + strconv.Quote(modelsPath) + "\n)\n\n")
+	} else {
+		buffer.WriteString("\n\t" + config.PkgName + " " + strconv.Quote(modelsPath) + "\n)\n\n")
+	}
+
+	writeValueHelpers(buffer)
+	buffer.Write(body.Bytes())
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	return writeOutput(filepath.Join(dir, "factory.go"), buffer.Bytes())
+}
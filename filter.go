@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+)
+
+// writeFilter emits an XFilter struct with an optional field per column and a
+// Where() method turning the set fields into a WHERE clause and its args.
+// A set null value matches NULL.
+func writeFilter(buffer *bytes.Buffer, structName string, table Table) {
+	filterName := structName + "Filter"
+
+	buffer.WriteString("// " + filterName + " selects " + structName + " rows; nil fields are ignored.\n")
+	buffer.WriteString("type " + filterName + " struct {\n")
+	for _, cs := range table.Columns {
+		gt, _, err := goType(&cs)
+		if err != nil {
+			continue
+		}
+		buffer.WriteString("\t" + columnGoName(cs.TableName, cs.ColumnName) + " *" + gt + "\n")
+	}
+	buffer.WriteString("}\n\n")
+
+	buffer.WriteString("// Where returns the WHERE clause matching the filter, empty if no field is\n// set, and its args.\n")
+	buffer.WriteString("func (f " + filterName + ") Where() (string, []interface{}) {\n")
+	buffer.WriteString("\tconds := []string{}\n")
+	buffer.WriteString("\targs := []interface{}{}\n")
+	for _, cs := range table.Columns {
+		gt, _, err := goType(&cs)
+		if err != nil {
+			continue
+		}
+		field := "f." + columnGoName(cs.TableName, cs.ColumnName)
+		column := quoteIdent(cs.ColumnName)
+		buffer.WriteString("\tif " + field + " != nil {\n")
+		if sqlType, _ := sqlNullType(gt); strings.HasPrefix(sqlType, "sql.Null") {
+			buffer.WriteString("\t\tif !" + field + ".Valid {\n")
+			buffer.WriteString("\t\t\tconds = append(conds, " + strconv.Quote(column+" IS NULL") + ")\n")
+			buffer.WriteString("\t\t} else {\n")
+			buffer.WriteString("\t\t\tconds = append(conds, " + strconv.Quote(column+" = ?") + ")\n")
+			buffer.WriteString("\t\t\targs = append(args, *" + field + ")\n")
+			buffer.WriteString("\t\t}\n")
+		} else {
+			buffer.WriteString("\t\tconds = append(conds, " + strconv.Quote(column+" = ?") + ")\n")
+			buffer.WriteString("\t\targs = append(args, *" + field + ")\n")
+		}
+		buffer.WriteString("\t}\n")
+	}
+	buffer.WriteString("\tif len(conds) == 0 {\n")
+	buffer.WriteString("\t\treturn \"\", args\n\t}\n")
+	buffer.WriteString("\treturn \" WHERE \" + strings.Join(conds, \" AND \"), args\n}")
+}
+
+// writeCount emits CountX, counting the rows matching an XFilter, and for
+// tables with a primary key ExistsXByPK. For tables with a soft_delete_column
+// both skip deleted rows, as Get does, and their WithDeleted variants don't.
+func writeCount(buffer *bytes.Buffer, structName string, table Table) {
+	deleted := ""
+	if cs, ok := softDeleteColumn(table); ok {
+		deleted = quoteIdent(cs.ColumnName) + " IS NULL"
+	}
+	count := funcName("Count", structName)
+	writeCountQuery(buffer, count, structName, table, deleted)
+	if deleted != "" {
+		buffer.WriteString("\n\n")
+		writeCountQuery(buffer, count+"WithDeleted", structName, table, "")
+	}
+
+	pk := primaryKey(table)
+	if len(pk) == 0 {
+		return
+	}
+	params, args, wheres := []string{}, []string{}, []string{}
+	for _, cs := range pk {
+		gt, _, err := goType(&cs)
+		if err != nil {
+			return
+		}
+		param := paramName(cs)
+		params = append(params, param+" "+gt)
+		args = append(args, param)
+		wheres = append(wheres, quoteIdent(cs.ColumnName)+" = ?")
+	}
+	exists := funcName("Exists", structName) + "ByPK"
+	query := "SELECT EXISTS(SELECT 1 FROM " + tableIdent(table.Name) + " WHERE " + strings.Join(wheres, " AND ")
+	writeExistsQuery(buffer, exists, table, strings.Join(params, ", "), strings.Join(args, ", "), query+notDeleted(table)+")")
+	if deleted != "" {
+		writeExistsQuery(buffer, exists+"WithDeleted", table, strings.Join(params, ", "), strings.Join(args, ", "), query+")")
+	}
+}
+
+// writeCountQuery emits a function named name counting the rows matching an
+// XFilter and, unless empty, the deleted condition.
+func writeCountQuery(buffer *bytes.Buffer, name, structName string, table Table, deleted string) {
+	count := "SELECT COUNT(*) FROM " + tableIdent(table.Name)
+	buffer.WriteString("func " + name + "(ctx context.Context, " + dbParam() + ", filter " + structName + "Filter) (int64, error) {\n")
+	writeQueryLabel(buffer, name, table.Name)
+	buffer.WriteString("\twhere, args := filter.Where()\n")
+	if deleted != "" {
+		buffer.WriteString("\tif where == \"\" {\n")
+		buffer.WriteString("\t\twhere = " + strconv.Quote(" WHERE "+deleted) + "\n")
+		buffer.WriteString("\t} else {\n")
+		buffer.WriteString("\t\twhere += " + strconv.Quote(" AND "+deleted) + "\n\t}\n")
+	}
+	buffer.WriteString("\tvar n int64\n")
+	buffer.WriteString("\terr := db.QueryRowContext(ctx, " + strconv.Quote(count) + "+where, args...).Scan(&n)\n")
+	buffer.WriteString("\treturn n, err\n}")
+}
+
+// writeExistsQuery emits a function named name reporting whether query,
+// run with args, finds a row, preceded by a blank line.
+func writeExistsQuery(buffer *bytes.Buffer, name string, table Table, params, args, query string) {
+	buffer.WriteString("\n\nfunc " + name + "(ctx context.Context, " + dbParam() + ", " + params + ") (bool, error) {\n")
+	writeQueryLabel(buffer, name, table.Name)
+	buffer.WriteString("\tvar exists bool\n")
+	buffer.WriteString("\terr := db.QueryRowContext(ctx, " + strconv.Quote(query) + ", " + args + ").Scan(&exists)\n")
+	buffer.WriteString("\treturn exists, err\n}")
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var flatBuffersDir = flag.String("flatbuffers", "", "Also write a FlatBuffers schema (.fbs) per table to this directory")
+
+// flatBuffersType returns the FlatBuffers type of a column and, for the
+// columns FlatBuffers has no type for, a comment saying how they are held.
+func flatBuffersType(cs ColumnSchema) (string, string) {
+	unsigned := strings.Contains(cs.ColumnType, "unsigned")
+	pick := func(signed, unsignedType string) string {
+		if unsigned {
+			return unsignedType
+		}
+		return signed
+	}
+	switch cs.DataType {
+	case "tinyint":
+		if cs.ColumnType == "tinyint(1)" {
+			return "bool", ""
+		}
+		return pick("byte", "ubyte"), ""
+	case "smallint":
+		return pick("short", "ushort"), ""
+	case "mediumint", "int":
+		return pick("int", "uint"), ""
+	case "bigint":
+		return pick("long", "ulong"), ""
+	case "year":
+		return "short", ""
+	case "float":
+		return "float", ""
+	case "double":
+		return "double", ""
+	case "decimal":
+		return "string", strings.ToUpper(cs.ColumnType) + " as its exact decimal string, e.g. \"12.30\"."
+	case "json":
+		return "string", "JSON document as text."
+	case "date":
+		return "int", "DATE as days since 1970-01-01."
+	case "datetime", "timestamp":
+		return "long", strings.ToUpper(cs.DataType) + " as microseconds since 1970-01-01 00:00:00 UTC."
+	case "time":
+		return "long", "TIME as microseconds."
+	case "enum", "set":
+		return "string", ""
+	}
+	if strings.Contains(cs.DataType, "blob") || strings.Contains(cs.DataType, "binary") {
+		return "[ubyte]", ""
+	}
+	if isSpatial(cs) {
+		return "[ubyte]", spatialComment(cs)
+	}
+	return "string", ""
+}
+
+// writeFlatBuffers writes a FlatBuffers schema per table to dir, named after
+// the table, e.g. users.fbs, with a table named as its struct as root type.
+// Nullable scalars default to null, which needs flatc 2.0, and NOT NULL
+// strings and vectors are required.
+func writeFlatBuffers(dir string, schemas []ColumnSchema) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for _, table := range groupTables(schemas) {
+		name := plainIdent(typeName(table.Name))
+		buffer := bytes.NewBufferString("namespace " + config.PkgName + ";\n\n")
+		buffer.WriteString("table " + name + " {\n")
+		for _, cs := range table.Columns {
+			fbType, note := flatBuffersType(cs)
+			for _, comment := range []string{strings.Join(strings.Fields(cs.ColumnComment), " "), note} {
+				if comment != "" {
+					buffer.WriteString("  /// " + comment + "\n")
+				}
+			}
+			line := "  " + plainIdent(cs.ColumnName) + ":" + fbType
+			scalar := fbType != "string" && fbType != "[ubyte]"
+			switch {
+			case scalar && cs.IsNullable == "YES":
+				line += " = null"
+			case !scalar && cs.IsNullable != "YES":
+				line += " (required)"
+			}
+			buffer.WriteString(line + ";\n")
+		}
+		buffer.WriteString("}\n\nroot_type " + name + ";\n")
+		if err := writeOutput(filepath.Join(dir, table.Name+".fbs"), buffer.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
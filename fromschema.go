@@ -0,0 +1,27 @@
+package main
+
+import (
+	"flag"
+)
+
+var fromSchema = flag.String("from-schema", "", "Generate from a dump-schema snapshot instead of the database, \"-\" reading it from stdin")
+
+// snapshotSchema returns the schema of the -from-schema snapshot, keeping the
+// tables and columns to generate as getSchema does. The snapshot holds the
+// metadata dump-schema reads, so only the flags needing the database itself
+// (-watch, -cache, db_names) are refused.
+func snapshotSchema() (Schema, error) {
+	switch {
+	case *watch > 0:
+		return Schema{}, configError("-watch can't be used with -from-schema")
+	case *cacheIntrospection:
+		return Schema{}, configError("-cache can't be used with -from-schema")
+	case len(config.DbNames) > 0:
+		return Schema{}, configError("db_names can't be used with -from-schema, which holds a single schema")
+	}
+	schema, err := readSnapshot(*fromSchema)
+	if err != nil {
+		return schema, err
+	}
+	return filterColumns(filterTables(schema)), nil
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+var goGenerate = flag.Bool("go-generate", false, "Add a //go:generate line reproducing the invocation to the structs, so that go generate regenerates them")
+
+// generateArgs are the arguments of the generate command, reproduced by the
+// //go:generate line.
+var generateArgs []string
+
+// goGenerateDirective is the prefix of the line added by -go-generate.
+const goGenerateDirective = "//go:generate struct-create "
+
+// goGenerateLine returns the //go:generate line running generate with the
+// arguments of this run from the directory of the structs, where go generate
+// runs it: relative paths of file flags are rebased on that directory.
+func goGenerateLine() (string, error) {
+	dir, err := filepath.Abs(filepath.Dir(*output))
+	if err != nil {
+		return "", err
+	}
+	args := []string{}
+	for i := 0; i < len(generateArgs); i++ {
+		arg := generateArgs[i]
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		f := flag.CommandLine.Lookup(name)
+		if !strings.HasPrefix(arg, "-") || f == nil {
+			args = append(args, quoteArg(arg))
+			continue
+		}
+		if !hasValue && !isBoolFlag(f) && i+1 < len(generateArgs) {
+			i++
+			value, hasValue = generateArgs[i], true
+		}
+		if skippedGenerateFlags[name] {
+			continue
+		}
+		if isFileFlag(name) && value != "-" && value != "" && !filepath.IsAbs(value) {
+			abs, err := filepath.Abs(value)
+			if err != nil {
+				return "", err
+			}
+			if value, err = filepath.Rel(dir, abs); err != nil {
+				return "", err
+			}
+		}
+		switch {
+		case !isBoolFlag(f):
+			args = append(args, "-"+name, quoteArg(value))
+		case hasValue:
+			args = append(args, "-"+name+"="+value)
+		default:
+			args = append(args, "-"+name)
+		}
+	}
+	return goGenerateDirective + strings.Join(args, " ") + "\n", nil
+}
+
+// skippedGenerateFlags are the flags of the run left out of the
+// //go:generate line, as go generate runs once and writes the files.
+var skippedGenerateFlags = map[string]bool{"watch": true, "dry-run": true}
+
+// quoteArg quotes an argument for go generate when it has spaces, quotes or
+// is empty.
+func quoteArg(arg string) string {
+	if arg == "" || strings.ContainsAny(arg, " \t\"'") {
+		return strconv.Quote(arg)
+	}
+	return arg
+}
+
+// regenerateCommand implements the regenerate command, running generate
+// with the arguments of the //go:generate line of a Go file from its
+// directory, as go generate would.
+func regenerateCommand(ctx context.Context, args []string) error {
+	if len(args) != 1 {
+		return configError("Usage: struct-create regenerate file.go")
+	}
+	line, err := readGoGenerate(args[0])
+	if err != nil {
+		return err
+	}
+	generateArgs, err := splitArgs(line)
+	if err != nil {
+		return err
+	}
+	if err := os.Chdir(filepath.Dir(args[0])); err != nil {
+		return classify(exitConfig, err)
+	}
+	return generateCommand(ctx, generateArgs)
+}
+
+// readGoGenerate returns the arguments of the //go:generate struct-create
+// line of a Go file.
+func readGoGenerate(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", classify(exitConfig, err)
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if line := scanner.Text(); strings.HasPrefix(line, goGenerateDirective) {
+			return strings.TrimPrefix(line, goGenerateDirective), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", classify(exitConfig, err)
+	}
+	return "", configError("No //go:generate struct-create line in " + path)
+}
+
+// splitArgs splits a //go:generate line into its arguments, separated by
+// spaces or tabs, as go generate does, unquoting double-quoted ones.
+func splitArgs(line string) ([]string, error) {
+	args := []string{}
+	for line = strings.TrimSpace(line); line != ""; line = strings.TrimLeft(line, " \t") {
+		if line[0] != '"' {
+			end := strings.IndexAny(line, " \t")
+			if end < 0 {
+				end = len(line)
+			}
+			args = append(args, line[:end])
+			line = line[end:]
+			continue
+		}
+		quoted, err := strconv.QuotedPrefix(line)
+		if err != nil {
+			return nil, configError("Invalid //go:generate line: " + err.Error())
+		}
+		arg, _ := strconv.Unquote(quoted)
+		args = append(args, arg)
+		line = line[len(quoted):]
+	}
+	return args, nil
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// writeGoldenTest writes a test file checking that the tag_label tags of each
+// struct are the columns of its table in testdata/schema.json, a snapshot of
+// the schema written next to it in the format of dump-schema. Refreshing the
+// snapshot with dump-schema in CI then fails the test until the structs are
+// regenerated.
+func writeGoldenTest(path string, schemas []ColumnSchema) error {
+	if config.TagLabel == "" {
+		return configError("-golden-test checks the tag_label tags and needs a tag_label")
+	}
+
+	snapshot, err := json.MarshalIndent(Schema{Columns: schemas}, "", "  ")
+	if err != nil {
+		return err
+	}
+	testdata := filepath.Join(filepath.Dir(path), "testdata")
+	if err := os.MkdirAll(testdata, 0755); err != nil {
+		return err
+	}
+	if err := writeOutput(filepath.Join(testdata, "schema.json"), append(snapshot, '\n')); err != nil {
+		return err
+	}
+
+	buffer := bytes.NewBufferString(buildConstraint() + "package " + config.PkgName + "\n\n")
+	buffer.WriteString("import (\n\t\"encoding/json\"\n\t\"os\"\n\t\"reflect\"\n\t\"sort\"\n\t\"strings\"\n\t\"testing\"\n)\n\n")
+
+	buffer.WriteString("// goldenStructs are the generated structs of each table.\n")
+	buffer.WriteString("var goldenStructs = map[string]reflect.Type{\n")
+	for _, table := range groupTables(schemas) {
+		buffer.WriteString("\t" + strconv.Quote(table.Name) + ": reflect.TypeOf(" + typeName(table.Name) + "{}),\n")
+	}
+	buffer.WriteString("}\n\n")
+
+	buffer.WriteString("// goldenColumns returns the sorted " + config.TagLabel + " tags of a struct and the\n")
+	buffer.WriteString("// structs it embeds.\n")
+	buffer.WriteString("func goldenColumns(t reflect.Type) []string {\n")
+	buffer.WriteString("\tcolumns := []string{}\n")
+	buffer.WriteString("\tfor i := 0; i < t.NumField(); i++ {\n")
+	buffer.WriteString("\t\tf := t.Field(i)\n")
+	buffer.WriteString("\t\tif f.Anonymous && f.Type.Kind() == reflect.Struct {\n")
+	buffer.WriteString("\t\t\tcolumns = append(columns, goldenColumns(f.Type)...)\n")
+	buffer.WriteString("\t\t\tcontinue\n")
+	buffer.WriteString("\t\t}\n")
+	buffer.WriteString("\t\tif name, _, _ := strings.Cut(f.Tag.Get(" + strconv.Quote(config.TagLabel) + "), \",\"); name != \"\" && name != \"-\" {\n")
+	buffer.WriteString("\t\t\tcolumns = append(columns, name)\n")
+	buffer.WriteString("\t\t}\n")
+	buffer.WriteString("\t}\n")
+	buffer.WriteString("\tsort.Strings(columns)\n")
+	buffer.WriteString("\treturn columns\n")
+	buffer.WriteString("}\n\n")
+
+	buffer.WriteString("func TestStructsMatchSchema(t *testing.T) {\n")
+	buffer.WriteString("\tdata, err := os.ReadFile(\"testdata/schema.json\")\n")
+	buffer.WriteString("\tif err != nil {\n\t\tt.Fatal(err)\n\t}\n")
+	buffer.WriteString("\tvar snapshot struct {\n\t\tColumns []struct{ TableName, ColumnName string }\n\t}\n")
+	buffer.WriteString("\tif err := json.Unmarshal(data, &snapshot); err != nil {\n\t\tt.Fatal(err)\n\t}\n")
+	buffer.WriteString("\ttables := map[string][]string{}\n")
+	buffer.WriteString("\tfor _, c := range snapshot.Columns {\n")
+	buffer.WriteString("\t\ttables[c.TableName] = append(tables[c.TableName], c.ColumnName)\n")
+	buffer.WriteString("\t}\n\n")
+	buffer.WriteString("\tfor table, columns := range tables {\n")
+	buffer.WriteString("\t\tst, ok := goldenStructs[table]\n")
+	buffer.WriteString("\t\tif !ok {\n")
+	buffer.WriteString("\t\t\tt.Errorf(\"table %s has no struct\", table)\n")
+	buffer.WriteString("\t\t\tcontinue\n")
+	buffer.WriteString("\t\t}\n")
+	buffer.WriteString("\t\tsort.Strings(columns)\n")
+	buffer.WriteString("\t\tif fields := goldenColumns(st); !reflect.DeepEqual(fields, columns) {\n")
+	buffer.WriteString("\t\t\tt.Errorf(\"%s has %d fields %v, table %s has %d columns %v\", st.Name(), len(fields), fields, table, len(columns), columns)\n")
+	buffer.WriteString("\t\t}\n")
+	buffer.WriteString("\t}\n")
+	buffer.WriteString("\tfor table, st := range goldenStructs {\n")
+	buffer.WriteString("\t\tif _, ok := tables[table]; !ok {\n")
+	buffer.WriteString("\t\t\tt.Errorf(\"%s has no table %s\", st.Name(), table)\n")
+	buffer.WriteString("\t\t}\n")
+	buffer.WriteString("\t}\n")
+	buffer.WriteString("}\n")
+
+	return writeOutput(path, buffer.Bytes())
+}
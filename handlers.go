@@ -0,0 +1,170 @@
+package main
+
+import "bytes"
+
+// writeHandlerTypes emits the XRequest JSON body, leaving out auto increment
+// and generated columns, and the XResponse JSON representation, leaving out sensitive
+// columns, with the functions converting them from and to the struct.
+func writeHandlerTypes(buffer *bytes.Buffer, structName string, table Table) {
+	request, response := structName+"Request", structName+"Response"
+
+	buffer.WriteString("// " + request + " is the JSON body creating or updating a " + structName + ".\n")
+	buffer.WriteString("type " + request + " struct {\n")
+	apply := ""
+	for _, cs := range table.Columns {
+		gt, _, err := goType(&cs)
+		if err != nil || hasExtra(cs, "auto_increment") || isReadOnly(cs) {
+			continue
+		}
+		buffer.WriteString("\t" + columnGoName(cs.TableName, cs.ColumnName) + " " + gt + " " + structTag([]string{tag("json", cs.ColumnName)}) + "\n")
+		apply += "\trow." + fieldName(cs) + " = body." + columnGoName(cs.TableName, cs.ColumnName) + "\n"
+	}
+	buffer.WriteString("}\n\n")
+
+	buffer.WriteString("func (body " + request + ") apply(row *" + structName + ") {\n")
+	buffer.WriteString(apply + "}\n\n")
+
+	buffer.WriteString("// " + response + " is the JSON representation of a " + structName + ".\n")
+	buffer.WriteString("type " + response + " struct {\n")
+	fields := ""
+	for _, cs := range table.Columns {
+		gt, _, err := goType(&cs)
+		if err != nil || isSensitive(cs) {
+			continue
+		}
+		buffer.WriteString("\t" + columnGoName(cs.TableName, cs.ColumnName) + " " + gt + " " + structTag([]string{tag("json", cs.ColumnName)}) + "\n")
+		fields += "\t\t" + columnGoName(cs.TableName, cs.ColumnName) + ": row." + fieldName(cs) + ",\n"
+	}
+	buffer.WriteString("}\n\n")
+
+	buffer.WriteString("func " + funcName("New", response) + "(row *" + structName + ") " + response + " {\n")
+	buffer.WriteString("\treturn " + response + "{\n" + fields + "\t}\n}")
+}
+
+// writeHandlers emits a RegisterXHandlers function serving the table's CRUD
+// functions as JSON on a net/http ServeMux: POST /table and, for tables whose
+// primary key columns are integers or strings, GET, PUT and DELETE
+// /table/{id}, or /table/{a}/{b} with a path value per column of a composite
+// key.
+func writeHandlers(buffer *bytes.Buffer, structName string, table Table) {
+	prefix := "/" + table.Name
+	request, response := structName+"Request", structName+"Response"
+	newResponse := funcName("New", response)
+
+	buffer.WriteString("// " + funcName("Register", structName) + "Handlers serves " + table.Name + " rows under " + prefix + " on mux.\n")
+	buffer.WriteString("func " + funcName("Register", structName) + "Handlers(mux *http.ServeMux, " + dbParam() + ") {\n")
+
+	buffer.WriteString("\tmux.HandleFunc(\"POST " + prefix + "\", func(w http.ResponseWriter, req *http.Request) {\n")
+	buffer.WriteString("\t\tvar body " + request + "\n")
+	buffer.WriteString("\t\tif err := json.NewDecoder(req.Body).Decode(&body); err != nil {\n")
+	buffer.WriteString("\t\t\thttp.Error(w, err.Error(), http.StatusBadRequest)\n\t\t\treturn\n\t\t}\n")
+	buffer.WriteString("\t\trow := &" + structName + "{}\n")
+	buffer.WriteString("\t\tbody.apply(row)\n")
+	buffer.WriteString("\t\tif err := " + funcName("Insert", structName) + "(req.Context(), db, row); err != nil {\n")
+	buffer.WriteString("\t\t\tserverError(w, req, err)\n\t\t\treturn\n\t\t}\n")
+	buffer.WriteString("\t\twriteJSON(w, http.StatusCreated, " + newResponse + "(row))\n")
+	buffer.WriteString("\t})\n")
+
+	pk := primaryKey(table)
+	if !servesKey(pk) {
+		buffer.WriteString("}")
+		return
+	}
+	item, parseKey, get, keyArg, setKey := prefix, "", funcName("Get", structName)+"ByKey", "key", ""
+	if len(pk) == 1 {
+		item += "/{id}"
+		if gt, _, _ := goType(&pk[0]); gt == "int64" {
+			parseKey = "\t\tid, err := strconv.ParseInt(req.PathValue(\"id\"), 10, 64)\n" +
+				"\t\tif err != nil {\n\t\t\thttp.Error(w, err.Error(), http.StatusBadRequest)\n\t\t\treturn\n\t\t}\n"
+		} else {
+			parseKey = "\t\tid := req.PathValue(\"id\")\n"
+		}
+		get, keyArg, setKey = funcName("Get", structName)+"ByID", "id", "\t\trow."+fieldName(pk[0])+" = id\n"
+	} else {
+		declare := "\t\tkey := " + structName + "Key{}\n"
+		for _, cs := range pk {
+			name, field := paramName(cs), fieldName(cs)
+			item += "/{" + name + "}"
+			if gt, _, _ := goType(&cs); gt == "int64" {
+				declare = "\t\tkey := " + structName + "Key{}\n\t\tvar err error\n"
+				parseKey += "\t\tif key." + field + ", err = strconv.ParseInt(req.PathValue(\"" + name + "\"), 10, 64); err != nil {\n" +
+					"\t\t\thttp.Error(w, err.Error(), http.StatusBadRequest)\n\t\t\treturn\n\t\t}\n"
+			} else {
+				parseKey += "\t\tkey." + field + " = req.PathValue(\"" + name + "\")\n"
+			}
+			setKey += "\t\trow." + field + " = key." + field + "\n"
+		}
+		parseKey = declare + parseKey
+	}
+
+	buffer.WriteString("\tmux.HandleFunc(\"GET " + item + "\", func(w http.ResponseWriter, req *http.Request) {\n")
+	buffer.WriteString(parseKey)
+	buffer.WriteString("\t\trow, err := " + get + "(req.Context(), db, " + keyArg + ")\n")
+	buffer.WriteString("\t\tif errors.Is(err, sql.ErrNoRows) {\n\t\t\thttp.NotFound(w, req)\n\t\t\treturn\n\t\t}\n")
+	buffer.WriteString("\t\tif err != nil {\n\t\t\tserverError(w, req, err)\n\t\t\treturn\n\t\t}\n")
+	buffer.WriteString("\t\twriteJSON(w, http.StatusOK, " + newResponse + "(row))\n")
+	buffer.WriteString("\t})\n")
+
+	if len(updateColumns(table)) > 0 {
+		buffer.WriteString("\tmux.HandleFunc(\"PUT " + item + "\", func(w http.ResponseWriter, req *http.Request) {\n")
+		buffer.WriteString(parseKey)
+		buffer.WriteString("\t\tvar body " + request + "\n")
+		buffer.WriteString("\t\tif err := json.NewDecoder(req.Body).Decode(&body); err != nil {\n")
+		buffer.WriteString("\t\t\thttp.Error(w, err.Error(), http.StatusBadRequest)\n\t\t\treturn\n\t\t}\n")
+		buffer.WriteString("\t\trow := &" + structName + "{}\n")
+		buffer.WriteString("\t\tbody.apply(row)\n")
+		buffer.WriteString(setKey)
+		buffer.WriteString("\t\tif err := " + funcName("Update", structName) + "(req.Context(), db, row); err != nil {\n")
+		buffer.WriteString("\t\t\tserverError(w, req, err)\n\t\t\treturn\n\t\t}\n")
+		buffer.WriteString("\t\twriteJSON(w, http.StatusOK, " + newResponse + "(row))\n")
+		buffer.WriteString("\t})\n")
+	}
+
+	buffer.WriteString("\tmux.HandleFunc(\"DELETE " + item + "\", func(w http.ResponseWriter, req *http.Request) {\n")
+	buffer.WriteString(parseKey)
+	buffer.WriteString("\t\tif err := " + funcName("Delete", structName) + "(req.Context(), db, " + keyArg + "); err != nil {\n")
+	buffer.WriteString("\t\t\tserverError(w, req, err)\n\t\t\treturn\n\t\t}\n")
+	buffer.WriteString("\t\tw.WriteHeader(http.StatusNoContent)\n")
+	buffer.WriteString("\t})\n}")
+}
+
+// servesKey reports whether the handlers can take the primary key from the
+// path: it has columns and they are all integers or strings.
+func servesKey(pk []ColumnSchema) bool {
+	for _, cs := range pk {
+		if gt, _, err := goType(&cs); err != nil || gt != "int64" && gt != "string" {
+			return false
+		}
+	}
+	return len(pk) > 0
+}
+
+// handlerImports returns the imports of the handlers of a table.
+func handlerImports(table Table) []string {
+	imports := []string{"encoding/json", "net/http"}
+	pk := primaryKey(table)
+	if !servesKey(pk) {
+		return imports
+	}
+	imports = append(imports, "database/sql", "errors")
+	for _, cs := range pk {
+		if gt, _, _ := goType(&cs); gt == "int64" {
+			return append(imports, "strconv")
+		}
+	}
+	return imports
+}
+
+// writeHTTPHelpers emits the response helpers shared by the handlers; they
+// need the encoding/json, log and net/http imports.
+func writeHTTPHelpers(buffer *bytes.Buffer) {
+	buffer.WriteString("func writeJSON(w http.ResponseWriter, status int, v interface{}) {\n")
+	buffer.WriteString("\tw.Header().Set(\"Content-Type\", \"application/json\")\n")
+	buffer.WriteString("\tw.WriteHeader(status)\n")
+	buffer.WriteString("\tjson.NewEncoder(w).Encode(v)\n}\n\n")
+
+	buffer.WriteString("// serverError logs err and hides it from the client.\n")
+	buffer.WriteString("func serverError(w http.ResponseWriter, req *http.Request, err error) {\n")
+	buffer.WriteString("\tlog.Printf(\"%s %s: %v\", req.Method, req.URL.Path, err)\n")
+	buffer.WriteString("\thttp.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)\n}")
+}
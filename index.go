@@ -0,0 +1,229 @@
+package main
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+)
+
+// indexColumns returns the table columns making up the index, or nil if one
+// of them can't be found.
+func indexColumns(table Table, index Index) []ColumnSchema {
+	columns := []ColumnSchema{}
+	for _, name := range index.Columns {
+		for _, cs := range table.Columns {
+			if cs.ColumnName == name {
+				columns = append(columns, cs)
+			}
+		}
+	}
+	if len(columns) != len(index.Columns) {
+		return nil
+	}
+	return columns
+}
+
+// writeFindBy emits a lookup function per index of the table other than the
+// primary key: GetXByY returning one row for unique indexes, and ListXsByY
+// returning every match for the others. Unique indexes on nullable columns
+// allow several NULL rows and are treated as secondary indexes. Soft deleted
+// rows are skipped except by the WithDeleted variants. With uniqueOnly, only
+// the GetXByY functions are emitted. FULLTEXT and SPATIAL indexes don't serve
+// equality lookups and are skipped. It reports whether it emitted any
+// function.
+func writeFindBy(buffer *bytes.Buffer, structName string, table Table, indexes []Index, uniqueOnly bool) bool {
+	seen, emitted := map[string]bool{}, false
+
+	for _, index := range indexes {
+		if index.TableName != table.Name || index.IndexName == "PRIMARY" || index.Type == "FULLTEXT" || index.Type == "SPATIAL" {
+			continue
+		}
+		columns := indexColumns(table, index)
+		if len(columns) == 0 || seen[strings.Join(index.Columns, ",")] {
+			continue
+		}
+		seen[strings.Join(index.Columns, ",")] = true
+
+		unique := !index.NonUnique
+		names, params, args, wheres := []string{}, []string{}, []string{}, []string{}
+		for _, cs := range columns {
+			gt, _, err := goType(&cs)
+			if err != nil {
+				return emitted
+			}
+			if cs.IsNullable == "YES" {
+				unique = false
+			}
+			param := paramName(cs)
+			names = append(names, columnGoName(cs.TableName, cs.ColumnName))
+			params = append(params, param+" "+gt)
+			args = append(args, param)
+			wheres = append(wheres, quoteIdent(cs.ColumnName)+" = ?")
+		}
+		if uniqueOnly && !unique {
+			continue
+		}
+		by := "By" + strings.Join(names, "And")
+		query := "SELECT " + selectList(table.Columns) + " FROM " + tableIdent(table.Name) + " WHERE " + strings.Join(wheres, " AND ")
+		name := funcName("List", pluralName(structName)) + by
+		write := writeQueryMany
+		if unique {
+			name = funcName("Get", structName) + by
+			write = writeQueryOne
+		}
+
+		write(buffer, name, strings.Join(params, ", "), structName, table, query+notDeleted(table), strings.Join(args, ", "))
+		if notDeleted(table) != "" {
+			write(buffer, name+"WithDeleted", strings.Join(params, ", "), structName, table, query, strings.Join(args, ", "))
+		}
+		emitted = true
+	}
+	return emitted
+}
+
+// schemaIndexes holds the introspected indexes for the index tags.
+var schemaIndexes []Index
+
+// indexTag returns the index tag of a column's field listing the indexes it
+// belongs to, e.g. `index:"PRIMARY:unique,idx_user_created"`, or "" if none.
+func indexTag(cs ColumnSchema) string {
+	names := []string{}
+	for _, index := range schemaIndexes {
+		if index.TableName != cs.TableName {
+			continue
+		}
+		for _, column := range index.Columns {
+			if column != cs.ColumnName {
+				continue
+			}
+			name := index.IndexName
+			if !index.NonUnique {
+				name += ":unique"
+			}
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return ""
+	}
+	return tag("index", strings.Join(names, ","))
+}
+
+// writeIndexComment emits a comment listing the indexes of the table, placed
+// above its struct.
+func writeIndexComment(buffer *bytes.Buffer, structName string, table Table, indexes []Index) {
+	lines := []string{}
+	for _, index := range indexes {
+		if index.TableName != table.Name {
+			continue
+		}
+		line := "//   - " + index.IndexName + " (" + strings.Join(index.Columns, ", ") + ")"
+		if !index.NonUnique {
+			line += " unique"
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) == 0 {
+		return
+	}
+	buffer.WriteString("// " + structName + " indexes:\n//\n" + strings.Join(lines, "\n") + "\n")
+}
+
+// writeIndexType emits the TableIndex type of the XIndexes variables.
+func writeIndexType(buffer *bytes.Buffer) {
+	buffer.WriteString("// TableIndex describes an index of a table.\n")
+	buffer.WriteString("type TableIndex struct {\n")
+	buffer.WriteString("\tName    string\n")
+	buffer.WriteString("\tUnique  bool\n")
+	buffer.WriteString("\tColumns []string\n}")
+}
+
+// writeIndexes emits an XIndexes variable listing the indexes of the table.
+func writeIndexes(buffer *bytes.Buffer, structName string, table Table, indexes []Index) {
+	buffer.WriteString("// " + structName + "Indexes lists the indexes of " + table.Name + ".\n")
+	buffer.WriteString("var " + structName + "Indexes = []TableIndex{\n")
+	for _, index := range indexes {
+		if index.TableName != table.Name {
+			continue
+		}
+		columns := []string{}
+		for _, column := range index.Columns {
+			columns = append(columns, strconv.Quote(column))
+		}
+		buffer.WriteString("\t{Name: " + strconv.Quote(index.IndexName) + ", Unique: " + strconv.FormatBool(!index.NonUnique) +
+			", Columns: []string{" + strings.Join(columns, ", ") + "}},\n")
+	}
+	buffer.WriteString("}")
+}
+
+// uniqueKeys returns the unique indexes of the table other than its primary
+// key.
+func uniqueKeys(table Table, indexes []Index) []Index {
+	unique := []Index{}
+	for _, index := range indexes {
+		if index.TableName == table.Name && !index.NonUnique && index.IndexName != "PRIMARY" {
+			unique = append(unique, index)
+		}
+	}
+	return unique
+}
+
+// writeUniqueKeysComment emits a comment listing the unique keys of the
+// table, placed above its struct.
+func writeUniqueKeysComment(buffer *bytes.Buffer, structName string, table Table, indexes []Index) {
+	keys := uniqueKeys(table, indexes)
+	if len(keys) == 0 {
+		return
+	}
+	buffer.WriteString("// " + structName + " unique keys:\n//\n")
+	for _, index := range keys {
+		buffer.WriteString("//   - " + index.IndexName + " (" + strings.Join(index.Columns, ", ") + ")\n")
+	}
+}
+
+// writeUniqueKeys emits an XUniqueKeys variable mapping each unique key of
+// the table to its columns.
+func writeUniqueKeys(buffer *bytes.Buffer, structName string, table Table, indexes []Index) {
+	buffer.WriteString("// " + structName + "UniqueKeys maps the unique keys of " + table.Name + " to their columns.\n")
+	buffer.WriteString("var " + structName + "UniqueKeys = map[string][]string{\n")
+	for _, index := range uniqueKeys(table, indexes) {
+		columns := []string{}
+		for _, column := range index.Columns {
+			columns = append(columns, strconv.Quote(column))
+		}
+		buffer.WriteString("\t" + strconv.Quote(index.IndexName) + ": {" + strings.Join(columns, ", ") + "},\n")
+	}
+	buffer.WriteString("}")
+}
+
+// writeSearch emits a SearchX function per FULLTEXT index of the table,
+// returning the rows matching a natural language query, most relevant first.
+// Tables with several of them get SearchXByY functions instead. It reports
+// whether the table has any.
+func writeSearch(buffer *bytes.Buffer, structName string, table Table, indexes []Index) bool {
+	fulltext := []Index{}
+	for _, index := range indexes {
+		if index.TableName == table.Name && index.Type == "FULLTEXT" && indexColumns(table, index) != nil {
+			fulltext = append(fulltext, index)
+		}
+	}
+
+	for _, index := range fulltext {
+		name := funcName("Search", structName)
+		if len(fulltext) > 1 {
+			names := []string{}
+			for _, column := range index.Columns {
+				names = append(names, columnGoName(table.Name, column))
+			}
+			name += "By" + strings.Join(names, "And")
+		}
+		columns := []string{}
+		for _, column := range index.Columns {
+			columns = append(columns, quoteIdent(column))
+		}
+		query := "SELECT " + selectList(table.Columns) + " FROM " + tableIdent(table.Name) +
+			" WHERE MATCH (" + strings.Join(columns, ", ") + ") AGAINST (? IN NATURAL LANGUAGE MODE)" + notDeleted(table)
+		writeQueryMany(buffer, name, "query string", structName, table, query, "query")
+	}
+	return len(fulltext) > 0
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"flag"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// sampleSetting is a setting of the sample config written by init, commented
+// out when optional.
+type sampleSetting struct {
+	Key      string
+	Value    interface{}
+	Comment  string
+	Optional bool
+}
+
+// sampleValue formats a string, number, list or map value as YAML or, with
+// toml set, TOML.
+func sampleValue(value interface{}, toml bool) string {
+	switch v := value.(type) {
+	case string:
+		return strconv.Quote(v)
+	case int:
+		return strconv.Itoa(v)
+	case []string:
+		items := []string{}
+		for _, item := range v {
+			items = append(items, strconv.Quote(item))
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case map[string]string:
+		keys := []string{}
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		items := []string{}
+		for _, key := range keys {
+			if toml {
+				items = append(items, strconv.Quote(key)+" = "+strconv.Quote(v[key]))
+			} else {
+				items = append(items, strconv.Quote(key)+": "+strconv.Quote(v[key]))
+			}
+		}
+		return "{" + strings.Join(items, ", ") + "}"
+	}
+	return ""
+}
+
+// sampleConfig returns the settings of the sample config, the connection
+// ones taken from the probed DSN when there is one.
+func sampleConfig(probe Configuration, schemas []string) []sampleSetting {
+	dbComment := "Schema to generate the structs of"
+	if len(schemas) > 1 {
+		dbComment += "; found " + strings.Join(schemas, ", ")
+	}
+	settings := []sampleSetting{
+		{Key: "host", Value: probe.Host, Comment: "Database server, also set by STRUCT_CREATE_HOST or -host"},
+		{Key: "port", Value: probe.Port},
+		{Key: "socket", Value: "/var/run/mysqld/mysqld.sock", Comment: "Unix socket to connect through instead of host and port", Optional: true},
+		{Key: "db_user", Value: probe.DbUser, Comment: "User to connect as"},
+		{Key: "db_password", Value: "vault:secret/data/db#password", Optional: true,
+			Comment: "Left out of the file: set STRUCT_CREATE_PASSWORD, or reference a secret"},
+		{Key: "db_name", Value: probe.DbName, Comment: dbComment},
+		{Key: "pkg_name", Value: probe.PkgName, Comment: "Package of the generated code"},
+		{Key: "tag_label", Value: probe.TagLabel, Comment: "Struct tag holding the column names, none when empty"},
+		{Key: "output", Value: "models/models.go", Comment: "File to write unless -out is given", Optional: true},
+		{Key: "tables", Value: []string{"users", "orders"}, Comment: "Tables to generate, or glob patterns matching them; all by default", Optional: true},
+		{Key: "exclude_tables", Value: []string{"schema_migrations"}, Optional: true},
+		{Key: "strip_prefixes", Value: []string{"tbl_"}, Comment: "Prefixes dropped from table names before naming their structs", Optional: true},
+		{Key: "type_names", Value: map[string]string{"users": "Account"}, Comment: "Struct names of tables, overriding the naming rules", Optional: true},
+		{Key: "field_names", Value: map[string]string{"users.email_addr": "Email"}, Optional: true},
+	}
+	if probe.Socket != "" {
+		settings[0].Optional, settings[1].Optional = true, true
+		settings[2].Value, settings[2].Optional = probe.Socket, false
+	}
+	return settings
+}
+
+// writeSample formats the sample config as YAML or, with toml set, TOML.
+func writeSample(settings []sampleSetting, toml bool) []byte {
+	var buffer bytes.Buffer
+	buffer.WriteString("# struct-create configuration. Flags and STRUCT_CREATE_* environment variables\n")
+	buffer.WriteString("# override these settings; uncomment the optional ones to use them.\n")
+	separator := ": "
+	if toml {
+		separator = " = "
+	}
+	for _, s := range settings {
+		if s.Comment != "" {
+			buffer.WriteString("\n# " + s.Comment + "\n")
+		}
+		if s.Optional {
+			buffer.WriteString("# ")
+		}
+		buffer.WriteString(s.Key + separator + sampleValue(s.Value, toml) + "\n")
+	}
+	return buffer.Bytes()
+}
+
+// probeDSN connects with the DSN and returns the connection settings it
+// holds, and the schemas of the server when it names none, picking the only
+// one there is.
+func probeDSN(ctx context.Context, dsn string) (Configuration, []string, error) {
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return Configuration{}, nil, configError("Invalid dsn: " + err.Error())
+	}
+	probe := defaults
+	probe.DbUser = cfg.User
+	probe.DbName = cfg.DBName
+	switch cfg.Net {
+	case "unix":
+		probe.Socket = cfg.Addr
+	case "tcp":
+		host, port, err := net.SplitHostPort(cfg.Addr)
+		if err == nil {
+			probe.Host = host
+			probe.Port, _ = strconv.Atoi(port)
+		}
+	}
+
+	cfg.DBName = "information_schema"
+	conn, err := sql.Open("mysql", cfg.FormatDSN())
+	if err != nil {
+		return Configuration{}, nil, classify(exitConnection, err)
+	}
+	defer conn.Close()
+	if err := ping(ctx, conn); err != nil {
+		return Configuration{}, nil, classify(exitConnection, err)
+	}
+	if probe.DbName != "" {
+		return probe, nil, nil
+	}
+
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+	rows, err := conn.QueryContext(ctx, "SELECT SCHEMA_NAME FROM SCHEMATA WHERE SCHEMA_NAME NOT IN "+
+		"('information_schema', 'mysql', 'performance_schema', 'sys') ORDER BY SCHEMA_NAME")
+	if err != nil {
+		return Configuration{}, nil, classify(exitConnection, err)
+	}
+	schemas := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return Configuration{}, nil, classify(exitConnection, err)
+		}
+		schemas = append(schemas, name)
+	}
+	if err := rows.Err(); err != nil {
+		return Configuration{}, nil, classify(exitConnection, err)
+	}
+	if len(schemas) == 1 {
+		probe.DbName = schemas[0]
+	}
+	return probe, schemas, nil
+}
+
+// The flags of the init command.
+var (
+	initOut   = new(string)
+	initDSN   = new(string)
+	initForce = new(bool)
+)
+
+// initFlags returns the flags of the init command.
+func initFlags() *flag.FlagSet {
+	flags := flag.NewFlagSet("init", flag.ExitOnError)
+	flags.StringVar(initOut, "out", "struct-create.yaml", "Config file to write, YAML (.yaml, .yml) or TOML (.toml)")
+	flags.StringVar(initDSN, "dsn", "", "MySQL data source name to probe, prefilling the connection settings")
+	flags.BoolVar(initForce, "force", false, "Overwrite the config file when it exists")
+	addLogFlags(flags)
+	return flags
+}
+
+// initConfig implements the init command, writing a commented sample config
+// prefilled from -dsn when given.
+func initConfig(ctx context.Context, args []string) error {
+	initFlags().Parse(args)
+	if err := setupLogging(); err != nil {
+		return err
+	}
+	out, dsn, force := initOut, initDSN, initForce
+
+	toml := false
+	switch strings.ToLower(filepath.Ext(*out)) {
+	case ".yaml", ".yml":
+	case ".toml":
+		toml = true
+	default:
+		return configError("Invalid -out " + *out + ", expected a .yaml, .yml or .toml file")
+	}
+
+	probe, schemas := defaults, []string(nil)
+	if *dsn != "" {
+		var err error
+		if probe, schemas, err = probeDSN(ctx, *dsn); err != nil {
+			return err
+		}
+	}
+
+	mode := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	if *force {
+		mode = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+	f, err := os.OpenFile(*out, mode, 0644)
+	if os.IsExist(err) {
+		return configError(*out + " already exists, run with -force to overwrite it")
+	}
+	if err != nil {
+		return classify(exitWrite, err)
+	}
+	if _, err := f.Write(writeSample(sampleConfig(probe, schemas), toml)); err != nil {
+		f.Close()
+		return classify(exitWrite, err)
+	}
+	if err := f.Close(); err != nil {
+		return classify(exitWrite, err)
+	}
+	slog.Info("Wrote the config", "file", *out)
+	return nil
+}
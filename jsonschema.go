@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var jsonSchemaDir = flag.String("json-schema", "", "Also write a JSON Schema document per table to this directory")
+
+// jsonSchemaDocument is the JSON Schema of a table's rows.
+type jsonSchemaDocument struct {
+	Schema               string                        `json:"$schema"`
+	Title                string                        `json:"title"`
+	Type                 string                        `json:"type"`
+	Properties           map[string]jsonSchemaProperty `json:"properties"`
+	Required             []string                      `json:"required"`
+	AdditionalProperties bool                          `json:"additionalProperties"`
+}
+
+// jsonSchemaProperty is the JSON Schema of a column.
+type jsonSchemaProperty struct {
+	Type            interface{}   `json:"type,omitempty"`
+	Format          string        `json:"format,omitempty"`
+	ContentEncoding string        `json:"contentEncoding,omitempty"`
+	MaxLength       int64         `json:"maxLength,omitempty"`
+	Minimum         *int64        `json:"minimum,omitempty"`
+	Enum            []interface{} `json:"enum,omitempty"`
+	Description     string        `json:"description,omitempty"`
+}
+
+// jsonSchemaFormats are the formats of the temporal columns.
+var jsonSchemaFormats = map[string]string{"date": "date", "time": "time", "datetime": "date-time", "timestamp": "date-time"}
+
+// columnJSONSchema returns the JSON Schema of a column: its JSON type, the
+// format of temporal columns, the character limit of CHAR and VARCHAR ones,
+// the values of enums and a minimum of 0 for unsigned numbers. Nullable
+// columns also take null.
+func columnJSONSchema(cs ColumnSchema) jsonSchemaProperty {
+	property := jsonSchemaProperty{Description: cs.ColumnComment}
+	typ := ""
+	switch {
+	case cs.DataType == "enum":
+		typ = "string"
+		for _, v := range enumValues(cs) {
+			property.Enum = append(property.Enum, v)
+		}
+		if cs.IsNullable == "YES" {
+			property.Enum = append(property.Enum, nil)
+		}
+	case jsonSchemaFormats[cs.DataType] != "":
+		typ, property.Format = "string", jsonSchemaFormats[cs.DataType]
+	case cs.DataType == "tinyint", cs.DataType == "smallint", cs.DataType == "int",
+		cs.DataType == "mediumint", cs.DataType == "bigint", cs.DataType == "year":
+		typ = "integer"
+	case cs.DataType == "float", cs.DataType == "double", cs.DataType == "decimal":
+		typ = "number"
+	case strings.Contains(cs.DataType, "blob"), strings.Contains(cs.DataType, "binary"), isSpatial(cs):
+		typ, property.ContentEncoding = "string", "base64"
+	case cs.DataType == "json":
+	default:
+		typ = "string"
+	}
+	if chars, _, ok := maxLength(cs); ok && chars > 0 {
+		property.MaxLength = chars
+	}
+	if (typ == "integer" || typ == "number") && strings.Contains(cs.ColumnType, "unsigned") {
+		property.Minimum = new(int64)
+	}
+	switch {
+	case typ == "":
+	case cs.IsNullable == "YES":
+		property.Type = []string{typ, "null"}
+	default:
+		property.Type = typ
+	}
+	return property
+}
+
+// writeJSONSchemas writes a JSON Schema document (draft 2020-12) per table
+// to dir, named after the table, requiring the NOT NULL columns and no
+// others, for request validation and contract tests.
+func writeJSONSchemas(dir string, schemas []ColumnSchema) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for _, table := range groupTables(schemas) {
+		doc := jsonSchemaDocument{
+			Schema:     "https://json-schema.org/draft/2020-12/schema",
+			Title:      typeName(table.Name),
+			Type:       "object",
+			Properties: map[string]jsonSchemaProperty{},
+			Required:   []string{},
+		}
+		for _, cs := range table.Columns {
+			doc.Properties[cs.ColumnName] = columnJSONSchema(cs)
+			if cs.IsNullable != "YES" {
+				doc.Required = append(doc.Required, cs.ColumnName)
+			}
+		}
+		data, err := json.MarshalIndent(doc, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := writeOutput(filepath.Join(dir, table.Name+".schema.json"), append(data, '\n')); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"strconv"
+)
+
+// textTypes are the string types whose length is limited in bytes rather
+// than in characters.
+var textTypes = map[string]bool{"tinytext": true, "text": true, "mediumtext": true, "longtext": true}
+
+// maxLength returns the maximum length of a string or binary column: in
+// characters for CHAR and VARCHAR columns, zero for the others, and in bytes,
+// which for utf8mb4 is up to four times the characters.
+func maxLength(cs ColumnSchema) (int64, int64, bool) {
+	if !cs.CharacterOctetLength.Valid {
+		return 0, 0, false
+	}
+	switch {
+	case cs.DataType == "char" || cs.DataType == "varchar":
+		return cs.CharacterMaximumLength.Int64, cs.CharacterOctetLength.Int64, cs.CharacterMaximumLength.Valid
+	case textTypes[cs.DataType], cs.DataType == "binary", cs.DataType == "varbinary",
+		cs.DataType == "tinyblob", cs.DataType == "blob", cs.DataType == "mediumblob", cs.DataType == "longblob":
+		return 0, cs.CharacterOctetLength.Int64, true
+	}
+	return 0, 0, false
+}
+
+// lengthComment returns the field comment describing the maximum length of
+// the column, e.g. "Max length: 255 characters, 1020 bytes in utf8mb4.".
+func lengthComment(cs ColumnSchema) string {
+	chars, bytes, ok := maxLength(cs)
+	if !ok {
+		return ""
+	}
+	comment := "Max length: "
+	if chars > 0 {
+		comment += strconv.FormatInt(chars, 10) + " characters, "
+	}
+	comment += strconv.FormatInt(bytes, 10) + " bytes"
+	if cs.CharacterSetName.Valid {
+		comment += " in " + cs.CharacterSetName.String
+	}
+	return comment + "."
+}
+
+// lengthTag returns a go-playground/validator max tag for the column, or ""
+// when the limit can't be expressed: its max counts characters in strings and
+// bytes in slices, leaving out the byte limit of TEXT columns held in strings.
+func lengthTag(cs ColumnSchema) string {
+	chars, bytes, ok := maxLength(cs)
+	gt, _, err := goType(&cs)
+	if !ok || err != nil {
+		return ""
+	}
+	switch {
+	case gt == "string" && chars > 0:
+		return "validate:\"max=" + strconv.FormatInt(chars, 10) + "\""
+	case gt == "[]byte":
+		return "validate:\"max=" + strconv.FormatInt(bytes, 10) + "\""
+	}
+	return ""
+}
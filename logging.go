@@ -0,0 +1,47 @@
+package main
+
+import (
+	"flag"
+	"log/slog"
+	"os"
+)
+
+var (
+	verbose   = new(bool)
+	quiet     = new(bool)
+	logFormat = new(string)
+)
+
+// addLogFlags registers the verbosity flags.
+func addLogFlags(flags *flag.FlagSet) {
+	flags.BoolVar(verbose, "v", false, "Also log progress at debug level: tables found, columns skipped, durations")
+	flags.BoolVar(quiet, "q", false, "Only log errors")
+	flags.StringVar(logFormat, "log-format", "text", "Log as text or as JSON objects, one per line: text or json")
+}
+
+// setupLogging logs to stderr at the level set by -v and -q, in the format
+// set by -log-format.
+func setupLogging() error {
+	level := slog.LevelInfo
+	switch {
+	case *quiet:
+		level = slog.LevelError
+	case *verbose:
+		level = slog.LevelDebug
+	}
+	options := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler
+	switch *logFormat {
+	case "text":
+		handler = slog.NewTextHandler(os.Stderr, options)
+	case "json":
+		handler = slog.NewJSONHandler(os.Stderr, options)
+	default:
+		return configError("Invalid -log-format value " + *logFormat + ", expected text or json")
+	}
+	if *manifest != "" {
+		handler = warningRecorder{handler}
+	}
+	slog.SetDefault(slog.New(handler))
+	return nil
+}
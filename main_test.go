@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// generateStructs runs writeStructs on the columns with the flags set by
+// setup, returning the generated file.
+func generateStructs(t *testing.T, columns []ColumnSchema, setup func()) string {
+	t.Helper()
+	savedConfig, savedOutput, savedOut := config, *output, structsOut
+	savedStringer, savedComments, savedDefaults := *stringer, *sqlComments, *columnDefaults
+	defer func() {
+		config, *output, structsOut = savedConfig, savedOutput, savedOut
+		*stringer, *sqlComments, *columnDefaults = savedStringer, savedComments, savedDefaults
+	}()
+
+	config = defaults
+	config.PkgName = "models"
+	var buf bytes.Buffer
+	*output, structsOut = "-", &buf
+	if setup != nil {
+		setup()
+	}
+	if err := resolveCollisions(columns); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := writeStructs(Schema{Columns: columns}); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+// hostileColumns are column names that break generated code unless escaped.
+var hostileColumns = []struct {
+	name   string
+	column string
+}{
+	{"backquote", "back`quote"},
+	{"double quote", `double"quote`},
+	{"space", "with space"},
+	{"percent", "100%"},
+	{"comment end", "end*/here"},
+	{"newline", "new\nline"},
+	{"leading digit", "1st_place"},
+	{"backslash", `back\slash`},
+}
+
+func TestHostileColumnNames(t *testing.T) {
+	for _, tc := range hostileColumns {
+		t.Run(tc.name, func(t *testing.T) {
+			columns := []ColumnSchema{
+				{TableName: "hostile", ColumnName: "id", IsNullable: "NO", DataType: "int", ColumnType: "int", ColumnKey: "PRI"},
+				{
+					TableName: "hostile", ColumnName: tc.column, IsNullable: "YES", DataType: "varchar", ColumnType: "varchar(20)",
+					ColumnDefault: sql.NullString{String: tc.column, Valid: true},
+					ColumnComment: tc.column + " DEPRECATED:",
+				},
+			}
+			src := generateStructs(t, columns, func() {
+				*stringer, *sqlComments, *columnDefaults = true, true, "comments"
+			})
+
+			fset := token.NewFileSet()
+			file, err := parser.ParseFile(fset, "models.go", src, parser.ParseComments)
+			if err != nil {
+				t.Fatalf("generated file doesn't parse: %v\n%s", err, src)
+			}
+			conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+			if _, err := conf.Check("models", fset, []*ast.File{file}, nil); err != nil {
+				t.Fatalf("generated file doesn't type-check: %v\n%s", err, src)
+			}
+
+			tagged := []string{}
+			ast.Inspect(file, func(n ast.Node) bool {
+				field, ok := n.(*ast.Field)
+				if !ok || field.Tag == nil {
+					return true
+				}
+				tag, err := strconv.Unquote(field.Tag.Value)
+				if err != nil {
+					t.Fatalf("invalid tag literal %s: %v", field.Tag.Value, err)
+				}
+				value, ok := reflect.StructTag(tag).Lookup("db")
+				if !ok {
+					t.Fatalf("tag %q has no db key", tag)
+				}
+				tagged = append(tagged, value)
+				return true
+			})
+			if want := []string{"id", tc.column}; !reflect.DeepEqual(tagged, want) {
+				t.Errorf("db tags = %q, want %q", tagged, want)
+			}
+
+			for _, group := range file.Comments {
+				for _, comment := range group.List {
+					if !strings.HasPrefix(comment.Text, "//") || strings.Contains(comment.Text, "\n") {
+						t.Errorf("comment spans lines: %q", comment.Text)
+					}
+				}
+			}
+
+			ast.Inspect(file, func(n ast.Node) bool {
+				call, ok := n.(*ast.CallExpr)
+				if !ok {
+					return true
+				}
+				if sel, ok := call.Fun.(*ast.SelectorExpr); !ok || sel.Sel.Name != "Sprintf" {
+					return true
+				}
+				format, err := strconv.Unquote(call.Args[0].(*ast.BasicLit).Value)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if verbs := strings.Count(strings.Replace(format, "%%", "", -1), "%"); verbs != len(call.Args)-1 {
+					t.Errorf("format %q has %d verbs for %d arguments", format, verbs, len(call.Args)-1)
+				}
+				return true
+			})
+		})
+	}
+}
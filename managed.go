@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// optionalMetadata returns the error of a query reading optional metadata,
+// such as foreign keys or table statistics, or nil after logging a warning
+// when the server has no such information_schema table (CHECK_CONSTRAINTS
+// before MySQL 8.0.16) or, with -managed, refused to list it: Aurora,
+// PlanetScale and Cloud SQL restrict parts of information_schema, and the
+// structs can be generated without them.
+func optionalMetadata(what string, err error) error {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return err
+	}
+	if missingTable(err) {
+		slog.Warn("Going on without the "+what+" the server has no table for", "error", err)
+		return nil
+	}
+	if !*managed {
+		return err
+	}
+	slog.Warn("Going on without the "+what+" the server refused to list", "error", err)
+	return nil
+}
+
+// missingTable reports whether err is MySQL not knowing a table (1109 in
+// information_schema, 1146 elsewhere).
+func missingTable(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && (mysqlErr.Number == 1109 || mysqlErr.Number == 1146)
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+func TestOptionalMetadata(t *testing.T) {
+	missing := &mysql.MySQLError{Number: 1109, Message: "Unknown table 'CHECK_CONSTRAINTS' in information_schema"}
+	denied := &mysql.MySQLError{Number: 1142, Message: "SELECT command denied"}
+	tests := []struct {
+		name    string
+		err     error
+		managed bool
+		wantErr bool
+	}{
+		{"no error", nil, false, false},
+		{"missing table", missing, false, false},
+		{"wrapped missing table", errors.Join(errors.New("reading checks"), missing), false, false},
+		{"denied", denied, false, true},
+		{"denied with -managed", denied, true, false},
+	}
+	defer func(saved bool) { *managed = saved }(*managed)
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			*managed = tc.managed
+			if err := optionalMetadata("CHECK constraints", tc.err); (err != nil) != tc.wantErr {
+				t.Errorf("optionalMetadata(%v) = %v, want error %v", tc.err, err, tc.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log/slog"
+	"path/filepath"
+	"sync"
+)
+
+var manifest = flag.String("manifest", "", "Also write a JSON manifest of the tables, structs, fields, files and warnings of the run to this file")
+
+// generationManifest is the -manifest file, for tools to consume instead of
+// parsing the generated code.
+type generationManifest struct {
+	Tables   []manifestTable   `json:"tables"`
+	Files    []manifestFile    `json:"files"`
+	Warnings []manifestWarning `json:"warnings"`
+}
+
+type manifestTable struct {
+	Schema string          `json:"schema"`
+	Table  string          `json:"table"`
+	Struct string          `json:"struct"`
+	File   string          `json:"file"`
+	Fields []manifestField `json:"fields"`
+}
+
+type manifestField struct {
+	Field  string `json:"field"`
+	Column string `json:"column"`
+	Type   string `json:"type"`
+}
+
+type manifestFile struct {
+	Path      string `json:"path"`
+	Unchanged bool   `json:"unchanged"`
+}
+
+type manifestWarning struct {
+	Message string            `json:"message"`
+	Attrs   map[string]string `json:"attrs,omitempty"`
+}
+
+// generated is what the run generated so far, for -manifest.
+var generated generationManifest
+
+// recordTable adds the struct of a table written to -out to the manifest.
+func recordTable(structName string, table Table) {
+	if *manifest == "" {
+		return
+	}
+	file := *output
+	if file != "-" {
+		if abs, err := filepath.Abs(file); err == nil {
+			file = abs
+		}
+	}
+	fields := []manifestField{}
+	for _, cs := range table.Columns {
+		goType, _, _ := goType(&cs)
+		fields = append(fields, manifestField{fieldName(cs), cs.ColumnName, goType})
+	}
+	generated.Tables = append(generated.Tables, manifestTable{config.DbName, table.Name, structName, file, fields})
+}
+
+// recordFile adds an output file to the manifest.
+func recordFile(path string, unchanged bool) {
+	if *manifest == "" {
+		return
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	generated.Files = append(generated.Files, manifestFile{path, unchanged})
+}
+
+// writeManifest writes the manifest of the run to -manifest, with the paths
+// relative to its directory.
+func writeManifest() error {
+	path, err := filepath.Abs(*manifest)
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(path)
+	relative := func(file string) string {
+		if rel, err := filepath.Rel(dir, file); err == nil && filepath.IsAbs(file) {
+			return filepath.ToSlash(rel)
+		}
+		return file
+	}
+	m := generated
+	if m.Tables == nil {
+		m.Tables = []manifestTable{}
+	}
+	if m.Files == nil {
+		m.Files = []manifestFile{}
+	}
+	if m.Warnings == nil {
+		m.Warnings = []manifestWarning{}
+	}
+	for i := range m.Tables {
+		m.Tables[i].File = relative(m.Tables[i].File)
+	}
+	for i := range m.Files {
+		m.Files[i].Path = relative(m.Files[i].Path)
+	}
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeOutput(path, append(data, '\n'))
+}
+
+// warningRecorder is a slog handler adding the warnings to the manifest,
+// including those -q doesn't log.
+type warningRecorder struct {
+	slog.Handler
+}
+
+// warningsMu guards the warnings of the manifest, logged by the workers of
+// -parallel too.
+var warningsMu sync.Mutex
+
+func (h warningRecorder) Enabled(ctx context.Context, level slog.Level) bool {
+	return level == slog.LevelWarn || h.Handler.Enabled(ctx, level)
+}
+
+func (h warningRecorder) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level == slog.LevelWarn {
+		warning := manifestWarning{Message: r.Message}
+		r.Attrs(func(a slog.Attr) bool {
+			if warning.Attrs == nil {
+				warning.Attrs = map[string]string{}
+			}
+			warning.Attrs[a.Key] = a.Value.String()
+			return true
+		})
+		warningsMu.Lock()
+		generated.Warnings = append(generated.Warnings, warning)
+		warningsMu.Unlock()
+	}
+	if !h.Handler.Enabled(ctx, r.Level) {
+		return nil
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h warningRecorder) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return warningRecorder{h.Handler.WithAttrs(attrs)}
+}
+
+func (h warningRecorder) WithGroup(name string) slog.Handler {
+	return warningRecorder{h.Handler.WithGroup(name)}
+}
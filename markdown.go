@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+)
+
+// columnKeys names the COLUMN_KEY values in the data dictionary.
+var columnKeys = map[string]string{"PRI": "primary", "UNI": "unique", "MUL": "index"}
+
+// markdownCell escapes a value for a cell of a Markdown table.
+func markdownCell(s string) string {
+	s = strings.Replace(s, "|", "\\|", -1)
+	return strings.Replace(strings.Replace(s, "\r\n", "<br>", -1), "\n", "<br>", -1)
+}
+
+// markdownAnchor returns the anchor GitHub gives to a heading.
+func markdownAnchor(heading string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(heading) {
+		switch {
+		case r == ' ':
+			b.WriteRune('-')
+		case r == '-' || r == '_' || 'a' <= r && r <= 'z' || '0' <= r && r <= '9':
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// writeDictionary writes the -format markdown output in place of the structs:
+// a data dictionary with a section per table listing its columns with their
+// type, nullability, default, keys and comment.
+func writeDictionary(schema Schema) error {
+	references := map[string][]string{}
+	for _, fk := range schema.ForeignKeys {
+		target := fk.ReferencedTableName + "." + fk.ReferencedColumnName
+		if fk.ReferencedSchema != "" {
+			target = fk.ReferencedSchema + "." + target
+		}
+		references[fk.TableName+"."+fk.ColumnName] = append(references[fk.TableName+"."+fk.ColumnName], "references "+target)
+	}
+
+	var buffer bytes.Buffer
+	buffer.WriteString("# " + config.DbName + "\n\n")
+	tables := groupTables(schema.Columns)
+	for _, table := range tables {
+		buffer.WriteString("- [" + table.Name + "](#" + markdownAnchor(table.Name) + ")\n")
+	}
+	for _, table := range tables {
+		buffer.WriteString("\n## " + table.Name + "\n\n")
+		buffer.WriteString("| Column | Type | Nullable | Default | Keys | Comment |\n")
+		buffer.WriteString("| --- | --- | --- | --- | --- | --- |\n")
+		for _, cs := range table.Columns {
+			// DEFAULT_GENERATED only says the default is an expression.
+			columnType := strings.Join(append([]string{cs.ColumnType}, strings.Fields(strings.Replace(cs.Extra, "DEFAULT_GENERATED", "", -1))...), " ")
+			nullable := "no"
+			if cs.IsNullable == "YES" {
+				nullable = "yes"
+			}
+			d, _ := columnDefault(cs)
+			keys := []string{}
+			if key := columnKeys[cs.ColumnKey]; key != "" {
+				keys = append(keys, key)
+			}
+			keys = append(keys, references[cs.TableName+"."+cs.ColumnName]...)
+			cells := []string{cs.ColumnName, columnType, nullable, d, strings.Join(keys, ", "), cs.ColumnComment}
+			for i, cell := range cells {
+				cells[i] = markdownCell(cell)
+			}
+			buffer.WriteString("| " + strings.Join(cells, " | ") + " |\n")
+		}
+	}
+
+	if *output == "-" {
+		_, err := buffer.WriteTo(structsOut)
+		return err
+	}
+	return writeOutput(*output, buffer.Bytes())
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"log/slog"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// patterns caches the regular expressions compiled by matchName.
+var patterns = map[string]*regexp.Regexp{}
+
+// compilePattern returns the regular expression of a pattern between
+// slashes, or nil for a glob or an exact name, failing when it's invalid.
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	if len(pattern) > 1 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+		if re, ok := patterns[pattern]; ok {
+			return re, nil
+		}
+		re, err := regexp.Compile(pattern[1 : len(pattern)-1])
+		if err != nil {
+			return nil, configError("Invalid pattern " + pattern + ": " + err.Error())
+		}
+		patterns[pattern] = re
+		return re, nil
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, configError("Invalid pattern " + pattern + ": " + err.Error())
+	}
+	return nil, nil
+}
+
+// checkPatterns fails on the first invalid pattern of the tables,
+// exclude_tables, exclude_columns and exclude_types filters.
+func checkPatterns() error {
+	for _, list := range [][]string{config.Tables, config.ExcludeTables, config.ExcludeColumns, config.ExcludeTypes} {
+		for _, pattern := range list {
+			if _, err := compilePattern(pattern); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// matchName reports whether name matches pattern: a regular expression
+// between slashes ("/^audit_/"), a glob ("billing_*") or an exact name.
+// Invalid patterns, reported by checkPatterns, match nothing.
+func matchName(pattern, name string) bool {
+	re, err := compilePattern(pattern)
+	if err != nil {
+		return false
+	}
+	if re != nil {
+		return re.MatchString(name)
+	}
+	ok, _ := path.Match(pattern, name)
+	return ok
+}
+
+// matchAny reports whether name matches one of patterns.
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if matchName(pattern, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// includeTable reports whether a table passes the tables and exclude_tables
+// filters.
+func includeTable(name string) bool {
+	if len(config.Tables) > 0 && !matchAny(config.Tables, name) {
+		return false
+	}
+	return !matchAny(config.ExcludeTables, name)
+}
+
+// filterTables drops the tables left out by the tables and exclude_tables
+// filters, along with their indexes, checks, partitioning and the foreign
+// keys from or to them; foreign keys into other schemas are kept.
+func filterTables(schema Schema) Schema {
+	if len(config.Tables) == 0 && len(config.ExcludeTables) == 0 {
+		return schema
+	}
+	for _, table := range groupTables(schema.Columns) {
+		if !includeTable(table.Name) {
+			slog.Debug("Skipping table", "table", table.Name)
+		}
+	}
+	return selectTables(schema, includeTable)
+}
+
+// selectTables keeps the tables for which include returns true, along with
+// their foreign keys to included tables or other schemas, indexes, checks and
+// partitions.
+func selectTables(schema Schema, includeTable func(string) bool) Schema {
+	columns := []ColumnSchema{}
+	for _, cs := range schema.Columns {
+		if includeTable(cs.TableName) {
+			columns = append(columns, cs)
+		}
+	}
+	schema.Columns = columns
+
+	foreignKeys := []ForeignKey{}
+	for _, fk := range schema.ForeignKeys {
+		if includeTable(fk.TableName) && (fk.ReferencedSchema != "" || includeTable(fk.ReferencedTableName)) {
+			foreignKeys = append(foreignKeys, fk)
+		}
+	}
+	if schema.ForeignKeys != nil {
+		schema.ForeignKeys = foreignKeys
+	}
+
+	indexes := []Index{}
+	for _, index := range schema.Indexes {
+		if includeTable(index.TableName) {
+			indexes = append(indexes, index)
+		}
+	}
+	if schema.Indexes != nil {
+		schema.Indexes = indexes
+	}
+
+	checks := []Check{}
+	for _, check := range schema.Checks {
+		if includeTable(check.TableName) {
+			checks = append(checks, check)
+		}
+	}
+	if schema.Checks != nil {
+		schema.Checks = checks
+	}
+
+	partitions := []Partitioning{}
+	for _, p := range schema.Partitions {
+		if includeTable(p.TableName) {
+			partitions = append(partitions, p)
+		}
+	}
+	if schema.Partitions != nil {
+		schema.Partitions = partitions
+	}
+	return schema
+}
+
+// excludeColumn reports whether a column matches one of the exclude_columns
+// entries, either by its name or as "table.column".
+func excludeColumn(tableName, columnName string) bool {
+	return matchAny(config.ExcludeColumns, columnName) || matchAny(config.ExcludeColumns, tableName+"."+columnName)
+}
+
+// excludeType reports whether a column matches one of the exclude_types
+// entries, either by its data type ("longblob") or its full column type
+// ("tinyint(1)"), ignoring case.
+func excludeType(cs ColumnSchema) bool {
+	return matchAny(config.ExcludeTypes, strings.ToLower(cs.DataType)) || matchAny(config.ExcludeTypes, strings.ToLower(cs.ColumnType))
+}
+
+// filterColumns drops the columns matching exclude_columns or
+// exclude_types, along with the indexes and foreign keys using them. The
+// columns dropped by type are listed in a warning, as they may be dropped
+// by a type only some tables use.
+func filterColumns(schema Schema) Schema {
+	if len(config.ExcludeColumns) == 0 && len(config.ExcludeTypes) == 0 {
+		return schema
+	}
+	excludedTypes := map[string]bool{}
+	listed := []string{}
+	for _, cs := range schema.Columns {
+		switch {
+		case excludeColumn(cs.TableName, cs.ColumnName):
+			slog.Debug("Skipping column", "table", cs.TableName, "column", cs.ColumnName)
+		case excludeType(cs):
+			excludedTypes[cs.TableName+"."+cs.ColumnName] = true
+			listed = append(listed, cs.TableName+"."+cs.ColumnName+" ("+cs.ColumnType+")")
+		}
+	}
+	if len(listed) > 0 {
+		slog.Warn("Skipping columns excluded by type", "count", len(listed), "columns", strings.Join(listed, ", "))
+	}
+	return dropColumns(schema, func(tableName, columnName string) bool {
+		return excludeColumn(tableName, columnName) || excludedTypes[tableName+"."+columnName]
+	})
+}
+
+// dropColumns drops the columns for which drop returns true, along with the
+// indexes and foreign keys using them.
+func dropColumns(schema Schema, drop func(tableName, columnName string) bool) Schema {
+	columns := []ColumnSchema{}
+	for _, cs := range schema.Columns {
+		if !drop(cs.TableName, cs.ColumnName) {
+			columns = append(columns, cs)
+		}
+	}
+	schema.Columns = columns
+
+	excluded := map[string]bool{}
+	for _, fk := range schema.ForeignKeys {
+		if drop(fk.TableName, fk.ColumnName) || drop(fk.ReferencedTableName, fk.ReferencedColumnName) {
+			excluded[fk.TableName+"."+fk.ConstraintName] = true
+		}
+	}
+	foreignKeys := []ForeignKey{}
+	for _, fk := range schema.ForeignKeys {
+		if !excluded[fk.TableName+"."+fk.ConstraintName] {
+			foreignKeys = append(foreignKeys, fk)
+		}
+	}
+	if schema.ForeignKeys != nil {
+		schema.ForeignKeys = foreignKeys
+	}
+
+	indexes := []Index{}
+	for _, index := range schema.Indexes {
+		kept := true
+		for _, column := range index.Columns {
+			if drop(index.TableName, column) {
+				kept = false
+			}
+		}
+		if kept {
+			indexes = append(indexes, index)
+		}
+	}
+	if schema.Indexes != nil {
+		schema.Indexes = indexes
+	}
+	return schema
+}
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"log/slog"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var merge = flag.Bool("merge", false, "Merge into the existing -out file: keep the code of the tables whose generated code didn't change as it is, in its order, regenerate the changed ones, drop the removed ones and append the new ones")
+
+// mergeRecord starts the comment ending the files written with -merge, with
+// a "// Struct hash" line per table hashing the code generated for it.
+const mergeRecord = "// struct-create -merge record of the code generated per table:"
+
+// tableSection is where the code generated for a table, starting with its
+// struct, lies in the body of the structs.
+type tableSection struct {
+	name       string
+	start, end int
+}
+
+// mergeStructs merges the generated body of the structs into the existing
+// file. The existing file is cut into the code of each table, from its
+// struct (a struct found by parseStructs that isn't one of the other types
+// generated) to the next one or to the first
+// declaration generated after the tables. The code of a table is kept as it
+// is, along with the imports it uses, so that hand edits and formatting
+// survive, when its struct has the same fields as the generated one and the
+// code generated for it is the one the mergeRecord of the file hashes, or,
+// in files without one, still declares everything generated for it. The
+// others are regenerated in place, the removed tables dropped and the new
+// ones appended. Everything before and after the tables is regenerated, and
+// the mergeRecord of the generated code appended.
+func mergeStructs(path string, body []byte, preludeEnd int, sections []tableSection, neededImports map[string]bool) ([]byte, error) {
+	existing, err := os.ReadFile(path)
+	if os.IsNotExist(err) || len(sections) == 0 {
+		return appendMergeRecord(body, body, sections), nil
+	}
+	if err != nil {
+		return nil, classify(exitWrite, err)
+	}
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, path, existing, parser.ParseComments)
+	if err != nil {
+		return nil, configError("-merge can't parse " + path + ": " + err.Error())
+	}
+	recorded, recordStart := readMergeRecord(fset, file)
+	if recorded != nil {
+		existing = existing[:recordStart]
+	}
+	tableStructs, err := parseStructs(path, existing)
+	if err != nil {
+		return nil, err
+	}
+
+	// Cut the existing file at the struct of each table and at the first
+	// declaration after them that is also generated after the tables. The
+	// other structs generated along with the tables, such as the keys of
+	// -crud, aren't tables.
+	helpers := map[string]bool{}
+	if f, err := parser.ParseFile(token.NewFileSet(), "", append([]byte("package p\n"), body...), 0); err == nil {
+		for _, decl := range f.Decls {
+			if gen, ok := decl.(*ast.GenDecl); ok && gen.Tok == token.TYPE {
+				helpers[declKey(decl)] = true
+			}
+		}
+	}
+	for _, s := range sections {
+		delete(helpers, s.name)
+	}
+	footer := body[sections[len(sections)-1].end:]
+	footerDecls := map[string]bool{}
+	if f, err := parser.ParseFile(token.NewFileSet(), "", append([]byte("package p\n"), footer...), 0); err == nil {
+		for _, decl := range f.Decls {
+			footerDecls[declKey(decl)] = true
+		}
+	}
+	type oldSection struct {
+		name       string
+		start, end int
+		decls      []ast.Decl
+	}
+	old := []oldSection{}
+	for _, decl := range file.Decls {
+		start := fset.Position(decl.Pos()).Offset
+		if doc := declDoc(decl); doc != nil {
+			start = fset.Position(doc.Pos()).Offset
+		}
+		name := declKey(decl)
+		gen, isType := decl.(*ast.GenDecl)
+		switch {
+		case isType && gen.Tok == token.TYPE && tableStructs[name] != nil && !helpers[name]:
+			if len(old) > 0 {
+				old[len(old)-1].end = start
+			}
+			old = append(old, oldSection{name: name, start: start, end: len(existing)})
+		case len(old) > 0 && footerDecls[name] && old[len(old)-1].end == len(existing):
+			old[len(old)-1].end = start
+		}
+		if len(old) > 0 && start < old[len(old)-1].end {
+			old[len(old)-1].decls = append(old[len(old)-1].decls, decl)
+		}
+	}
+
+	generated := map[string]tableSection{}
+	for _, s := range sections {
+		generated[s.name] = s
+	}
+	imports := map[string]string{}
+	for _, spec := range file.Imports {
+		importPath, _ := strconv.Unquote(spec.Path.Value)
+		name := importPath[strings.LastIndex(importPath, "/")+1:]
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		imports[name] = importPath
+	}
+
+	var merged bytes.Buffer
+	merged.Write(body[:preludeEnd])
+	written := map[string]bool{}
+	for _, s := range old {
+		section, ok := generated[s.name]
+		if !ok {
+			slog.Debug("Dropping the struct of a removed table", "struct", s.name)
+			continue
+		}
+		if len(written) > 0 {
+			merged.WriteString("\n\n")
+		}
+		written[s.name] = true
+		text := body[section.start:section.end]
+		src := append(append([]byte("package p\n"), body[:preludeEnd]...), text...)
+		fields, err := parseStructs(path, src)
+		unchanged := err == nil && reflect.DeepEqual(fields[s.name], tableStructs[s.name])
+		if recorded != nil {
+			unchanged = unchanged && recorded[s.name] == sectionHash(text)
+		} else {
+			unchanged = unchanged && declaresAll(s.decls, text)
+		}
+		if unchanged {
+			merged.Write(bytes.TrimRight(existing[s.start:s.end], " \t\n"))
+			for _, decl := range s.decls {
+				for name := range usedPackages(decl) {
+					if importPath, ok := imports[name]; ok {
+						neededImports[importPath] = true
+					}
+				}
+			}
+			continue
+		}
+		slog.Debug("Regenerating the struct of a changed table", "struct", s.name)
+		merged.Write(text)
+	}
+	for _, section := range sections {
+		if written[section.name] {
+			continue
+		}
+		if len(written) > 0 {
+			merged.WriteString("\n\n")
+		}
+		written[section.name] = true
+		merged.Write(body[section.start:section.end])
+	}
+	merged.Write(footer)
+	return appendMergeRecord(merged.Bytes(), body, sections), nil
+}
+
+// sectionHash returns the hash of the code generated for a table, the hex
+// of the first 8 bytes of its SHA-256.
+func sectionHash(text []byte) string {
+	sum := sha256.Sum256(text)
+	return hex.EncodeToString(sum[:8])
+}
+
+// appendMergeRecord appends to merged the mergeRecord of the sections of the
+// generated body.
+func appendMergeRecord(merged, body []byte, sections []tableSection) []byte {
+	record := bytes.NewBuffer(bytes.TrimRight(merged, "\n"))
+	record.WriteString("\n\n" + mergeRecord + "\n")
+	for _, s := range sections {
+		record.WriteString("// " + s.name + " " + sectionHash(body[s.start:s.end]) + "\n")
+	}
+	return record.Bytes()
+}
+
+// readMergeRecord returns the hashes of the mergeRecord of a file by struct
+// and the offset it starts at, or nil if the file has none.
+func readMergeRecord(fset *token.FileSet, file *ast.File) (map[string]string, int) {
+	for _, group := range file.Comments {
+		if len(group.List) == 0 || group.List[0].Text != mergeRecord {
+			continue
+		}
+		hashes := map[string]string{}
+		for _, comment := range group.List[1:] {
+			if fields := strings.Fields(strings.TrimPrefix(comment.Text, "//")); len(fields) == 2 {
+				hashes[fields[0]] = fields[1]
+			}
+		}
+		return hashes, fset.Position(group.Pos()).Offset
+	}
+	return nil, 0
+}
+
+// declaresAll reports whether decls declare everything the generated code
+// of a table, text, does, which tells in files without a mergeRecord whether
+// generators were turned on since.
+func declaresAll(decls []ast.Decl, text []byte) bool {
+	f, err := parser.ParseFile(token.NewFileSet(), "", append([]byte("package p\n"), text...), 0)
+	if err != nil {
+		return false
+	}
+	declared := map[string]bool{}
+	for _, decl := range decls {
+		declared[declKey(decl)] = true
+	}
+	for _, decl := range f.Decls {
+		if !declared[declKey(decl)] {
+			return false
+		}
+	}
+	return true
+}
+
+// declKey names a declaration: its type, function, "Receiver.Method" or first
+// variable or constant.
+func declKey(decl ast.Decl) string {
+	switch d := decl.(type) {
+	case *ast.FuncDecl:
+		if d.Recv != nil && len(d.Recv.List) > 0 {
+			return strings.TrimPrefix(exprString(d.Recv.List[0].Type), "*") + "." + d.Name.Name
+		}
+		return d.Name.Name
+	case *ast.GenDecl:
+		if len(d.Specs) == 0 {
+			return ""
+		}
+		switch spec := d.Specs[0].(type) {
+		case *ast.TypeSpec:
+			return spec.Name.Name
+		case *ast.ValueSpec:
+			return spec.Names[0].Name
+		}
+	}
+	return ""
+}
+
+// declDoc returns the doc comment of a declaration.
+func declDoc(decl ast.Decl) *ast.CommentGroup {
+	switch d := decl.(type) {
+	case *ast.FuncDecl:
+		return d.Doc
+	case *ast.GenDecl:
+		return d.Doc
+	}
+	return nil
+}
+
+// usedPackages returns the identifiers a declaration qualifies names with,
+// which are the packages it uses.
+func usedPackages(decl ast.Decl) map[string]bool {
+	used := map[string]bool{}
+	ast.Inspect(decl, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if ident, ok := sel.X.(*ast.Ident); ok {
+				used[ident.Name] = true
+			}
+		}
+		return true
+	})
+	return used
+}
@@ -0,0 +1,666 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// receiverName returns the receiver identifier used for generated methods.
+func receiverName(structName string) string {
+	r, _ := utf8.DecodeRuneInString(structName)
+	return string(unicode.ToLower(r))
+}
+
+// funcName joins prefix and name into a function name that is exported only
+// when name is, so unexported types don't get exported constructors.
+func funcName(prefix, name string) string {
+	if r, size := utf8.DecodeRuneInString(name); unicode.IsLower(r) {
+		return strings.ToLower(prefix[:1]) + prefix[1:] + string(unicode.ToUpper(r)) + name[size:]
+	}
+	return prefix + name
+}
+
+// isSensitive reports whether the column is classified, by the
+// classified_columns or sensitive_columns config or a marker in its comment.
+func isSensitive(cs ColumnSchema) bool {
+	class, _ := columnClassification(cs)
+	return class != ""
+}
+
+// writeStringer emits a String() method printing the row as key=value pairs,
+// masking classified columns.
+func writeStringer(buffer *bytes.Buffer, structName string, table Table) {
+	r := receiverName(structName)
+	format := []string{}
+	args := []string{}
+
+	for _, cs := range table.Columns {
+		// A % in a column name would be taken for a verb.
+		name := strings.Replace(cs.ColumnName, "%", "%%", -1)
+		if isSensitive(cs) {
+			format = append(format, name+"=***")
+			continue
+		}
+		format = append(format, name+"=%v")
+		args = append(args, r+"."+fieldName(cs))
+	}
+
+	buffer.WriteString("func (" + r + " " + structName + ") String() string {\n")
+	buffer.WriteString("\treturn fmt.Sprintf(" + strconv.Quote(structName+"{"+strings.Join(format, " ")+"}"))
+	for _, a := range args {
+		buffer.WriteString(", " + a)
+	}
+	buffer.WriteString(")\n}")
+}
+
+// columnDefault returns the column default as a plain value. MariaDB quotes
+// literal defaults and reports a NULL default as the string "NULL".
+func columnDefault(cs ColumnSchema) (string, bool) {
+	if !cs.ColumnDefault.Valid || cs.ColumnDefault.String == "NULL" {
+		return "", false
+	}
+	d := cs.ColumnDefault.String
+	if len(d) >= 2 && d[0] == '\'' && d[len(d)-1] == '\'' {
+		d = strings.Replace(d[1:len(d)-1], "''", "'", -1)
+	}
+	return d, true
+}
+
+// enumValues returns the allowed values of an enum or set column.
+func enumValues(cs ColumnSchema) []string {
+	ct := cs.ColumnType
+	start, end := strings.Index(ct, "("), strings.LastIndex(ct, ")")
+	if start < 0 || end <= start {
+		return nil
+	}
+	values := []string{}
+	for _, v := range strings.Split(ct[start+1:end], "','") {
+		v = strings.TrimSuffix(strings.TrimPrefix(v, "'"), "'")
+		values = append(values, strings.Replace(v, "''", "'", -1))
+	}
+	return values
+}
+
+// defaultLiteral returns a Go expression of the given type holding the column
+// default, and the import it requires.
+func defaultLiteral(cs ColumnSchema, goType string) (string, string, bool) {
+	if sqlType, wrapped := sqlNullType(goType); wrapped {
+		literal, requiredImport, ok := defaultLiteral(cs, sqlType)
+		if !ok || literal == "" {
+			return "", "", false
+		}
+		if *nullPackage != "" {
+			return "null." + strings.TrimPrefix(literal, "sql."), requiredImport, true
+		}
+		return goType + "{" + literal + "}", requiredImport, true
+	}
+
+	d, ok := columnDefault(cs)
+	if !ok {
+		// MySQL falls back to the first value of a NOT NULL enum
+		if cs.DataType == "enum" && cs.IsNullable == "NO" {
+			if values := enumValues(cs); len(values) > 0 {
+				return strconv.Quote(values[0]), "", true
+			}
+		}
+		return "", "", false
+	}
+
+	switch goType {
+	case "string":
+		return strconv.Quote(d), "", true
+	case "sql.NullString":
+		return "sql.NullString{String: " + strconv.Quote(d) + ", Valid: true}", "", true
+	case "[]byte":
+		return "[]byte(" + strconv.Quote(d) + ")", "", true
+	case "int64", "sql.NullInt64":
+		if _, err := strconv.ParseInt(d, 10, 64); err != nil {
+			return "", "", false
+		}
+		if goType == "int64" {
+			return d, "", true
+		}
+		return "sql.NullInt64{Int64: " + d + ", Valid: true}", "", true
+	case "float64", "sql.NullFloat64":
+		if _, err := strconv.ParseFloat(d, 64); err != nil {
+			return "", "", false
+		}
+		if goType == "float64" {
+			return d, "", true
+		}
+		return "sql.NullFloat64{Float64: " + d + ", Valid: true}", "", true
+	case "time.Time", "sql.NullTime":
+		literal := ""
+		if strings.HasPrefix(strings.ToUpper(d), "CURRENT_TIMESTAMP") || strings.HasPrefix(strings.ToLower(d), "now(") {
+			literal = "time.Now()"
+		}
+		for _, layout := range []string{"2006-01-02 15:04:05", "2006-01-02"} {
+			if t, err := time.Parse(layout, d); err == nil && literal == "" {
+				literal = fmt.Sprintf("time.Date(%d, %d, %d, %d, %d, %d, 0, time.UTC)",
+					t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second())
+			}
+		}
+		if literal == "" {
+			return "", "", false
+		}
+		if goType == "time.Time" {
+			return literal, "time", true
+		}
+		return "sql.NullTime{Time: " + literal + ", Valid: true}", "time", true
+	}
+	return "", "", false
+}
+
+// writeDefaults emits the composite literal fields setting each column with a
+// usable default, indented by indent. Base columns are nested in the embedded
+// base struct.
+func writeDefaults(buffer *bytes.Buffer, table Table, neededImports map[string]bool, indent string) {
+	var base bytes.Buffer
+	for _, cs := range table.Columns {
+		gt, _, err := goType(&cs)
+		if err != nil {
+			continue
+		}
+		literal, requiredImport, ok := defaultLiteral(cs, gt)
+		if !ok {
+			continue
+		}
+		if requiredImport != "" {
+			neededImports[requiredImport] = true
+		}
+		if table.EmbedsBase && isBaseColumn(cs) {
+			base.WriteString(indent + "\t" + fieldName(cs) + ": " + literal + ",\n")
+			continue
+		}
+		buffer.WriteString(indent + fieldName(cs) + ": " + literal + ",\n")
+	}
+
+	if base.Len() > 0 {
+		buffer.WriteString(indent + config.BaseStruct + ": " + config.BaseStruct + "{\n")
+		base.WriteTo(buffer)
+		buffer.WriteString(indent + "},\n")
+	}
+}
+
+// writeDefaultsMap emits a Defaults() method mapping each column with a
+// default the Go code can express to that value.
+func writeDefaultsMap(buffer *bytes.Buffer, structName string, table Table, neededImports map[string]bool) {
+	r := receiverName(structName)
+	buffer.WriteString("// Defaults returns the column defaults of " + table.Name + " by column name.\n")
+	buffer.WriteString("func (" + r + " " + structName + ") Defaults() map[string]interface{} {\n")
+	buffer.WriteString("\treturn map[string]interface{}{\n")
+	for _, cs := range table.Columns {
+		gt, _, err := goType(&cs)
+		if err != nil {
+			continue
+		}
+		literal, requiredImport, ok := defaultLiteral(cs, gt)
+		if !ok {
+			continue
+		}
+		if requiredImport != "" {
+			neededImports[requiredImport] = true
+		}
+		// untyped constants would default to int in the map
+		if gt == "int64" || gt == "float64" {
+			literal = gt + "(" + literal + ")"
+		}
+		buffer.WriteString("\t\t" + strconv.Quote(cs.ColumnName) + ": " + literal + ",\n")
+	}
+	buffer.WriteString("\t}\n}")
+}
+
+// writeConstructor emits a NewX() function returning a row initialized with
+// the column defaults.
+func writeConstructor(buffer *bytes.Buffer, structName string, table Table, neededImports map[string]bool) {
+	buffer.WriteString("func " + funcName("New", structName) + "() *" + structName + " {\n")
+	buffer.WriteString("\treturn &" + structName + "{\n")
+	writeDefaults(buffer, table, neededImports, "\t\t")
+	buffer.WriteString("\t}\n}")
+}
+
+// writeOptionsConstructor emits a NewX() function taking the required
+// columns and XOption functions, one WithXY per other writable column,
+// returning a row initialized with the column defaults, the required values
+// and the options.
+func writeOptionsConstructor(buffer *bytes.Buffer, structName string, table Table, neededImports map[string]bool) {
+	optionName := structName + "Option"
+	r := receiverName(structName)
+	params := []string{}
+	var required, options bytes.Buffer
+
+	for _, cs := range table.Columns {
+		gt, _, err := goType(&cs)
+		if err != nil || isReadOnly(cs) {
+			continue
+		}
+		field := fieldName(cs)
+		if table.EmbedsBase && isBaseColumn(cs) {
+			field = config.BaseStruct + "." + field
+		}
+		if isRequired(cs) {
+			param := paramName(cs)
+			params = append(params, param+" "+gt)
+			required.WriteString("	" + r + "." + field + " = " + param + "\n")
+			continue
+		}
+		options.WriteString("\n\n// " + funcName("With", structName+columnGoName(cs.TableName, cs.ColumnName)) + " sets " + cs.ColumnName + ".\n")
+		options.WriteString("func " + funcName("With", structName+columnGoName(cs.TableName, cs.ColumnName)) + "(value " + gt + ") " + optionName + " {\n")
+		options.WriteString("\treturn func(" + r + " *" + structName + ") {\n")
+		options.WriteString("\t\t" + r + "." + field + " = value\n")
+		options.WriteString("\t}\n}")
+	}
+	params = append(params, "opts ..."+optionName)
+
+	buffer.WriteString("// " + optionName + " sets an optional column of a row created by " + funcName("New", structName) + ".\n")
+	buffer.WriteString("type " + optionName + " func(*" + structName + ")\n\n")
+	buffer.WriteString("func " + funcName("New", structName) + "(" + strings.Join(params, ", ") + ") *" + structName + " {\n")
+	buffer.WriteString("\t" + r + " := &" + structName + "{\n")
+	writeDefaults(buffer, table, neededImports, "\t\t")
+	buffer.WriteString("\t}\n")
+	required.WriteTo(buffer)
+	buffer.WriteString("\tfor _, opt := range opts {\n")
+	buffer.WriteString("\t\topt(" + r + ")\n")
+	buffer.WriteString("\t}\n")
+	buffer.WriteString("\treturn " + r + "\n}")
+	options.WriteTo(buffer)
+}
+
+// primaryKey returns the columns making up the table's primary key.
+func primaryKey(table Table) []ColumnSchema {
+	pk := []ColumnSchema{}
+	for _, cs := range table.Columns {
+		if cs.ColumnKey == "PRI" {
+			pk = append(pk, cs)
+		}
+	}
+	return pk
+}
+
+// writeSlice emits a slice type for the struct. Tables with a single column
+// primary key also get IDs() and ByID() helpers.
+func writeSlice(buffer *bytes.Buffer, structName string, table Table) {
+	sliceName := collectionName(structName)
+	buffer.WriteString("type " + sliceName + " []" + structName)
+
+	pk := primaryKey(table)
+	if len(pk) != 1 {
+		return
+	}
+	pkType, _, err := goType(&pk[0])
+	if err != nil || pkType == "[]byte" {
+		return
+	}
+	pkField := fieldName(pk[0])
+	r := receiverName(sliceName)
+
+	buffer.WriteString("\n\nfunc (" + r + " " + sliceName + ") IDs() []" + pkType + " {\n")
+	buffer.WriteString("\tids := make([]" + pkType + ", len(" + r + "))\n")
+	buffer.WriteString("\tfor idx := range " + r + " {\n")
+	buffer.WriteString("\t\tids[idx] = " + r + "[idx]." + pkField + "\n")
+	buffer.WriteString("\t}\n\treturn ids\n}")
+
+	buffer.WriteString("\n\nfunc (" + r + " " + sliceName + ") ByID() map[" + pkType + "]" + structName + " {\n")
+	buffer.WriteString("\tbyID := make(map[" + pkType + "]" + structName + ", len(" + r + "))\n")
+	buffer.WriteString("\tfor _, row := range " + r + " {\n")
+	buffer.WriteString("\t\tbyID[row." + pkField + "] = row\n")
+	buffer.WriteString("\t}\n\treturn byID\n}")
+}
+
+// writeClone emits a Clone() method copying slice and pointer fields so the
+// copy doesn't share memory with the original.
+func writeClone(buffer *bytes.Buffer, structName string, table Table) {
+	r := receiverName(structName)
+	buffer.WriteString("func (" + r + " " + structName + ") Clone() " + structName + " {\n")
+	buffer.WriteString("\tclone := " + r + "\n")
+
+	for _, cs := range table.Columns {
+		gt, _, err := goType(&cs)
+		if err != nil {
+			continue
+		}
+		field := fieldName(cs)
+		switch {
+		case strings.HasPrefix(gt, "[]") || gt == "json.RawMessage" || gt == "EncryptedBytes":
+			buffer.WriteString("\tif " + r + "." + field + " != nil {\n")
+			buffer.WriteString("\t\tclone." + field + " = append(" + gt + "(nil), " + r + "." + field + "...)\n")
+			buffer.WriteString("\t}\n")
+		case strings.HasPrefix(gt, "*"):
+			buffer.WriteString("\tif " + r + "." + field + " != nil {\n")
+			buffer.WriteString("\t\tv := *" + r + "." + field + "\n")
+			buffer.WriteString("\t\tclone." + field + " = &v\n")
+			buffer.WriteString("\t}\n")
+		}
+	}
+
+	buffer.WriteString("\treturn clone\n}")
+}
+
+// writeAccessors emits a getter and a setter for every unexported field.
+func writeAccessors(buffer *bytes.Buffer, structName string, table Table) {
+	r := receiverName(structName)
+
+	for i, cs := range table.Columns {
+		gt, _, err := goType(&cs)
+		if err != nil {
+			continue
+		}
+		if i > 0 {
+			buffer.WriteString("\n\n")
+		}
+		name, field := columnGoName(cs.TableName, cs.ColumnName), fieldName(cs)
+
+		buffer.WriteString("func (" + r + " *" + structName + ") " + name + "() " + gt + " {\n")
+		buffer.WriteString("\treturn " + r + "." + field + "\n}\n\n")
+
+		buffer.WriteString("func (" + r + " *" + structName + ") Set" + name + "(value " + gt + ") {\n")
+		buffer.WriteString("\t" + r + "." + field + " = value\n}")
+	}
+}
+
+// isRequired reports whether a value must be supplied for the column when
+// inserting a row.
+func isRequired(cs ColumnSchema) bool {
+	if cs.IsNullable == "YES" || cs.DataType == "enum" || hasExtra(cs, "auto_increment") || isReadOnly(cs) {
+		return false
+	}
+	_, hasDefault := columnDefault(cs)
+	return !hasDefault
+}
+
+// writeBuilder emits a fluent builder for the struct whose Build() method
+// fails when a required column was never set.
+func writeBuilder(buffer *bytes.Buffer, structName string, table Table, neededImports map[string]bool) {
+	builderName := structName + "Builder"
+	required := []string{}
+
+	buffer.WriteString("type " + builderName + " struct {\n")
+	buffer.WriteString("\trow " + structName + "\n")
+	buffer.WriteString("\tset map[string]bool\n}\n\n")
+
+	buffer.WriteString("func " + funcName("New", builderName) + "() *" + builderName + " {\n")
+	buffer.WriteString("\treturn &" + builderName + "{\n")
+	buffer.WriteString("\t\trow: " + structName + "{\n")
+	writeDefaults(buffer, table, neededImports, "\t\t\t")
+	buffer.WriteString("\t\t},\n")
+	buffer.WriteString("\t\tset: map[string]bool{},\n")
+	buffer.WriteString("\t}\n}")
+
+	for _, cs := range table.Columns {
+		gt, _, err := goType(&cs)
+		if err != nil {
+			continue
+		}
+		if isRequired(cs) {
+			required = append(required, strconv.Quote(cs.ColumnName))
+		}
+		buffer.WriteString("\n\nfunc (b *" + builderName + ") " + columnGoName(cs.TableName, cs.ColumnName) + "(v " + gt + ") *" + builderName + " {\n")
+		buffer.WriteString("\tb.row." + fieldName(cs) + " = v\n")
+		buffer.WriteString("\tb.set[" + strconv.Quote(cs.ColumnName) + "] = true\n")
+		buffer.WriteString("\treturn b\n}")
+	}
+
+	buffer.WriteString("\n\nfunc (b *" + builderName + ") Build() (" + structName + ", error) {\n")
+	if len(required) > 0 {
+		neededImports["fmt"] = true
+		buffer.WriteString("\tfor _, column := range []string{" + strings.Join(required, ", ") + "} {\n")
+		buffer.WriteString("\t\tif !b.set[column] {\n")
+		buffer.WriteString("\t\t\treturn " + structName + "{}, fmt.Errorf(\"" + builderName + ": %s is required\", column)\n")
+		buffer.WriteString("\t\t}\n\t}\n")
+	}
+	buffer.WriteString("\treturn b.row, nil\n}")
+}
+
+// writeTableName emits a TableName() method returning the table name.
+func writeTableName(buffer *bytes.Buffer, structName string, table Table) {
+	r := receiverName(structName)
+	buffer.WriteString("func (" + r + " " + structName + ") TableName() string {\n")
+	buffer.WriteString("\treturn " + strconv.Quote(table.Name) + "\n}")
+}
+
+// keyMethod is the name of the method returning the XKey of a struct:
+// PrimaryKey, unless -models already uses that name for the Model interface.
+func keyMethod() string {
+	if *models {
+		return "Key"
+	}
+	return "PrimaryKey"
+}
+
+// writeKey emits an XKey struct holding the columns of a composite primary
+// key, and the method returning it.
+func writeKey(buffer *bytes.Buffer, structName string, table Table) {
+	r := receiverName(structName)
+	keyName := structName + "Key"
+	pk := primaryKey(table)
+
+	buffer.WriteString("// " + keyName + " is the primary key of " + table.Name + ".\n")
+	buffer.WriteString("type " + keyName + " struct {\n")
+	fields := []string{}
+	for _, cs := range pk {
+		gt, _, _ := goType(&cs)
+		buffer.WriteString("\t" + fieldName(cs) + " " + gt + "\n")
+		fields = append(fields, fieldName(cs)+": "+r+"."+fieldName(cs))
+	}
+	buffer.WriteString("}\n\n")
+
+	buffer.WriteString("func (" + r + " " + structName + ") " + keyMethod() + "() " + keyName + " {\n")
+	buffer.WriteString("\treturn " + keyName + "{" + strings.Join(fields, ", ") + "}\n}")
+}
+
+// writeModelMethods emits the TableName() and PrimaryKey() methods of the
+// Model interface.
+func writeModelMethods(buffer *bytes.Buffer, structName string, table Table) {
+	r := receiverName(structName)
+	writeTableName(buffer, structName, table)
+	buffer.WriteString("\n\n")
+
+	keys := []string{}
+	for _, cs := range primaryKey(table) {
+		keys = append(keys, r+"."+fieldName(cs))
+	}
+	buffer.WriteString("func (" + r + " " + structName + ") PrimaryKey() []interface{} {\n")
+	buffer.WriteString("\treturn []interface{}{" + strings.Join(keys, ", ") + "}\n}")
+}
+
+// writeModelRegistry emits the Model interface and a Models slice holding a
+// zero value of every generated struct.
+func writeModelRegistry(buffer *bytes.Buffer, tables []Table) {
+	buffer.WriteString("// Model is implemented by every generated struct.\n")
+	buffer.WriteString("type Model interface {\n")
+	buffer.WriteString("\tTableName() string\n")
+	buffer.WriteString("\tPrimaryKey() []interface{}\n}\n\n")
+
+	buffer.WriteString("// Models lists every generated struct.\n")
+	buffer.WriteString("var Models = []Model{\n")
+	for _, t := range tables {
+		buffer.WriteString("\t&" + typeName(t.Name) + "{},\n")
+	}
+	buffer.WriteString("}")
+}
+
+// zeroCheck returns a Go expression reporting whether expr of type goType
+// holds its zero value.
+func zeroCheck(expr, goType string) string {
+	switch {
+	case strings.HasPrefix(goType, "[]"), goType == "json.RawMessage", goType == "EncryptedBytes":
+		return "len(" + expr + ") == 0"
+	case strings.HasPrefix(goType, "*"), goType == "interface{}":
+		return expr + " == nil"
+	case goType == "time.Time":
+		return expr + ".IsZero()"
+	case goType == "string", goType == "EncryptedString":
+		return expr + ` == ""`
+	case goType == "bool":
+		return "!" + expr
+	case strings.HasPrefix(goType, "int"), strings.HasPrefix(goType, "uint"), strings.HasPrefix(goType, "float"):
+		return expr + " == 0"
+	}
+	return expr + " == (" + goType + "{})"
+}
+
+// differCheck returns a Go expression reporting whether a and b of type goType
+// differ, and the import it requires. Times are compared with Equal, as the
+// same instant scanned in another location or with a monotonic reading
+// isn't ==.
+func differCheck(a, b, goType string) (string, string) {
+	sqlType, _ := sqlNullType(goType)
+	switch {
+	case strings.HasPrefix(goType, "[]"), goType == "json.RawMessage", goType == "EncryptedBytes":
+		return "!bytes.Equal(" + a + ", " + b + ")", "bytes"
+	case goType == "time.Time":
+		return "!" + a + ".Equal(" + b + ")", ""
+	case goType == "*time.Time":
+		return "(" + a + " == nil) != (" + b + " == nil) || " + a + " != nil && !" + a + ".Equal(*" + b + ")", ""
+	case sqlType == "sql.NullTime":
+		return a + ".Valid != " + b + ".Valid || " + a + ".Valid && !" + a + ".Time.Equal(" + b + ".Time)", ""
+	}
+	return a + " != " + b, ""
+}
+
+// writeIsZero emits an IsZero() method reporting whether every field holds its
+// zero value.
+func writeIsZero(buffer *bytes.Buffer, structName string, table Table) {
+	r := receiverName(structName)
+	checks := []string{}
+	for _, cs := range table.Columns {
+		gt, _, err := goType(&cs)
+		if err != nil {
+			continue
+		}
+		checks = append(checks, zeroCheck(r+"."+fieldName(cs), gt))
+	}
+	if len(checks) == 0 {
+		checks = append(checks, "true")
+	}
+
+	buffer.WriteString("func (" + r + " " + structName + ") IsZero() bool {\n")
+	buffer.WriteString("\treturn " + strings.Join(checks, " &&\n\t\t") + "\n}")
+}
+
+// writeChanged emits a Changed(other) method returning the names of the
+// columns whose values differ between the two rows.
+func writeChanged(buffer *bytes.Buffer, structName string, table Table, neededImports map[string]bool) {
+	r := receiverName(structName)
+	buffer.WriteString("func (" + r + " " + structName + ") Changed(other " + structName + ") []string {\n")
+	buffer.WriteString("\tchanged := []string{}\n")
+
+	for _, cs := range table.Columns {
+		gt, _, err := goType(&cs)
+		if err != nil {
+			continue
+		}
+		field := fieldName(cs)
+		differ, requiredImport := differCheck(r+"."+field, "other."+field, gt)
+		if requiredImport != "" {
+			neededImports[requiredImport] = true
+		}
+		buffer.WriteString("\tif " + differ + " {\n")
+		buffer.WriteString("\t\tchanged = append(changed, " + strconv.Quote(cs.ColumnName) + ")\n")
+		buffer.WriteString("\t}\n")
+	}
+
+	buffer.WriteString("\treturn changed\n}")
+}
+
+// writeChange emits the Change type the DiffX functions return by column.
+func writeChange(buffer *bytes.Buffer) {
+	buffer.WriteString("// Change is the value of a column before and after an update, nil for NULL.\n")
+	buffer.WriteString("type Change struct {\n")
+	buffer.WriteString("\tBefore interface{} `json:\"before\"`\n")
+	buffer.WriteString("\tAfter  interface{} `json:\"after\"`\n}")
+}
+
+// writeDiff emits a DiffX(old, new) function returning the columns whose
+// values differ between the two rows, by column name, with their values
+// before and after: nil for NULL, the value of the database/sql null types
+// and pointers otherwise.
+func writeDiff(buffer *bytes.Buffer, structName string, table Table, neededImports map[string]bool) {
+	name := funcName("Diff", structName)
+	buffer.WriteString("// " + name + " returns the columns whose values differ between old and new\n")
+	buffer.WriteString("// with their values before and after.\n")
+	buffer.WriteString("func " + name + "(old, new " + structName + ") map[string]Change {\n")
+	buffer.WriteString("\tchanges := map[string]Change{}\n")
+
+	for _, cs := range table.Columns {
+		gt, _, err := goType(&cs)
+		if err != nil {
+			continue
+		}
+		before, after := "old."+fieldName(cs), "new."+fieldName(cs)
+		differ, requiredImport := differCheck(before, after, gt)
+		if requiredImport != "" {
+			neededImports[requiredImport] = true
+		}
+		column := strconv.Quote(cs.ColumnName)
+		buffer.WriteString("\tif " + differ + " {\n")
+
+		sqlType, _ := sqlNullType(gt)
+		value, nullable := nullWrapperFields[strings.TrimPrefix(sqlType, "sql.")]
+		switch {
+		case nullable && strings.HasPrefix(sqlType, "sql."):
+			buffer.WriteString("\t\tchange := Change{}\n")
+			buffer.WriteString("\t\tif " + before + ".Valid {\n\t\t\tchange.Before = " + before + "." + value + "\n\t\t}\n")
+			buffer.WriteString("\t\tif " + after + ".Valid {\n\t\t\tchange.After = " + after + "." + value + "\n\t\t}\n")
+			buffer.WriteString("\t\tchanges[" + column + "] = change\n")
+		case strings.HasPrefix(gt, "*"):
+			buffer.WriteString("\t\tchange := Change{}\n")
+			buffer.WriteString("\t\tif " + before + " != nil {\n\t\t\tchange.Before = *" + before + "\n\t\t}\n")
+			buffer.WriteString("\t\tif " + after + " != nil {\n\t\t\tchange.After = *" + after + "\n\t\t}\n")
+			buffer.WriteString("\t\tchanges[" + column + "] = change\n")
+		default:
+			buffer.WriteString("\t\tchanges[" + column + "] = Change{" + before + ", " + after + "}\n")
+		}
+		buffer.WriteString("\t}\n")
+	}
+
+	buffer.WriteString("\treturn changes\n}")
+}
+
+// jsonTypes returns the local types that JSON columns are mapped to through
+// type_overrides, in a stable order.
+func jsonTypes(schemas []ColumnSchema) []string {
+	seen := map[string]bool{}
+	types := []string{}
+	for _, cs := range schemas {
+		override, ok := config.TypeOverrides[cs.TableName+"."+cs.ColumnName]
+		if !ok || cs.DataType != "json" {
+			continue
+		}
+		gt, requiredImport := parseTypeName(override)
+		gt = strings.TrimPrefix(gt, "*")
+		if requiredImport != "" || strings.HasPrefix(gt, "[]") || seen[gt] {
+			continue
+		}
+		seen[gt] = true
+		types = append(types, gt)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// writeJSONScanner emits the sql.Scanner and driver.Valuer methods storing the
+// type as JSON.
+func writeJSONScanner(buffer *bytes.Buffer, typeName string) {
+	r := receiverName(typeName)
+	buffer.WriteString("func (" + r + " *" + typeName + ") Scan(src interface{}) error {\n")
+	buffer.WriteString("\tswitch data := src.(type) {\n")
+	buffer.WriteString("\tcase nil:\n")
+	buffer.WriteString("\t\t*" + r + " = " + typeName + "{}\n")
+	buffer.WriteString("\t\treturn nil\n")
+	buffer.WriteString("\tcase []byte:\n")
+	buffer.WriteString("\t\treturn json.Unmarshal(data, " + r + ")\n")
+	buffer.WriteString("\tcase string:\n")
+	buffer.WriteString("\t\treturn json.Unmarshal([]byte(data), " + r + ")\n")
+	buffer.WriteString("\t}\n")
+	buffer.WriteString("\treturn fmt.Errorf(\"" + typeName + ": cannot scan %T\", src)\n}\n\n")
+
+	buffer.WriteString("func (" + r + " " + typeName + ") Value() (driver.Value, error) {\n")
+	buffer.WriteString("\treturn json.Marshal(" + r + ")\n}")
+}
@@ -0,0 +1,26 @@
+package main
+
+import "testing"
+
+func TestDifferCheckTimes(t *testing.T) {
+	tests := []struct {
+		goType       string
+		nullWrappers bool
+		want         string
+	}{
+		{"time.Time", false, "!a.Equal(b)"},
+		{"*time.Time", false, "(a == nil) != (b == nil) || a != nil && !a.Equal(*b)"},
+		{"sql.NullTime", false, "a.Valid != b.Valid || a.Valid && !a.Time.Equal(b.Time)"},
+		{"NullTime", true, "a.Valid != b.Valid || a.Valid && !a.Time.Equal(b.Time)"},
+		{"sql.NullString", false, "a != b"},
+	}
+	defer func(saved bool) { *nullWrappers = saved }(*nullWrappers)
+	for _, tc := range tests {
+		t.Run(tc.goType, func(t *testing.T) {
+			*nullWrappers = tc.nullWrappers
+			if got, _ := differCheck("a", "b", tc.goType); got != tc.want {
+				t.Errorf("differCheck(%s) = %s, want %s", tc.goType, got, tc.want)
+			}
+		})
+	}
+}
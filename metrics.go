@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"strconv"
+)
+
+var metrics = flag.Bool("metrics", false, "Generate a MetricsDB observing the queries of the data access functions in Prometheus, labeled by function, table and error")
+
+// writeQueryLabel emits the statement labeling the queries of a generated
+// data access function for MetricsDB and TracingDB, with -metrics or
+// -tracing.
+func writeQueryLabel(buffer *bytes.Buffer, name, table string) {
+	if *metrics || *tracing {
+		buffer.WriteString("\tctx = withQueryLabels(ctx, " + strconv.Quote(name) + ", " + strconv.Quote(table) + ")\n")
+	}
+}
+
+// writeQueryLabels emits the context key the data access functions put
+// their name and table under.
+func writeQueryLabels(buffer *bytes.Buffer) {
+	buffer.WriteString("type queryLabelsKey struct{}\n\n")
+	buffer.WriteString("// queryLabels are the function and table labeling the queries of a\n")
+	buffer.WriteString("// generated function.\n")
+	buffer.WriteString("type queryLabels struct {\n\tquery, table string\n}\n\n")
+
+	buffer.WriteString("func withQueryLabels(ctx context.Context, query, table string) context.Context {\n")
+	buffer.WriteString("\treturn context.WithValue(ctx, queryLabelsKey{}, queryLabels{query, table})\n}")
+}
+
+// writeMetrics emits MetricsDB, a DBTX wrapper recording the duration and
+// the count of the queries run through it in a Prometheus histogram and
+// counter, labeled by the generated function and table found in the context
+// and by whether they failed. The collectors are registered with the
+// Registerer given, sharing those already registered by another MetricsDB.
+func writeMetrics(buffer *bytes.Buffer) {
+	buffer.WriteString("// MetricsDB is a DBTX recording the queries of the generated functions in\n")
+	buffer.WriteString("// the db_query_duration_seconds histogram and db_queries_total counter,\n")
+	buffer.WriteString("// labeled by query (the function), table and error (\"true\" or \"false\").\n")
+	buffer.WriteString("// Queries returning rows are timed up to their first result.\n")
+	buffer.WriteString("type MetricsDB struct {\n")
+	buffer.WriteString("\tdb       DBTX\n")
+	buffer.WriteString("\tduration *prometheus.HistogramVec\n")
+	buffer.WriteString("\ttotal    *prometheus.CounterVec\n}\n\n")
+
+	buffer.WriteString("// NewMetricsDB returns a MetricsDB running the queries on db and registering\n")
+	buffer.WriteString("// its collectors with reg, prometheus.DefaultRegisterer when nil. Collectors\n")
+	buffer.WriteString("// already registered by another MetricsDB are shared.\n")
+	buffer.WriteString("func NewMetricsDB(db DBTX, reg prometheus.Registerer) (*MetricsDB, error) {\n")
+	buffer.WriteString("\tif reg == nil {\n\t\treg = prometheus.DefaultRegisterer\n\t}\n")
+	buffer.WriteString("\tlabels := []string{\"query\", \"table\", \"error\"}\n")
+	buffer.WriteString("\tm := &MetricsDB{\n")
+	buffer.WriteString("\t\tdb: db,\n")
+	buffer.WriteString("\t\tduration: prometheus.NewHistogramVec(prometheus.HistogramOpts{\n")
+	buffer.WriteString("\t\t\tName:    \"db_query_duration_seconds\",\n")
+	buffer.WriteString("\t\t\tHelp:    \"Duration of the queries of the generated data access functions.\",\n")
+	buffer.WriteString("\t\t\tBuckets: prometheus.DefBuckets,\n")
+	buffer.WriteString("\t\t}, labels),\n")
+	buffer.WriteString("\t\ttotal: prometheus.NewCounterVec(prometheus.CounterOpts{\n")
+	buffer.WriteString("\t\t\tName: \"db_queries_total\",\n")
+	buffer.WriteString("\t\t\tHelp: \"Queries run by the generated data access functions.\",\n")
+	buffer.WriteString("\t\t}, labels),\n")
+	buffer.WriteString("\t}\n")
+	buffer.WriteString("\tif err := reg.Register(m.duration); err != nil {\n")
+	buffer.WriteString("\t\tregistered, ok := err.(prometheus.AlreadyRegisteredError)\n")
+	buffer.WriteString("\t\tif !ok {\n\t\t\treturn nil, err\n\t\t}\n")
+	buffer.WriteString("\t\tm.duration = registered.ExistingCollector.(*prometheus.HistogramVec)\n")
+	buffer.WriteString("\t}\n")
+	buffer.WriteString("\tif err := reg.Register(m.total); err != nil {\n")
+	buffer.WriteString("\t\tregistered, ok := err.(prometheus.AlreadyRegisteredError)\n")
+	buffer.WriteString("\t\tif !ok {\n\t\t\treturn nil, err\n\t\t}\n")
+	buffer.WriteString("\t\tm.total = registered.ExistingCollector.(*prometheus.CounterVec)\n")
+	buffer.WriteString("\t}\n")
+	buffer.WriteString("\treturn m, nil\n}\n\n")
+
+	buffer.WriteString("func (m *MetricsDB) observe(ctx context.Context, start time.Time, err error) {\n")
+	buffer.WriteString("\tlabels, _ := ctx.Value(queryLabelsKey{}).(queryLabels)\n")
+	buffer.WriteString("\tfailed := strconv.FormatBool(err != nil)\n")
+	buffer.WriteString("\tm.duration.WithLabelValues(labels.query, labels.table, failed).Observe(time.Since(start).Seconds())\n")
+	buffer.WriteString("\tm.total.WithLabelValues(labels.query, labels.table, failed).Inc()\n}\n\n")
+
+	buffer.WriteString("func (m *MetricsDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {\n")
+	buffer.WriteString("\tstart := time.Now()\n")
+	buffer.WriteString("\tres, err := m.db.ExecContext(ctx, query, args...)\n")
+	buffer.WriteString("\tm.observe(ctx, start, err)\n")
+	buffer.WriteString("\treturn res, err\n}\n\n")
+
+	buffer.WriteString("func (m *MetricsDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {\n")
+	buffer.WriteString("\tstart := time.Now()\n")
+	buffer.WriteString("\trows, err := m.db.QueryContext(ctx, query, args...)\n")
+	buffer.WriteString("\tm.observe(ctx, start, err)\n")
+	buffer.WriteString("\treturn rows, err\n}\n\n")
+
+	buffer.WriteString("func (m *MetricsDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {\n")
+	buffer.WriteString("\tstart := time.Now()\n")
+	buffer.WriteString("\trow := m.db.QueryRowContext(ctx, query, args...)\n")
+	buffer.WriteString("\tm.observe(ctx, start, row.Err())\n")
+	buffer.WriteString("\treturn row\n}")
+}
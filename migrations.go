@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// writeMigration writes golang-migrate files to dir, named
+// "<UTC timestamp>_<-name>.up.sql" and ".down.sql", turning a database in the
+// state of the snapshot into the live schema and back: creating and dropping
+// tables, and adding, dropping and modifying columns. Created tables take the
+// SHOW CREATE TABLE of the live schema, tables dropped since the snapshot are
+// recreated from its columns and primary key only. They are stubs to review,
+// renames showing as a drop and an add.
+func writeMigration(ctx context.Context, conn *sql.DB, dir string, snapshot, schema Schema) error {
+	before, after := tablesByName(snapshot.Columns), tablesByName(schema.Columns)
+	names := map[string]bool{}
+	for name := range before {
+		names[name] = true
+	}
+	for name := range after {
+		names[name] = true
+	}
+
+	up, down := []string{}, []string{}
+	for _, name := range sortedSet(names) {
+		was, now := before[name], after[name]
+		switch {
+		case was == nil:
+			ddl, err := showCreateTable(ctx, conn, name)
+			if err != nil {
+				return classify(exitConnection, err)
+			}
+			up = append(up, ddl+";")
+			down = append(down, "DROP TABLE "+quoteIdent(name)+";")
+			continue
+		case now == nil:
+			up = append(up, "DROP TABLE "+quoteIdent(name)+";")
+			down = append(down, createTableFromColumns(*was)+";")
+			continue
+		}
+
+		wasColumns := map[string]ColumnSchema{}
+		for _, cs := range was.Columns {
+			wasColumns[cs.ColumnName] = cs
+		}
+		nowColumns := map[string]bool{}
+		for _, cs := range now.Columns {
+			nowColumns[cs.ColumnName] = true
+			old, ok := wasColumns[cs.ColumnName]
+			switch {
+			case !ok:
+				up = append(up, "ALTER TABLE "+quoteIdent(name)+" ADD COLUMN "+columnDDL(cs)+";")
+				down = append(down, "ALTER TABLE "+quoteIdent(name)+" DROP COLUMN "+quoteIdent(cs.ColumnName)+";")
+			case old.ColumnType != cs.ColumnType || old.IsNullable != cs.IsNullable:
+				up = append(up, "ALTER TABLE "+quoteIdent(name)+" MODIFY COLUMN "+columnDDL(cs)+";")
+				down = append(down, "ALTER TABLE "+quoteIdent(name)+" MODIFY COLUMN "+columnDDL(old)+";")
+			}
+		}
+		for _, cs := range was.Columns {
+			if !nowColumns[cs.ColumnName] {
+				up = append(up, "ALTER TABLE "+quoteIdent(name)+" DROP COLUMN "+quoteIdent(cs.ColumnName)+";")
+				down = append(down, "ALTER TABLE "+quoteIdent(name)+" ADD COLUMN "+columnDDL(cs)+";")
+			}
+		}
+	}
+
+	// Undo the changes in the reverse order.
+	for i, j := 0, len(down)-1; i < j; i, j = i+1, j-1 {
+		down[i], down[j] = down[j], down[i]
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return classify(exitWrite, err)
+	}
+	base := filepath.Join(dir, time.Now().UTC().Format("20060102150405")+"_"+*diffName)
+	header := "-- Generated by struct-create diff from " + *diffAgainst + ", review before applying.\n\n"
+	for file, statements := range map[string][]string{base + ".up.sql": up, base + ".down.sql": down} {
+		if err := os.WriteFile(file, []byte(header+strings.Join(statements, "\n\n")+"\n"), 0644); err != nil {
+			return classify(exitWrite, err)
+		}
+	}
+	slog.Info("Wrote the migration", "up", base+".up.sql", "down", base+".down.sql")
+	return nil
+}
+
+// tablesByName returns the tables of the columns by name.
+func tablesByName(columns []ColumnSchema) map[string]*Table {
+	tables := map[string]*Table{}
+	for _, table := range groupTables(columns) {
+		t := table
+		tables[t.Name] = &t
+	}
+	return tables
+}
+
+// showCreateTable returns the CREATE TABLE statement of a table of the
+// schema.
+func showCreateTable(ctx context.Context, conn *sql.DB, table string) (string, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+	var name, ddl string
+	err := conn.QueryRowContext(ctx, "SHOW CREATE TABLE "+quoteIdent(config.DbName)+"."+quoteIdent(table)).Scan(&name, &ddl)
+	return ddl, err
+}
+
+// createTableFromColumns returns a CREATE TABLE statement for a table from
+// its columns and primary key, leaving out its other indexes and options.
+func createTableFromColumns(table Table) string {
+	lines := []string{}
+	for _, cs := range table.Columns {
+		lines = append(lines, "  "+columnDDL(cs))
+	}
+	if pk := primaryKey(table); len(pk) > 0 {
+		lines = append(lines, "  PRIMARY KEY ("+columnList(pk)+")")
+	}
+	return "CREATE TABLE " + quoteIdent(table.Name) + " (\n" + strings.Join(lines, ",\n") + "\n)"
+}
+
+// columnDDL returns the definition of a column in a CREATE or ALTER TABLE
+// statement, leaving out the DEFAULT_GENERATED flag information_schema adds
+// to expression defaults.
+func columnDDL(cs ColumnSchema) string {
+	def := strings.Fields(strings.Replace(columnDefinition(cs), "DEFAULT_GENERATED", "", -1))
+	return quoteIdent(cs.ColumnName) + " " + strings.Join(def, " ")
+}
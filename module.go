@@ -0,0 +1,168 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"go/parser"
+	"go/token"
+	"log/slog"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var initModule = flag.String("init-module", "", "Also write a go.mod declaring this module path in the directory of -out, requiring the modules the generated packages import, so that they can live in their own repository")
+
+// moduleGoVersion is the go directive of the -init-module go.mod, the
+// version the net/http patterns of -handlers need.
+const moduleGoVersion = "1.22"
+
+// knownModules are the modules the generated code may import, by module
+// path, at the version the go.mod of -init-module requires.
+var knownModules = map[string]string{
+	"github.com/apache/arrow-go/v18":      "v18.0.0",
+	"github.com/go-sql-driver/mysql":      "v1.8.1",
+	"github.com/jmoiron/sqlx":             "v1.4.0",
+	"github.com/prometheus/client_golang": "v1.20.5",
+	"go.opentelemetry.io/otel":            "v1.31.0",
+	"go.opentelemetry.io/otel/trace":      "v1.31.0",
+	"google.golang.org/protobuf":          "v1.35.1",
+}
+
+// moduleRoot is the directory of the -init-module go.mod and moduleImports
+// the import paths of the Go files written under it.
+var (
+	moduleRoot    string
+	moduleImports = map[string]bool{}
+)
+
+// checkModulePath validates the -init-module path and places the module in
+// the directory of -out, which the other Go outputs must be in.
+func checkModulePath() error {
+	if *output == "-" {
+		return configError("-init-module writes a go.mod next to the structs and needs -out")
+	}
+	for _, element := range strings.Split(*initModule, "/") {
+		if element == "" || strings.Trim(element, ".") == "" || strings.IndexFunc(element, func(r rune) bool {
+			return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-._~", r))
+		}) >= 0 {
+			return configError("Invalid -init-module path " + *initModule)
+		}
+	}
+	root, err := filepath.Abs(filepath.Dir(*output))
+	if err != nil {
+		return classify(exitWrite, err)
+	}
+	if moduleRoot != "" && moduleRoot != root {
+		return configError("-init-module writes a single go.mod and needs every profile to write -out to " + moduleRoot)
+	}
+	moduleRoot = root
+	for name, dir := range map[string]string{"-factory": *factory, "-seed": *seed} {
+		if dir == "" {
+			continue
+		}
+		if _, ok := moduleDir(dir); !ok {
+			return configError(name + " " + dir + " is outside of the -init-module directory " + moduleRoot)
+		}
+	}
+	return nil
+}
+
+// moduleDir returns the slash-separated path of dir relative to the module
+// root, and whether dir is in the module.
+func moduleDir(dir string) (string, bool) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", false
+	}
+	rel, err := filepath.Rel(moduleRoot, dir)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
+
+// recordImports adds the imports of a Go file written in the module to
+// moduleImports.
+func recordImports(file string, data []byte) {
+	if *initModule == "" || !strings.HasSuffix(file, ".go") {
+		return
+	}
+	if _, ok := moduleDir(filepath.Dir(file)); !ok {
+		return
+	}
+	parsed, err := parser.ParseFile(token.NewFileSet(), file, data, parser.ImportsOnly)
+	if err != nil {
+		slog.Warn("Can't read the imports of a generated file", "file", file, "error", err)
+		return
+	}
+	for _, spec := range parsed.Imports {
+		if importPath, err := strconv.Unquote(spec.Path.Value); err == nil {
+			moduleImports[importPath] = true
+		}
+	}
+}
+
+// importModule returns the known module providing an import path, the
+// longest matching one.
+func importModule(importPath string) (string, bool) {
+	for module := importPath; module != "."; module = path.Dir(module) {
+		if _, ok := knownModules[module]; ok {
+			return module, true
+		}
+	}
+	return "", false
+}
+
+// writeModule writes the go.mod of -init-module, requiring the modules of
+// the generated imports as go mod tidy would, at the versions of
+// knownModules. Imports of other modules, e.g. from type_overrides, are
+// reported to be added with go get.
+func writeModule() error {
+	if moduleRoot == "" {
+		return errors.New("-init-module has no -out directory to write go.mod to")
+	}
+
+	requires, unknown := map[string]bool{}, []string{}
+	for importPath := range moduleImports {
+		first := strings.SplitN(importPath, "/", 2)[0]
+		switch {
+		case !strings.Contains(first, "."):
+			// standard library
+		case importPath == *initModule, strings.HasPrefix(importPath, *initModule+"/"):
+		default:
+			if module, ok := importModule(importPath); ok {
+				requires[module] = true
+			} else {
+				unknown = append(unknown, importPath)
+			}
+		}
+	}
+	modules := []string{}
+	for module := range requires {
+		modules = append(modules, module)
+	}
+	sort.Strings(modules)
+	sort.Strings(unknown)
+
+	var buffer strings.Builder
+	buffer.WriteString("module " + *initModule + "\n\n")
+	buffer.WriteString("go " + moduleGoVersion + "\n")
+	switch len(modules) {
+	case 0:
+	case 1:
+		buffer.WriteString("\nrequire " + modules[0] + " " + knownModules[modules[0]] + "\n")
+	default:
+		buffer.WriteString("\nrequire (\n")
+		for _, module := range modules {
+			buffer.WriteString("\t" + module + " " + knownModules[module] + "\n")
+		}
+		buffer.WriteString(")\n")
+	}
+	if len(unknown) > 0 {
+		slog.Warn("The generated code imports packages of unknown modules, add them to go.mod with go get", "imports", strings.Join(unknown, ", "))
+	}
+	return writeOutput(filepath.Join(moduleRoot, "go.mod"), []byte(buffer.String()))
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// readOptionFile returns the options of the [client] section of a MySQL
+// option file such as ~/.my.cnf, with dashes in their names turned to
+// underscores and quotes around their values removed.
+func readOptionFile(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	options := map[string]string{}
+	section := ""
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || line[0] == '#' || line[0] == ';' || line[0] == '!':
+			continue
+		case line[0] == '[':
+			section = strings.ToLower(strings.TrimSpace(strings.Trim(line, "[]")))
+			continue
+		case section != "client":
+			continue
+		}
+		name, value, _ := strings.Cut(line, "=")
+		name = strings.ReplaceAll(strings.ToLower(strings.TrimSpace(name)), "-", "_")
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		options[name] = value
+	}
+	return options, scanner.Err()
+}
+
+// applyOptionFile overrides the connection settings with the user, password,
+// host, port and socket of the [client] section of a MySQL option file, the
+// my_cnf setting or, without a config file, ~/.my.cnf when it exists, so the
+// tool connects as the mysql client does.
+func applyOptionFile(configured bool) error {
+	path := config.MyCnf
+	if path == "" {
+		if configured {
+			return nil
+		}
+		path = "~/.my.cnf"
+	}
+	file, err := homePath(path)
+	if err != nil {
+		return classify(exitConfig, err)
+	}
+	if _, err := os.Stat(file); err != nil && config.MyCnf == "" {
+		return nil
+	}
+	options, err := readOptionFile(file)
+	if err != nil {
+		return classify(exitConfig, err)
+	}
+
+	if user, ok := options["user"]; ok {
+		config.DbUser = user
+	}
+	if password, ok := options["password"]; ok && password != "" {
+		config.DbPassword = password
+	}
+	if host, ok := options["host"]; ok {
+		config.Host = host
+	}
+	if port, ok := options["port"]; ok {
+		p, err := strconv.Atoi(port)
+		if err != nil {
+			return configError("Invalid port " + port + " in " + path + ", expected a number")
+		}
+		config.Port = p
+	}
+	if socket, ok := options["socket"]; ok {
+		config.Socket = socket
+	}
+	return nil
+}
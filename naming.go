@@ -0,0 +1,583 @@
+package main
+
+import (
+	"go/token"
+	"log/slog"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// typeRenames and fieldRenames hold the names -collisions suffix gives the
+// tables, and the "table.column" columns, whose names collide.
+var (
+	typeRenames  = map[string]string{}
+	fieldRenames = map[string]string{}
+)
+
+// commonInitialisms are the initialisms Go spells in a single case, as listed
+// by golint: user_id becomes UserID and api_url APIURL.
+var commonInitialisms = map[string]bool{
+	"ACL": true, "API": true, "ASCII": true, "CPU": true, "CSS": true, "DNS": true, "EOF": true,
+	"GUID": true, "HTML": true, "HTTP": true, "HTTPS": true, "ID": true, "IP": true, "JSON": true,
+	"LHS": true, "QPS": true, "RAM": true, "RHS": true, "RPC": true, "SLA": true, "SMTP": true,
+	"SQL": true, "SSH": true, "TCP": true, "TLS": true, "TTL": true, "UDP": true, "UI": true,
+	"UID": true, "UUID": true, "URI": true, "URL": true, "UTF8": true, "VM": true, "XML": true,
+	"XMPP": true, "XSRF": true, "XSS": true,
+}
+
+// predeclared are Go's predeclared identifiers, which unexported names would
+// shadow.
+var predeclared = map[string]bool{
+	"any": true, "bool": true, "byte": true, "comparable": true, "complex64": true, "complex128": true,
+	"error": true, "float32": true, "float64": true, "int": true, "int8": true, "int16": true,
+	"int32": true, "int64": true, "rune": true, "string": true, "uint": true, "uint8": true,
+	"uint16": true, "uint32": true, "uint64": true, "uintptr": true, "true": true, "false": true,
+	"iota": true, "nil": true, "append": true, "cap": true, "clear": true, "close": true,
+	"complex": true, "copy": true, "delete": true, "imag": true, "len": true, "make": true,
+	"max": true, "min": true, "new": true, "panic": true, "print": true, "println": true,
+	"real": true, "recover": true,
+}
+
+// generatedMethods are the methods generated on the structs, which fields
+// and -accessors getters can't be named after; such fields get a Field
+// suffix (String becomes StringField).
+var generatedMethods = map[string]bool{
+	"String": true, "Clone": true, "Defaults": true, "TableName": true, "PrimaryKey": true, "Key": true,
+	"IsZero": true, "Changed": true, "Validate": true, "ToProto": true, "Scan": true, "Value": true,
+}
+
+// generatedTypes are the types generated next to the structs, which tables
+// can't be named after; such tables get a Table suffix (StoreTable).
+var generatedTypes = map[string]bool{
+	"Model": true, "Models": true, "DBTX": true, "Store": true, "Statements": true, "RowScanner": true,
+	"TableIndex": true, "TablePartitioning": true, "TablePartition": true, "NullString": true,
+	"NullInt64": true, "NullFloat64": true, "NullBool": true, "NullTime": true, "Change": true,
+	"Cipher": true, "EncryptedString": true, "EncryptedBytes": true,
+	"MetricsDB": true, "TracingDB": true, "Cache": true, "MemoryCache": true,
+}
+
+// generatedParams are the parameters and variables of the generated
+// functions, which column parameters get an Arg suffix to not shadow.
+var generatedParams = map[string]bool{
+	"ctx": true, "db": true, "err": true, "rows": true, "row": true, "result": true, "query": true,
+	"args": true, "key": true, "exists": true, "filter": true, "cursor": true, "limit": true, "asOf": true,
+	"opts": true, "opt": true,
+}
+
+// paramName returns the name of the function parameter holding the value of
+// a column, e.g. userID, or typeArg when it would shadow the variables of
+// the generated function.
+func paramName(cs ColumnSchema) string {
+	param := unexport(columnGoName(cs.TableName, cs.ColumnName))
+	if generatedParams[param] || param == receiverName(typeName(cs.TableName)) {
+		param += "Arg"
+	}
+	return param
+}
+
+// nameWords splits a snake_case, camelCase or PascalCase name into words:
+// any character other than a letter or digit separates words, and so do a
+// lower case letter or digit followed by an upper case one (createdAt) and
+// the last capital of a run followed by a lower case letter (XMLData is XML
+// Data). Digits stay with the word they follow (utf8, street1) and a final s
+// with the run of capitals it pluralizes (URLs).
+func nameWords(name string) []string {
+	words := []string{}
+	parts := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, part := range parts {
+		runes := []rune(part)
+		start := 0
+		for i := 1; i < len(runes); i++ {
+			prev, r := runes[i-1], runes[i]
+			boundary := (unicode.IsLower(prev) || unicode.IsDigit(prev)) && unicode.IsUpper(r)
+			if unicode.IsUpper(prev) && unicode.IsUpper(r) && i+1 < len(runes) && unicode.IsLower(runes[i+1]) {
+				plural := runes[i+1] == 's' && (i+2 == len(runes) || !unicode.IsLower(runes[i+2]))
+				boundary = !plural
+			}
+			if boundary {
+				words = append(words, string(runes[start:i]))
+				start = i
+			}
+		}
+		words = append(words, string(runes[start:]))
+	}
+	return words
+}
+
+// isInitialism reports whether the upper case word is one of the
+// commonInitialisms or of the initialisms config.
+func isInitialism(upper string) bool {
+	if commonInitialisms[upper] {
+		return true
+	}
+	for _, initialism := range config.Initialisms {
+		if strings.ToUpper(initialism) == upper {
+			return true
+		}
+	}
+	return false
+}
+
+// initialism returns the Go spelling of a name part that is an initialism,
+// e.g. "ID" for "id" and "URLs" for "urls".
+func initialism(part string) (string, bool) {
+	upper := strings.ToUpper(part)
+	if isInitialism(upper) {
+		return upper, true
+	}
+	if strings.HasSuffix(upper, "S") && isInitialism(upper[:len(upper)-1]) {
+		return upper[:len(upper)-1] + "s", true
+	}
+	return "", false
+}
+
+// leadingInitialism returns the length of the initialisms name starts with,
+// as formatName spells them, or zero.
+func leadingInitialism(name string) int {
+	n := 0
+	for n < len(name) {
+		length := 0
+		for end := n + 1; end <= len(name); end++ {
+			if isInitialism(name[n:end]) && initialismEnds(name[end:]) {
+				length = end - n
+			}
+		}
+		if length == 0 {
+			break
+		}
+		n += length
+	}
+	return n
+}
+
+// initialismEnds reports whether rest, following an initialism, starts a new
+// word: nothing, an upper case letter, a digit or a plural s.
+func initialismEnds(rest string) bool {
+	if strings.HasPrefix(rest, "s") {
+		rest = rest[1:]
+	}
+	return rest == "" || unicode.IsUpper(rune(rest[0])) || unicode.IsDigit(rune(rest[0]))
+}
+
+// irregularSingulars are the plural words the suffix rules of singularize get
+// wrong, and the uncountable ones kept as they are.
+var irregularSingulars = map[string]string{
+	"people": "person", "children": "child", "men": "man", "women": "woman", "mice": "mouse",
+	"geese": "goose", "feet": "foot", "teeth": "tooth", "oxen": "ox", "indices": "index",
+	"matrices": "matrix", "vertices": "vertex", "criteria": "criterion", "data": "data",
+	"media": "media", "news": "news", "series": "series", "species": "species",
+	"information": "information", "equipment": "equipment", "metadata": "metadata",
+	"movies": "movie", "cookies": "cookie", "shoes": "shoe",
+}
+
+// tableGoName returns the exported type name of a table: its type_names entry,
+// without the package of "auth.Token", or its base name in CamelCase.
+func tableGoName(tableName string) string {
+	if name, ok := typeRenames[tableName]; ok {
+		return name
+	}
+	if name, ok := config.TypeNames[tableName]; ok {
+		return name[strings.LastIndex(name, ".")+1:]
+	}
+	name := formatName(tableBaseName(tableName))
+	if generatedTypes[name] {
+		name += "Table"
+	}
+	return name
+}
+
+// columnGoName returns the exported field name of a column: its field_names
+// entry, as "table.column" or "column", or its name in CamelCase.
+func columnGoName(tableName, columnName string) string {
+	if name, ok := fieldRenames[tableName+"."+columnName]; ok {
+		return name
+	}
+	if name, ok := config.FieldNames[tableName+"."+columnName]; ok {
+		return name
+	}
+	if name, ok := config.FieldNames[columnName]; ok {
+		return name
+	}
+	columnName = renameColumn(columnName)
+	name := formatName(columnName)
+	if config.Naming == "verbatim" {
+		name = verbatimName(columnName)
+	}
+	if generatedMethods[name] {
+		name += "Field"
+	}
+	return name
+}
+
+// RenameRule rewrites the column names matching the regular expression
+// Pattern with Replace, which may refer to its submatches ($1).
+type RenameRule struct {
+	Pattern string `json:"pattern"`
+	Replace string `json:"replace"`
+}
+
+// renamePatterns caches the regular expressions of the rename_rules.
+var renamePatterns = map[string]*regexp.Regexp{}
+
+// compileRenameRules compiles the patterns of the rename_rules into
+// renamePatterns, failing on the first invalid one.
+func compileRenameRules() error {
+	for _, rule := range config.RenameRules {
+		if _, ok := renamePatterns[rule.Pattern]; ok {
+			continue
+		}
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return configError("Invalid rename_rules pattern " + rule.Pattern + ": " + err.Error())
+		}
+		renamePatterns[rule.Pattern] = re
+	}
+	return nil
+}
+
+// renameColumn applies the rename_rules to a column name in order, each to
+// the result of the previous ones. Their patterns are compiled by
+// compileRenameRules.
+func renameColumn(columnName string) string {
+	for _, rule := range config.RenameRules {
+		if re, ok := renamePatterns[rule.Pattern]; ok {
+			columnName = re.ReplaceAllString(columnName, rule.Replace)
+		}
+	}
+	return columnName
+}
+
+// verbatimName returns the column name with its first letter upper cased,
+// characters not allowed in Go identifiers replaced by underscores and an X
+// prefix when it still doesn't start with an upper case letter.
+func verbatimName(columnName string) string {
+	name := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' {
+			return r
+		}
+		return '_'
+	}, columnName)
+	r, size := utf8.DecodeRuneInString(name)
+	if !unicode.IsUpper(unicode.ToUpper(r)) {
+		return "X" + name
+	}
+	return string(unicode.ToUpper(r)) + name[size:]
+}
+
+// tableBaseName returns the table name the type names derive from, without
+// its strip_prefixes prefix and singularized by -singular.
+func tableBaseName(tableName string) string {
+	return singularName(stripPrefix(tableName))
+}
+
+// stripPrefix removes the first of the strip_prefixes the table name, or the
+// table part of a "schema.table" name, starts with.
+func stripPrefix(tableName string) string {
+	i := strings.LastIndex(tableName, ".") + 1
+	for _, prefix := range config.StripPrefixes {
+		if strings.HasPrefix(tableName[i:], prefix) && len(tableName[i:]) > len(prefix) {
+			return tableName[:i] + tableName[i+len(prefix):]
+		}
+	}
+	return tableName
+}
+
+// irregularPlurals are the singular words the suffix rules of pluralize get
+// wrong, and the uncountable ones kept as they are.
+var irregularPlurals = map[string]string{
+	"person": "people", "child": "children", "man": "men", "woman": "women", "mouse": "mice",
+	"goose": "geese", "foot": "feet", "tooth": "teeth", "ox": "oxen", "matrix": "matrices",
+	"vertex": "vertices", "criterion": "criteria", "data": "data", "media": "media", "news": "news",
+	"series": "series", "species": "species", "information": "information", "equipment": "equipment",
+	"metadata": "metadata", "status": "statuses",
+}
+
+// pluralName returns the name used for a collection of structName, the plural
+// of its last word: ListUsers for User, ListPeople for Person. Names already
+// plural, such as the Users struct of the users table without -singular, and
+// uncountable ones are returned as they are.
+func pluralName(structName string) string {
+	words := nameWords(structName)
+	if len(words) == 0 {
+		return structName + "s"
+	}
+	last := words[len(words)-1]
+	i := strings.LastIndex(structName, last)
+	return structName[:i] + pluralize(last) + structName[i+len(last):]
+}
+
+// collectionName returns pluralName, or name with a List suffix when that is
+// name itself, for declarations which can't share the name of the struct
+// (the Users slice type of User, but UsersList of Users).
+func collectionName(name string) string {
+	if plural := pluralName(name); plural != name {
+		return plural
+	}
+	return name + "List"
+}
+
+// pluralize returns the plural of an English word, keeping its case.
+func pluralize(word string) string {
+	lower := strings.ToLower(word)
+	if plural, ok := irregularPlurals[lower]; ok {
+		_, size := utf8.DecodeRuneInString(word)
+		return word[:size] + matchCase(word[size:], plural[size:])
+	}
+	if singularize(word) != word {
+		return word
+	}
+	switch {
+	case word == strings.ToUpper(word) && len(word) > 1:
+		// initialisms: IDs, URLs
+		return word + "s"
+	case strings.HasSuffix(lower, "y") && len(lower) > 1 && !strings.ContainsRune("aeiou", rune(lower[len(lower)-2])):
+		return word[:len(word)-1] + matchCase(word[len(word)-1:], "ies")
+	case strings.HasSuffix(lower, "is") && len(lower) > 3:
+		// analysis, axis
+		return word[:len(word)-2] + matchCase(word[len(word)-2:], "es")
+	case strings.HasSuffix(lower, "s"), strings.HasSuffix(lower, "x"), strings.HasSuffix(lower, "z"),
+		strings.HasSuffix(lower, "ch"), strings.HasSuffix(lower, "sh"):
+		return word + matchCase(word[len(word)-1:], "es")
+	}
+	return word + matchCase(word[len(word)-1:], "s")
+}
+
+// singularName returns the table name with its last word singularized when
+// -singular is set, so that the users table becomes the User struct and
+// order_items OrderItem. The singular_exceptions config overrides it for a
+// whole table name or for a word.
+func singularName(tableName string) string {
+	if !*singular {
+		return tableName
+	}
+	if name, ok := config.SingularExceptions[tableName]; ok {
+		return name
+	}
+	i := strings.LastIndexAny(tableName, "_.") + 1
+	return tableName[:i] + singularize(tableName[i:])
+}
+
+// singularize returns the singular of an English word, keeping its case.
+func singularize(word string) string {
+	lower := strings.ToLower(word)
+	if singular, ok := config.SingularExceptions[lower]; ok {
+		return singular
+	}
+	if singular, ok := irregularSingulars[lower]; ok {
+		_, size := utf8.DecodeRuneInString(word)
+		return word[:size] + singular[1:]
+	}
+	switch {
+	case strings.HasSuffix(lower, "ies") && len(lower) > 4:
+		return word[:len(word)-3] + matchCase(word[len(word)-3:], "y")
+	case strings.HasSuffix(lower, "sses"), strings.HasSuffix(lower, "xes"), strings.HasSuffix(lower, "zzes"),
+		strings.HasSuffix(lower, "ches"), strings.HasSuffix(lower, "shes"):
+		return word[:len(word)-2]
+	case strings.HasSuffix(lower, "ss"), strings.HasSuffix(lower, "us"), strings.HasSuffix(lower, "is"):
+		// address, status, analysis
+		return word
+	case strings.HasSuffix(lower, "s") && len(lower) > 1:
+		return word[:len(word)-1]
+	}
+	return word
+}
+
+// matchCase returns s upper cased when like is upper case.
+func matchCase(like, s string) string {
+	if like == strings.ToUpper(like) {
+		return strings.ToUpper(s)
+	}
+	return s
+}
+
+// resolveCollisions looks for tables mapped to the same type name and columns
+// of a table mapped to the same field name, which would make the output fail
+// to compile. By default it fails naming them; with -collisions suffix the
+// later ones, in table and column order, get a 2, 3... suffix instead.
+func resolveCollisions(columns []ColumnSchema) error {
+	typeRenames, fieldRenames = map[string]string{}, map[string]string{}
+
+	tables := groupTables(columns)
+	tableNames := []string{}
+	for _, table := range tables {
+		tableNames = append(tableNames, table.Name)
+	}
+	suffixedTables, err := disambiguate(tableNames, tableGoName, "tables", "", "type")
+	if err != nil {
+		return err
+	}
+	for name, suffixed := range suffixedTables {
+		typeRenames[name] = suffixed
+	}
+	if err := resolveCompanions(tables); err != nil {
+		return err
+	}
+
+	for _, table := range tables {
+		columnNames := []string{}
+		for _, cs := range table.Columns {
+			columnNames = append(columnNames, cs.ColumnName)
+		}
+		columnField := func(columnName string) string {
+			return columnGoName(table.Name, columnName)
+		}
+		suffixedColumns, err := disambiguate(columnNames, columnField, "columns", " of "+table.Name, "field")
+		if err != nil {
+			return err
+		}
+		for name, suffixed := range suffixedColumns {
+			fieldRenames[table.Name+"."+name] = suffixed
+		}
+	}
+	return nil
+}
+
+// companionTypes returns the types the flags generate next to the struct of
+// a table named name, by what they are: its XKey, XFilter, XBuilder, XCursor
+// and slice.
+func companionTypes(table Table, name string) map[string]string {
+	companions := map[string]string{}
+	if (*keys || *crud || *handlers || *cached) && len(primaryKey(table)) > 1 {
+		companions[name+"Key"] = "key"
+	}
+	if *filters || *counts {
+		companions[name+"Filter"] = "filter"
+	}
+	if *builders {
+		companions[name+"Builder"] = "builder"
+	}
+	if *pagination && len(paginationColumns(table)) > 0 {
+		companions[name+"Cursor"] = "cursor"
+	}
+	if *slices {
+		companions[collectionName(name)] = "slice"
+	}
+	return companions
+}
+
+// resolveCompanions checks the type of each table against the companion
+// types of the others, user_key against the UserKey of user, failing unless
+// -collisions is suffix, which suffixes the type of the table.
+func resolveCompanions(tables []Table) error {
+	owners, taken := map[string]Table{}, map[string]bool{}
+	for _, table := range tables {
+		name := tableGoName(table.Name)
+		owners[name], taken[name] = table, true
+		for companion := range companionTypes(table, name) {
+			taken[companion] = true
+		}
+	}
+	for _, table := range tables {
+		companions := companionTypes(table, tableGoName(table.Name))
+		names := []string{}
+		for companion := range companions {
+			names = append(names, companion)
+		}
+		sort.Strings(names)
+		for _, companion := range names {
+			other, collides := owners[companion]
+			if !collides {
+				continue
+			}
+			collision := "Table " + other.Name + " maps to type " + companion + ", the " + companions[companion] + " type of table " + table.Name
+			if *collisions != "suffix" {
+				return configError(collision + ": rename one with type_names, or run with -collisions suffix")
+			}
+			for i := 2; ; i++ {
+				candidate := companion + strconv.Itoa(i)
+				if !taken[candidate] {
+					taken[candidate] = true
+					delete(owners, companion)
+					owners[candidate] = other
+					typeRenames[other.Name] = candidate
+					for renamed := range companionTypes(other, candidate) {
+						taken[renamed] = true
+					}
+					slog.Warn(collision, "name", other.Name, "renamed", candidate)
+					break
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// disambiguate returns the suffixed Go names of the names whose goName
+// collides with an earlier one, failing instead unless -collisions is suffix.
+func disambiguate(names []string, goName func(string) string, what, of, kind string) (map[string]string, error) {
+	taken := map[string]bool{}
+	for _, name := range names {
+		taken[goName(name)] = true
+	}
+	owners := map[string]string{}
+	suffixed := map[string]string{}
+	for _, name := range names {
+		n := goName(name)
+		owner, collides := owners[n]
+		if !collides {
+			owners[n] = name
+			continue
+		}
+		collision := what + " " + owner + " and " + name + of + " both map to " + kind + " " + n
+		if *collisions != "suffix" {
+			return nil, configError(strings.ToUpper(collision[:1]) + collision[1:] + ": rename one with type_names or field_names, or run with -collisions suffix")
+		}
+		for i := 2; ; i++ {
+			candidate := n + strconv.Itoa(i)
+			if !taken[candidate] {
+				taken[candidate] = true
+				suffixed[name] = candidate
+				slog.Warn(strings.ToUpper(collision[:1])+collision[1:], "name", name, "renamed", candidate)
+				break
+			}
+		}
+	}
+	return suffixed, nil
+}
+
+// lintName returns the problems staticcheck's ST1003 and go vet would report
+// about a generated name: not being a valid identifier, underscores and
+// initialisms not spelled in a single case (UserId for UserID).
+func lintName(name string) []string {
+	if !token.IsIdentifier(name) {
+		return []string{name + " is not a valid Go identifier"}
+	}
+	problems := []string{}
+	if strings.Contains(strings.TrimRight(name, "_"), "_") {
+		problems = append(problems, name+" should not use underscores")
+	}
+	for _, word := range nameWords(name) {
+		if upper := strings.ToUpper(word); word != upper && isInitialism(upper) && word != strings.ToLower(word) {
+			problems = append(problems, name+" should spell "+word+" as "+upper)
+		}
+	}
+	return problems
+}
+
+// checkNames fails listing every type and field name of the tables that
+// wouldn't pass lintName, so that the generated code doesn't trip linters
+// in CI.
+func checkNames(columns []ColumnSchema) error {
+	problems := []string{}
+	for _, table := range groupTables(columns) {
+		for _, problem := range lintName(typeName(table.Name)) {
+			problems = append(problems, "table "+table.Name+": "+problem)
+		}
+		for _, cs := range table.Columns {
+			for _, problem := range lintName(fieldName(cs)) {
+				problems = append(problems, "column "+table.Name+"."+cs.ColumnName+": "+problem)
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return configError("Generated names fail the naming lint checks, rename them with type_names or field_names:\n\t" +
+			strings.Join(problems, "\n\t"))
+	}
+	return nil
+}
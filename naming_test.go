@@ -0,0 +1,51 @@
+package main
+
+import "testing"
+
+func TestCompanionCollisions(t *testing.T) {
+	column := func(table, name, key string) ColumnSchema {
+		return ColumnSchema{TableName: table, ColumnName: name, IsNullable: "NO", DataType: "int", ColumnType: "int", ColumnKey: key, Extra: "auto_increment"}
+	}
+	tables := func(names ...string) []ColumnSchema {
+		columns := []ColumnSchema{}
+		for _, name := range names {
+			columns = append(columns, column(name, "id", "PRI"))
+		}
+		return columns
+	}
+	tests := []struct {
+		name    string
+		flag    *bool
+		columns []ColumnSchema
+		want    string
+	}{
+		{"key", keys, append([]ColumnSchema{column("user", "org_id", "PRI")}, tables("user", "user_key")...), "UserKey2"},
+		{"filter", filters, tables("user", "user_filter"), "UserFilter2"},
+		{"builder", builders, tables("user", "user_builder"), "UserBuilder2"},
+		{"cursor", pagination, tables("user", "user_cursor"), "UserCursor2"},
+		{"slice", slices, tables("user", "users"), "Users2"},
+		{"other flag", builders, tables("user", "user_key"), "UserKey"},
+	}
+	defer func(saved string) { *collisions = saved }(*collisions)
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			defer func(saved bool) { *tc.flag = saved }(*tc.flag)
+			*tc.flag = true
+			table := tc.columns[len(tc.columns)-1].TableName
+			collides := tc.want != formatName(table)
+
+			*collisions = "fail"
+			if err := resolveCollisions(tc.columns); (err != nil) != collides {
+				t.Errorf("resolveCollisions with -collisions fail = %v, want error %v", err, collides)
+			}
+
+			*collisions = "suffix"
+			if err := resolveCollisions(tc.columns); err != nil {
+				t.Fatal(err)
+			}
+			if got := tableGoName(table); got != tc.want {
+				t.Errorf("type of %s = %s, want %s", table, got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+var nullPackage = flag.String("null-package", "", "Also write a null package with NullString, NullInt64... types marshaling to JSON as null or a plain value to this directory, and use them for nullable columns")
+
+// nullWrapperFields maps each generated null wrapper to the value field of the
+// database/sql type it embeds.
+var nullWrapperFields = map[string]string{
+	"NullString":  "String",
+	"NullInt64":   "Int64",
+	"NullFloat64": "Float64",
+	"NullBool":    "Bool",
+	"NullTime":    "Time",
+}
+
+// nullValueTypes are the Go types of the value fields of the null types.
+var nullValueTypes = map[string]string{
+	"String":  "string",
+	"Int64":   "int64",
+	"Float64": "float64",
+	"Bool":    "bool",
+	"Time":    "time.Time",
+}
+
+// nullImportPath is the import path of the -null-package package.
+var nullImportPath string
+
+// nullWrapperTypes returns the null wrappers used by the schema when
+// -null-wrappers is set.
+func nullWrapperTypes(schemas []ColumnSchema) []string {
+	if !*nullWrappers {
+		return nil
+	}
+	seen := map[string]bool{}
+	types := []string{}
+	for _, cs := range schemas {
+		gt, _, err := goType(&cs)
+		if _, ok := nullWrapperFields[gt]; err != nil || !ok || seen[gt] {
+			continue
+		}
+		seen[gt] = true
+		types = append(types, gt)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// sqlNullType returns the database/sql type behind a generated null wrapper
+// or a type of the -null-package package, which has the same fields.
+func sqlNullType(goType string) (string, bool) {
+	if _, ok := nullWrapperFields[goType]; ok && *nullWrappers {
+		return "sql." + goType, true
+	}
+	if name := strings.TrimPrefix(goType, "null."); name != goType && *nullPackage != "" {
+		if _, ok := nullWrapperFields[name]; ok {
+			return "sql." + name, true
+		}
+	}
+	return goType, false
+}
+
+// writeNullWrapper emits a type embedding the database/sql null type, so it
+// scans the same way, but marshals to JSON as null or the plain value.
+func writeNullWrapper(buffer *bytes.Buffer, typeName string) {
+	field := nullWrapperFields[typeName]
+
+	buffer.WriteString("// " + typeName + " is a sql." + typeName + " marshaling to JSON as null or its value.\n")
+	buffer.WriteString("type " + typeName + " struct {\n")
+	buffer.WriteString("\tsql." + typeName + "\n}\n\n")
+
+	writeNullJSON(buffer, typeName, field)
+}
+
+// writeNullJSON emits the MarshalJSON and UnmarshalJSON methods of a null
+// type holding its value in field.
+func writeNullJSON(buffer *bytes.Buffer, typeName, field string) {
+	buffer.WriteString("func (n " + typeName + ") MarshalJSON() ([]byte, error) {\n")
+	buffer.WriteString("\tif !n.Valid {\n")
+	buffer.WriteString("\t\treturn []byte(\"null\"), nil\n")
+	buffer.WriteString("\t}\n")
+	buffer.WriteString("\treturn json.Marshal(n." + field + ")\n}\n\n")
+
+	buffer.WriteString("func (n *" + typeName + ") UnmarshalJSON(data []byte) error {\n")
+	buffer.WriteString("\tif string(data) == \"null\" {\n")
+	buffer.WriteString("\t\t*n = " + typeName + "{}\n")
+	buffer.WriteString("\t\treturn nil\n")
+	buffer.WriteString("\t}\n")
+	buffer.WriteString("\tif err := json.Unmarshal(data, &n." + field + "); err != nil {\n")
+	buffer.WriteString("\t\treturn err\n")
+	buffer.WriteString("\t}\n")
+	buffer.WriteString("\tn.Valid = true\n")
+	buffer.WriteString("\treturn nil\n}")
+}
+
+// checkNullPackage validates -null-package and resolves the import path of
+// its package, named null like its directory.
+func checkNullPackage() error {
+	if *nullWrappers {
+		return configError("-null-wrappers and -null-package both generate the null types, use one of them")
+	}
+	if filepath.Base(filepath.Clean(*nullPackage)) != "null" {
+		return configError("-null-package " + *nullPackage + " must be a directory named null, after its package")
+	}
+	var err error
+	if nullImportPath, err = packageImportPath(*nullPackage); err != nil {
+		return configError("-null-package needs a Go module or -init-module: " + err.Error())
+	}
+	return nil
+}
+
+// writeNullPackage writes the null package to dir: a NullX type per
+// database/sql one, with the same fields, scanning and writing values as
+// database/sql does and marshaling to JSON as null or the plain value, so
+// that the users of the structs don't need to import database/sql.
+func writeNullPackage(dir string) error {
+	names := []string{}
+	for name := range nullWrapperFields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	buffer := bytes.NewBufferString(buildConstraint())
+	buffer.WriteString("// Package null provides the types of nullable columns, marshaling to JSON\n")
+	buffer.WriteString("// as null or their value.\n")
+	buffer.WriteString("package null\n\n")
+	buffer.WriteString("import (\n\t\"database/sql\"\n\t\"database/sql/driver\"\n\t\"encoding/json\"\n\t\"time\"\n)\n")
+	for _, name := range names {
+		field := nullWrapperFields[name]
+		buffer.WriteString("\n// " + name + " is a " + nullValueTypes[field] + " that may be NULL.\n")
+		buffer.WriteString("type " + name + " struct {\n")
+		buffer.WriteString("\t" + field + " " + nullValueTypes[field] + "\n")
+		buffer.WriteString("\tValid bool\n}\n\n")
+
+		buffer.WriteString("func (n *" + name + ") Scan(value interface{}) error {\n")
+		buffer.WriteString("\tvar v sql." + name + "\n")
+		buffer.WriteString("\terr := v.Scan(value)\n")
+		buffer.WriteString("\tn." + field + ", n.Valid = v." + field + ", v.Valid\n")
+		buffer.WriteString("\treturn err\n}\n\n")
+
+		buffer.WriteString("func (n " + name + ") Value() (driver.Value, error) {\n")
+		buffer.WriteString("\tif !n.Valid {\n\t\treturn nil, nil\n\t}\n")
+		buffer.WriteString("\treturn n." + field + ", nil\n}\n\n")
+
+		writeNullJSON(buffer, name, field)
+		buffer.WriteString("\n")
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	return writeOutput(filepath.Join(dir, "null.go"), buffer.Bytes())
+}
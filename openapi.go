@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var openAPIFile = flag.String("openapi", "", "Also write an OpenAPI 3 components/schemas fragment with a schema per table, as YAML or, for .json files, JSON")
+
+// openAPISchema is an OpenAPI 3.0 schema object, of a table or a column.
+type openAPISchema struct {
+	Type                 string                   `json:"type,omitempty" yaml:"type,omitempty"`
+	Format               string                   `json:"format,omitempty" yaml:"format,omitempty"`
+	Nullable             bool                     `json:"nullable,omitempty" yaml:"nullable,omitempty"`
+	MaxLength            int64                    `json:"maxLength,omitempty" yaml:"maxLength,omitempty"`
+	Minimum              *int64                   `json:"minimum,omitempty" yaml:"minimum,omitempty"`
+	Enum                 []interface{}            `json:"enum,omitempty" yaml:"enum,omitempty"`
+	Description          string                   `json:"description,omitempty" yaml:"description,omitempty"`
+	Properties           map[string]openAPISchema `json:"properties,omitempty" yaml:"properties,omitempty"`
+	Required             []string                 `json:"required,omitempty" yaml:"required,omitempty"`
+	AdditionalProperties *bool                    `json:"additionalProperties,omitempty" yaml:"additionalProperties,omitempty"`
+}
+
+// columnOpenAPI returns the OpenAPI 3.0 schema of a column: its JSON Schema
+// with nullable in place of the null type and the byte format in place of
+// the base64 encoding, which 3.0 doesn't know.
+func columnOpenAPI(cs ColumnSchema) openAPISchema {
+	property := columnJSONSchema(cs)
+	schema := openAPISchema{
+		Format:      property.Format,
+		MaxLength:   property.MaxLength,
+		Minimum:     property.Minimum,
+		Enum:        property.Enum,
+		Description: property.Description,
+	}
+	switch t := property.Type.(type) {
+	case string:
+		schema.Type = t
+	case []string:
+		schema.Type, schema.Nullable = t[0], true
+	}
+	if property.ContentEncoding == "base64" {
+		schema.Format = "byte"
+	}
+	return schema
+}
+
+// writeOpenAPI writes the components/schemas fragment of an OpenAPI 3.0
+// document with a schema per table, named as its struct, for specs to $ref,
+// e.g. models.yaml#/components/schemas/Users.
+func writeOpenAPI(path string, schemas []ColumnSchema) error {
+	components := map[string]openAPISchema{}
+	closed := false
+	for _, table := range groupTables(schemas) {
+		schema := openAPISchema{Type: "object", Properties: map[string]openAPISchema{}, AdditionalProperties: &closed}
+		for _, cs := range table.Columns {
+			schema.Properties[cs.ColumnName] = columnOpenAPI(cs)
+			if cs.IsNullable != "YES" {
+				schema.Required = append(schema.Required, cs.ColumnName)
+			}
+		}
+		components[typeName(table.Name)] = schema
+	}
+	fragment := map[string]interface{}{"components": map[string]interface{}{"schemas": components}}
+
+	if strings.HasSuffix(path, ".json") {
+		data, err := json.MarshalIndent(fragment, "", "  ")
+		if err != nil {
+			return err
+		}
+		return writeOutput(path, append(data, '\n'))
+	}
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(fragment); err != nil {
+		return err
+	}
+	return writeOutput(path, buf.Bytes())
+}
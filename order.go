@@ -0,0 +1,61 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// typeLayout returns the size and alignment in bytes of a generated Go type on
+// 64-bit platforms.
+func typeLayout(goType string) (int, int) {
+	goType, _ = sqlNullType(goType)
+	switch {
+	case strings.HasPrefix(goType, "[]"), goType == "json.RawMessage", goType == "EncryptedBytes", goType == "time.Time", goType == "sql.NullString", goType == "sql.NullTime":
+		return 24, 8
+	case goType == "string", goType == "EncryptedString", goType == "sql.NullInt64", goType == "sql.NullFloat64", goType == "interface{}":
+		return 16, 8
+	case goType == "sql.NullBool":
+		return 2, 1
+	case goType == "bool", goType == "int8", goType == "uint8":
+		return 1, 1
+	case goType == "int16", goType == "uint16":
+		return 2, 2
+	case goType == "int32", goType == "uint32", goType == "float32":
+		return 4, 4
+	}
+	return 8, 8
+}
+
+// alignColumns orders columns by alignment and then size, largest first, which
+// minimizes the padding the compiler inserts between fields.
+func alignColumns(columns []ColumnSchema) []ColumnSchema {
+	sorted := append([]ColumnSchema(nil), columns...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		ti, _, _ := goType(&sorted[i])
+		tj, _, _ := goType(&sorted[j])
+		si, ai := typeLayout(ti)
+		sj, aj := typeLayout(tj)
+		if ai != aj {
+			return ai > aj
+		}
+		return si > sj
+	})
+	return sorted
+}
+
+// orderColumns orders columns according to the field_order config: "ordinal"
+// (the default), "alphabetical" or "primary_key_first".
+func orderColumns(columns []ColumnSchema) []ColumnSchema {
+	sorted := append([]ColumnSchema(nil), columns...)
+	switch config.FieldOrder {
+	case "alphabetical":
+		sort.SliceStable(sorted, func(i, j int) bool {
+			return sorted[i].ColumnName < sorted[j].ColumnName
+		})
+	case "primary_key_first":
+		sort.SliceStable(sorted, func(i, j int) bool {
+			return sorted[i].ColumnKey == "PRI" && sorted[j].ColumnKey != "PRI"
+		})
+	}
+	return sorted
+}
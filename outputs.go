@@ -0,0 +1,65 @@
+package main
+
+import (
+	"crypto/sha256"
+	"log/slog"
+	"os"
+)
+
+// stagedOutputs are the output files written by the run, each staged in a
+// ".partial" file next to it until commitOutputs renames them into place, so
+// that an interrupted or failed run leaves the previous outputs untouched.
+var stagedOutputs []string
+
+// writeOutput writes an output file to its staging file, or compares it
+// with the file for the check command. A file whose SHA-256 is already the
+// one of data is left alone, keeping its mtime so that build systems don't
+// redo the work depending on it.
+func writeOutput(path string, data []byte) error {
+	recordImports(path, data)
+	if checkOutputs {
+		return checkOutput(path, data)
+	}
+	if current, err := os.ReadFile(path); err == nil && sha256.Sum256(current) == sha256.Sum256(data) {
+		slog.Debug("Unchanged", "file", path)
+		recordFile(path, true)
+		return nil
+	}
+	recordFile(path, false)
+	if err := os.WriteFile(path+".partial", data, 0644); err != nil {
+		return err
+	}
+	stagedOutputs = append(stagedOutputs, path)
+	return nil
+}
+
+// commitOutputs renames the staged output files into place.
+func commitOutputs() error {
+	defer func() { stagedOutputs = nil }()
+	for _, path := range stagedOutputs {
+		if err := os.Rename(path+".partial", path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// finishRun commits the outputs of a run that succeeded, or else discards
+// them and returns the error of the run.
+func finishRun(err error) error {
+	if err != nil {
+		discardOutputs()
+		return err
+	}
+	return classify(exitWrite, commitOutputs())
+}
+
+// discardOutputs removes the staged output files.
+func discardOutputs() {
+	for _, path := range stagedOutputs {
+		if err := os.Remove(path + ".partial"); err != nil {
+			slog.Warn("Can't remove the partial output", "file", path+".partial", "error", err)
+		}
+	}
+	stagedOutputs = nil
+}
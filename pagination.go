@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"strconv"
+)
+
+// paginationColumns returns the columns a table can be paged on with a
+// keyset cursor: an auto-increment primary key, or an indexed created_at
+// column with the single column primary key as tie-breaker.
+func paginationColumns(table Table) []ColumnSchema {
+	pk := primaryKey(table)
+	if len(pk) != 1 {
+		return nil
+	}
+	for _, cs := range table.Columns {
+		if cs.ColumnName == "created_at" && cs.ColumnKey != "" && cs.IsNullable == "NO" {
+			return []ColumnSchema{cs, pk[0]}
+		}
+	}
+	if hasExtra(pk[0], "auto_increment") {
+		return pk
+	}
+	return nil
+}
+
+// writePagination emits an XCursor type and a ListXAfter function returning
+// the rows following the cursor and the cursor of the last one.
+func writePagination(buffer *bytes.Buffer, structName string, table Table) {
+	keys := paginationColumns(table)
+	if len(keys) == 0 {
+		return
+	}
+	cursorName := structName + "Cursor"
+	r := receiverName(structName)
+
+	buffer.WriteString("// " + cursorName + " is the position after which " + funcName("List", structName) + "After resumes.\n")
+	buffer.WriteString("type " + cursorName + " struct {\n")
+	for _, cs := range keys {
+		gt, _, _ := goType(&cs)
+		buffer.WriteString("\t" + columnGoName(cs.TableName, cs.ColumnName) + " " + gt + "\n")
+	}
+	buffer.WriteString("}\n\n")
+
+	where := "(" + columnList(keys) + ") > (" + placeholders(len(keys)) + ")"
+	if len(keys) == 1 {
+		where = quoteIdent(keys[0].ColumnName) + " > ?"
+	}
+	query := "SELECT " + selectList(table.Columns) + " FROM " + tableIdent(table.Name) +
+		" WHERE " + where + notDeleted(table) + " ORDER BY " + columnList(keys) + " LIMIT ?"
+
+	args := ""
+	next := ""
+	for _, cs := range keys {
+		args += "cursor." + columnGoName(cs.TableName, cs.ColumnName) + ", "
+		next += "\t\tcursor." + columnGoName(cs.TableName, cs.ColumnName) + " = " + r + "." + fieldName(cs) + "\n"
+	}
+
+	buffer.WriteString("func " + funcName("List", structName) + "After(ctx context.Context, " + dbParam() + ", cursor " + cursorName + ", limit int) ([]" + structName + ", " + cursorName + ", error) {\n")
+	writeQueryLabel(buffer, funcName("List", structName)+"After", table.Name)
+	buffer.WriteString("\trows, err := db.QueryContext(ctx, " + strconv.Quote(query) + ", " + args + "limit)\n")
+	buffer.WriteString("\tif err != nil {\n\t\treturn nil, cursor, err\n\t}\n")
+	buffer.WriteString("\tdefer rows.Close()\n\n")
+	buffer.WriteString("\tresult := []" + structName + "{}\n")
+	buffer.WriteString("\tfor rows.Next() {\n")
+	buffer.WriteString("\t\tvar " + r + " " + structName + "\n")
+	buffer.WriteString("\t\tif err := rows.Scan(" + fieldRefs(r, table.Columns, true) + "); err != nil {\n")
+	buffer.WriteString("\t\t\treturn nil, cursor, err\n\t\t}\n")
+	buffer.WriteString("\t\tresult = append(result, " + r + ")\n")
+	buffer.WriteString(next)
+	buffer.WriteString("\t}\n")
+	buffer.WriteString("\treturn result, cursor, rows.Err()\n}")
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"sync"
+)
+
+var parallel = flag.Int("parallel", 4, "Number of db_names schemas introspected at once, each on its own connection; they are generated one after the other")
+
+// introspectSchemas reads the schemas of db_names with -parallel workers,
+// the connection pool bounded to as many connections, returning them in the
+// order of names. The first failure stops the others, and is the error
+// returned rather than the cancellations it caused.
+func introspectSchemas(ctx context.Context, conn *sql.DB, names []string) ([]Schema, error) {
+	if *parallel < 1 {
+		return nil, configError("-parallel must be at least 1")
+	}
+	conn.SetMaxOpenConns(*parallel)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	schemas := make([]Schema, len(names))
+	errs := make([]error, len(names))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(*parallel, len(names)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if ctx.Err() != nil {
+					errs[i] = ctx.Err()
+					continue
+				}
+				if schemas[i], errs[i] = getSchema(ctx, conn, names[i]); errs[i] != nil {
+					cancel()
+				}
+			}
+		}()
+	}
+	for i := range names {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	var canceled error
+	for _, err := range errs {
+		switch {
+		case err == nil:
+		case errors.Is(err, context.Canceled):
+			canceled = err
+		default:
+			return nil, err
+		}
+	}
+	return schemas, canceled
+}
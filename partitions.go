@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+)
+
+// Partitioning describes how a partitioned table is split.
+type Partitioning struct {
+	TableName  string
+	Method     string // RANGE, LIST, HASH, KEY, RANGE COLUMNS...
+	Expression string
+	Partitions []Partition
+}
+
+// Partition is a partition of a table. Description holds the VALUES LESS
+// THAN or VALUES IN bound, empty for HASH and KEY partitioning.
+type Partition struct {
+	Name        string
+	Description string
+}
+
+func getPartitions(ctx context.Context, conn *sql.DB, dbName string) ([]Partitioning, error) {
+	q := "SELECT TABLE_NAME, PARTITION_NAME, PARTITION_METHOD, PARTITION_EXPRESSION, PARTITION_DESCRIPTION " +
+		"FROM PARTITIONS WHERE TABLE_SCHEMA = ? AND PARTITION_NAME IS NOT NULL " +
+		"ORDER BY TABLE_NAME, PARTITION_ORDINAL_POSITION, SUBPARTITION_ORDINAL_POSITION"
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+	rows, err := conn.QueryContext(ctx, q, dbName)
+	if err != nil {
+		return nil, err
+	}
+	partitionings := []Partitioning{}
+	for rows.Next() {
+		var tableName, partitionName string
+		var method, expression, description sql.NullString
+		if err := rows.Scan(&tableName, &partitionName, &method, &expression, &description); err != nil {
+			return nil, err
+		}
+		last := len(partitionings) - 1
+		if last < 0 || partitionings[last].TableName != tableName {
+			partitionings = append(partitionings, Partitioning{TableName: tableName, Method: method.String, Expression: expression.String})
+			last++
+		}
+		// Subpartitions repeat their partition once each.
+		partitions := partitionings[last].Partitions
+		if len(partitions) > 0 && partitions[len(partitions)-1].Name == partitionName {
+			continue
+		}
+		partitionings[last].Partitions = append(partitions, Partition{Name: partitionName, Description: description.String})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return partitionings, nil
+}
+
+// tablePartitioning returns the partitioning of the table, if partitioned.
+func tablePartitioning(table Table, partitionings []Partitioning) (Partitioning, bool) {
+	for _, p := range partitionings {
+		if p.TableName == table.Name {
+			return p, true
+		}
+	}
+	return Partitioning{}, false
+}
+
+// writePartitionComment emits a comment describing the partitioning of the
+// table, placed above its struct.
+func writePartitionComment(buffer *bytes.Buffer, structName string, table Table, partitionings []Partitioning) {
+	p, ok := tablePartitioning(table, partitionings)
+	if !ok {
+		return
+	}
+	buffer.WriteString("// " + structName + " is partitioned by " + p.Method + " (" + p.Expression + "):\n//\n")
+	for _, partition := range p.Partitions {
+		line := "//   - " + partition.Name
+		if partition.Description != "" {
+			line += " " + partitionBound(p.Method) + " (" + partition.Description + ")"
+		}
+		buffer.WriteString(line + "\n")
+	}
+}
+
+// partitionBound returns the clause introducing the partition descriptions of
+// a partitioning method.
+func partitionBound(method string) string {
+	if strings.HasPrefix(method, "LIST") {
+		return "VALUES IN"
+	}
+	return "VALUES LESS THAN"
+}
+
+// writePartitionType emits the TablePartitioning type of the XPartitioning
+// variables.
+func writePartitionType(buffer *bytes.Buffer) {
+	buffer.WriteString("// TablePartitioning describes how a partitioned table is split.\n")
+	buffer.WriteString("type TablePartitioning struct {\n")
+	buffer.WriteString("\tMethod     string\n")
+	buffer.WriteString("\tExpression string\n")
+	buffer.WriteString("\tPartitions []TablePartition\n}\n\n")
+	buffer.WriteString("// TablePartition is a partition and its VALUES LESS THAN or VALUES IN bound.\n")
+	buffer.WriteString("type TablePartition struct {\n")
+	buffer.WriteString("\tName        string\n")
+	buffer.WriteString("\tDescription string\n}")
+}
+
+// writePartitioning emits an XPartitioning variable describing the
+// partitioning of the table. Nothing is written for unpartitioned tables.
+func writePartitioning(buffer *bytes.Buffer, structName string, table Table, partitionings []Partitioning) {
+	p, ok := tablePartitioning(table, partitionings)
+	if !ok {
+		return
+	}
+	buffer.WriteString("\n\n// " + structName + "Partitioning describes the partitions of " + table.Name + ".\n")
+	buffer.WriteString("var " + structName + "Partitioning = TablePartitioning{\n")
+	buffer.WriteString("\tMethod:     " + strconv.Quote(p.Method) + ",\n")
+	buffer.WriteString("\tExpression: " + strconv.Quote(p.Expression) + ",\n")
+	buffer.WriteString("\tPartitions: []TablePartition{\n")
+	for _, partition := range p.Partitions {
+		buffer.WriteString("\t\t{Name: " + strconv.Quote(partition.Name) + ", Description: " + strconv.Quote(partition.Description) + "},\n")
+	}
+	buffer.WriteString("\t},\n}")
+}
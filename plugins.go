@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// pluginRequest is what a plugin reads on stdin.
+type pluginRequest struct {
+	PkgName  string `json:"pkg_name"`
+	DbName   string `json:"db_name"`
+	TagLabel string `json:"tag_label"`
+	// Output is the file of the structs, "-" for stdout.
+	Output string `json:"output"`
+	Schema Schema `json:"schema"`
+}
+
+// pluginResponse is what a plugin writes on stdout: the files to write,
+// their paths relative to the directory of the structs.
+type pluginResponse struct {
+	Files []struct {
+		Path    string `json:"path"`
+		Content string `json:"content"`
+	} `json:"files"`
+}
+
+// runPlugin runs a plugin of the plugins setting, a command line split as
+// go generate does, with the package and its schema as JSON on stdin, and
+// writes the files it answers with next to the structs, along with the other
+// outputs. The plugin's stderr goes to ours, and a plugin exiting with an
+// error fails the run.
+func runPlugin(ctx context.Context, plugin string, schema Schema) error {
+	args, err := splitArgs(plugin)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return configError("Empty command in plugins")
+	}
+	request, err := json.Marshal(pluginRequest{config.PkgName, config.DbName, config.TagLabel, *output, schema})
+	if err != nil {
+		return err
+	}
+
+	var stdout bytes.Buffer
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stdin = bytes.NewReader(request)
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+	slog.Debug("Running plugin", "command", plugin)
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("Plugin %s failed: %w", args[0], err)
+	}
+
+	var response pluginResponse
+	if err := json.Unmarshal(stdout.Bytes(), &response); err != nil {
+		return fmt.Errorf("Plugin %s answered with invalid JSON: %w", args[0], err)
+	}
+	dir := "."
+	if *output != "-" {
+		dir = filepath.Dir(*output)
+	}
+	for _, file := range response.Files {
+		if file.Path == "" || filepath.IsAbs(file.Path) || strings.HasPrefix(filepath.Clean(file.Path), "..") {
+			return fmt.Errorf("Plugin %s answered with the path %q, expected one relative to the directory of the structs", args[0], file.Path)
+		}
+		path := filepath.Join(dir, file.Path)
+		if !checkOutputs {
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				return classify(exitWrite, err)
+			}
+		}
+		if err := writeOutput(path, []byte(file.Content)); err != nil {
+			return classify(exitWrite, err)
+		}
+		slog.Debug("Wrote plugin output", "plugin", args[0], "file", path)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import "bytes"
+
+// writePrepared emits a Statements type implementing DBTX on top of a
+// *sql.DB, preparing each query the first time it runs and reusing the
+// statement afterwards.
+func writePrepared(buffer *bytes.Buffer) {
+	buffer.WriteString("// Statements prepares each query once on db and reuses the statement.\n")
+	buffer.WriteString("// It is safe for concurrent use; Close releases the statements.\n")
+	buffer.WriteString("type Statements struct {\n")
+	buffer.WriteString("\tdb    *sql.DB\n")
+	buffer.WriteString("\tmu    sync.Mutex\n")
+	buffer.WriteString("\tstmts map[string]*sql.Stmt\n}\n\n")
+
+	buffer.WriteString("func NewStatements(db *sql.DB) *Statements {\n")
+	buffer.WriteString("\treturn &Statements{db: db, stmts: map[string]*sql.Stmt{}}\n}\n\n")
+
+	buffer.WriteString("func (s *Statements) prepare(ctx context.Context, query string) (*sql.Stmt, error) {\n")
+	buffer.WriteString("\ts.mu.Lock()\n")
+	buffer.WriteString("\tdefer s.mu.Unlock()\n\n")
+	buffer.WriteString("\tif stmt, ok := s.stmts[query]; ok {\n\t\treturn stmt, nil\n\t}\n")
+	buffer.WriteString("\tstmt, err := s.db.PrepareContext(ctx, query)\n")
+	buffer.WriteString("\tif err != nil {\n\t\treturn nil, err\n\t}\n")
+	buffer.WriteString("\ts.stmts[query] = stmt\n")
+	buffer.WriteString("\treturn stmt, nil\n}\n\n")
+
+	buffer.WriteString("func (s *Statements) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {\n")
+	buffer.WriteString("\tstmt, err := s.prepare(ctx, query)\n")
+	buffer.WriteString("\tif err != nil {\n\t\treturn nil, err\n\t}\n")
+	buffer.WriteString("\treturn stmt.ExecContext(ctx, args...)\n}\n\n")
+
+	buffer.WriteString("func (s *Statements) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {\n")
+	buffer.WriteString("\tstmt, err := s.prepare(ctx, query)\n")
+	buffer.WriteString("\tif err != nil {\n\t\treturn nil, err\n\t}\n")
+	buffer.WriteString("\treturn stmt.QueryContext(ctx, args...)\n}\n\n")
+
+	buffer.WriteString("// QueryRowContext falls back to an unprepared query when preparing fails so\n")
+	buffer.WriteString("// the error surfaces from Scan.\n")
+	buffer.WriteString("func (s *Statements) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {\n")
+	buffer.WriteString("\tstmt, err := s.prepare(ctx, query)\n")
+	buffer.WriteString("\tif err != nil {\n\t\treturn s.db.QueryRowContext(ctx, query, args...)\n\t}\n")
+	buffer.WriteString("\treturn stmt.QueryRowContext(ctx, args...)\n}\n\n")
+
+	buffer.WriteString("// Tx returns a DBTX running the prepared statements inside tx.\n")
+	buffer.WriteString("func (s *Statements) Tx(tx *sql.Tx) DBTX {\n")
+	buffer.WriteString("\treturn txStatements{s, tx}\n}\n\n")
+
+	buffer.WriteString("// Close closes every prepared statement.\n")
+	buffer.WriteString("func (s *Statements) Close() error {\n")
+	buffer.WriteString("\ts.mu.Lock()\n")
+	buffer.WriteString("\tdefer s.mu.Unlock()\n\n")
+	buffer.WriteString("\tvar first error\n")
+	buffer.WriteString("\tfor query, stmt := range s.stmts {\n")
+	buffer.WriteString("\t\tif err := stmt.Close(); err != nil && first == nil {\n\t\t\tfirst = err\n\t\t}\n")
+	buffer.WriteString("\t\tdelete(s.stmts, query)\n")
+	buffer.WriteString("\t}\n")
+	buffer.WriteString("\treturn first\n}\n\n")
+
+	buffer.WriteString("type txStatements struct {\n\ts  *Statements\n\ttx *sql.Tx\n}\n\n")
+
+	buffer.WriteString("func (t txStatements) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {\n")
+	buffer.WriteString("\tstmt, err := t.s.prepare(ctx, query)\n")
+	buffer.WriteString("\tif err != nil {\n\t\treturn nil, err\n\t}\n")
+	buffer.WriteString("\treturn t.tx.StmtContext(ctx, stmt).ExecContext(ctx, args...)\n}\n\n")
+
+	buffer.WriteString("func (t txStatements) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {\n")
+	buffer.WriteString("\tstmt, err := t.s.prepare(ctx, query)\n")
+	buffer.WriteString("\tif err != nil {\n\t\treturn nil, err\n\t}\n")
+	buffer.WriteString("\treturn t.tx.StmtContext(ctx, stmt).QueryContext(ctx, args...)\n}\n\n")
+
+	buffer.WriteString("func (t txStatements) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {\n")
+	buffer.WriteString("\tstmt, err := t.s.prepare(ctx, query)\n")
+	buffer.WriteString("\tif err != nil {\n\t\treturn t.tx.QueryRowContext(ctx, query, args...)\n\t}\n")
+	buffer.WriteString("\treturn t.tx.StmtContext(ctx, stmt).QueryRowContext(ctx, args...)\n}")
+}
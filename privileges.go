@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// accessDenied reports whether err is MySQL refusing SELECT on a table
+// (1142) or on some of its columns (1143).
+func accessDenied(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && (mysqlErr.Number == 1142 || mysqlErr.Number == 1143)
+}
+
+// probeSelect runs a query selecting columns from the table without reading
+// any row, reporting whether the user may SELECT them.
+func probeSelect(ctx context.Context, conn *sql.DB, dbName, table string, columns []ColumnSchema) (bool, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+	rows, err := conn.QueryContext(ctx, "SELECT "+columnList(columns)+" FROM "+quoteIdent(dbName)+"."+quoteIdent(table)+" LIMIT 0")
+	if accessDenied(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, rows.Close()
+}
+
+// unreadableColumns returns the columns, as "table.column", the user can see
+// in information_schema but not SELECT, probing each table and, when denied,
+// each of its columns. Privileges may come from the global, schema, table or
+// column level or from roles, so asking the server beats reading
+// COLUMN_PRIVILEGES.
+func unreadableColumns(ctx context.Context, conn *sql.DB, dbName string, columns []ColumnSchema) ([]string, error) {
+	unreadable := []string{}
+	tables := groupTables(columns)
+	checked := newProgress("Checking privileges", len(tables))
+	for _, table := range tables {
+		checked.advance()
+		ok, err := probeSelect(ctx, conn, dbName, table.Name, table.Columns)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			continue
+		}
+		for _, cs := range table.Columns {
+			ok, err := probeSelect(ctx, conn, dbName, table.Name, []ColumnSchema{cs})
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				unreadable = append(unreadable, cs.TableName+"."+cs.ColumnName)
+			}
+		}
+	}
+	return unreadable, nil
+}
+
+// checkPrivileges handles the columns the user can't SELECT as set by
+// -unreadable: failing, or dropping them with a warning.
+func checkPrivileges(ctx context.Context, conn *sql.DB, dbName string, schema Schema) (Schema, error) {
+	if *unreadable == "keep" {
+		return schema, nil
+	}
+	columns, err := unreadableColumns(ctx, conn, dbName, schema.Columns)
+	if err != nil || len(columns) == 0 {
+		return schema, err
+	}
+	if *unreadable != "skip" {
+		return schema, configError("User " + config.DbUser + " can't SELECT " + strings.Join(columns, ", ") + " in " + dbName +
+			": grant SELECT on them, add them to exclude_columns or run with -unreadable skip")
+	}
+
+	slog.Warn("Skipping the columns the user can't SELECT", "user", config.DbUser, "columns", strings.Join(columns, ", "))
+	denied := map[string]bool{}
+	for _, column := range columns {
+		denied[column] = true
+	}
+	return dropColumns(schema, func(tableName, columnName string) bool {
+		return denied[tableName+"."+columnName]
+	}), nil
+}
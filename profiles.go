@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log/slog"
+	"sort"
+)
+
+var (
+	profile     = flag.String("profile", "", "Run the named profile of the config file")
+	allProfiles = flag.Bool("all", false, "Run every profile of the config file")
+)
+
+// profileNames returns the profiles to run, the one named by -profile or,
+// with -all, every profile of the config file in name order.
+func profileNames() ([]string, error) {
+	if *allProfiles {
+		if *profile != "" {
+			return nil, configError("Use either -profile or -all")
+		}
+		if len(config.Profiles) == 0 {
+			return nil, configError("-all needs profiles in the config file")
+		}
+		names := []string{}
+		for name := range config.Profiles {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return names, nil
+	}
+	if *profile == "" {
+		return nil, nil
+	}
+	if _, ok := config.Profiles[*profile]; !ok {
+		return nil, configError("Unknown profile " + *profile)
+	}
+	return []string{*profile}, nil
+}
+
+// runProfiles runs each profile in turn, starting every time from the
+// settings of the config file overridden by the ones of the profile.
+func runProfiles(ctx context.Context, names []string) error {
+	base, err := json.Marshal(config)
+	if err != nil {
+		return err
+	}
+	out := *output
+	for _, name := range names {
+		config = Configuration{}
+		if err := json.Unmarshal(base, &config); err != nil {
+			return err
+		}
+		if err := json.Unmarshal(config.Profiles[name], &config); err != nil {
+			return configError("Invalid profile " + name + ": " + err.Error())
+		}
+		*output = out
+		slog.Info("Running profile", "profile", name)
+		if err := run(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+var showProgress = flag.Bool("progress", false, "Report progress on stderr: a progress bar on a terminal, a status line every second otherwise")
+
+// progress reports how far a step over many tables has got.
+type progress struct {
+	step     string
+	total    int
+	done     int
+	terminal bool
+	last     time.Time
+}
+
+// isTerminal reports whether f is a terminal rather than a file or pipe.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// newProgress starts reporting a step over total items, when -progress is
+// set.
+func newProgress(step string, total int) *progress {
+	return &progress{step: step, total: total, terminal: isTerminal(os.Stderr)}
+}
+
+// advance counts an item done, redrawing the progress bar on a terminal or
+// printing a status line at most every second, and always for the last one.
+func (p *progress) advance() {
+	p.done++
+	if !*showProgress {
+		return
+	}
+	last := p.done == p.total
+	if !last && time.Since(p.last) < time.Second && !p.terminal {
+		return
+	}
+	p.last = time.Now()
+
+	if !p.terminal {
+		fmt.Fprintf(os.Stderr, "%s: %d/%d tables\n", p.step, p.done, p.total)
+		return
+	}
+	const width = 30
+	filled := width * p.done / p.total
+	fmt.Fprintf(os.Stderr, "\r%s [%s%s] %d/%d", p.step, strings.Repeat("#", filled), strings.Repeat(" ", width-filled), p.done, p.total)
+	if last {
+		fmt.Fprintln(os.Stderr)
+	}
+}
+
+// reportProgress prints a status line when -progress is set.
+func reportProgress(format string, args ...interface{}) {
+	if *showProgress {
+		fmt.Fprintf(os.Stderr, format+"\n", args...)
+	}
+}
@@ -0,0 +1,316 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// protoWrappers maps the database/sql null types to the well-known protobuf
+// message holding their value and the wrapperspb/timestamppb constructor.
+var protoWrappers = map[string][2]string{
+	"sql.NullString":  {"google.protobuf.StringValue", "wrapperspb.String"},
+	"sql.NullInt64":   {"google.protobuf.Int64Value", "wrapperspb.Int64"},
+	"sql.NullFloat64": {"google.protobuf.DoubleValue", "wrapperspb.Double"},
+	"sql.NullBool":    {"google.protobuf.BoolValue", "wrapperspb.Bool"},
+	"sql.NullTime":    {"google.protobuf.Timestamp", "timestamppb.New"},
+}
+
+// protoField returns the protobuf type of a field of type goType and, for
+// null types, the database/sql type behind it.
+func protoField(goType string) (string, string, bool) {
+	switch goType {
+	case "string", "int64":
+		return goType, "", true
+	case "float64":
+		return "double", "", true
+	case "[]byte":
+		return "bytes", "", true
+	case "time.Time":
+		return "google.protobuf.Timestamp", "", true
+	}
+	sqlType := goType
+	if wrapped, ok := sqlNullType(goType); ok {
+		sqlType = wrapped
+	}
+	if wrapper, ok := protoWrappers[sqlType]; ok {
+		return wrapper[0], sqlType, true
+	}
+	return "", "", false
+}
+
+// protoDecimal maps the protobuf types of DECIMAL columns, float64 in Go, to
+// the ones keeping their exact value as a string.
+var protoDecimal = map[string]string{
+	"double":                      "string",
+	"google.protobuf.DoubleValue": "google.protobuf.StringValue",
+}
+
+// protoColumn returns the protobuf type of a column, the database/sql type
+// behind it for null types, and whether it is a DECIMAL sent as a string.
+func protoColumn(cs ColumnSchema) (string, string, bool, bool) {
+	gt, _, err := goType(&cs)
+	if err != nil {
+		return "", "", false, false
+	}
+	pt, sqlType, ok := protoField(gt)
+	if decimal, isDecimal := protoDecimal[pt]; ok && isDecimal && cs.DataType == "decimal" {
+		return decimal, sqlType, true, true
+	}
+	return pt, sqlType, false, ok
+}
+
+// protoNumbers are the field numbers of the columns of each table, read from
+// and written to the sidecar of the -proto file so that they stay the same
+// across regenerations. Columns dropped from a table keep their number, which
+// the message reserves.
+type protoNumbers map[string]map[string]int
+
+// protoNumbersPath returns the sidecar of a proto file: models.proto keeps
+// its field numbers in models.fields.json.
+func protoNumbersPath(file string) string {
+	return strings.TrimSuffix(file, ".proto") + ".fields.json"
+}
+
+// readProtoNumbers reads the sidecar of a proto file, empty when there is
+// none yet.
+func readProtoNumbers(file string) (protoNumbers, error) {
+	numbers := protoNumbers{}
+	data, err := os.ReadFile(protoNumbersPath(file))
+	if os.IsNotExist(err) {
+		return numbers, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &numbers); err != nil {
+		return nil, configError("Invalid field numbers " + protoNumbersPath(file) + ": " + err.Error())
+	}
+	return numbers, nil
+}
+
+// number returns the field number of a column at position i of a table,
+// giving a new column its position when it is free and else the next number
+// after the highest of the table.
+func (numbers protoNumbers) number(table, column string, i int) int {
+	fields := numbers[table]
+	if fields == nil {
+		fields = map[string]int{}
+		numbers[table] = fields
+	}
+	if n, ok := fields[column]; ok {
+		return n
+	}
+	n, highest := i+1, 0
+	for _, used := range fields {
+		if used == n {
+			n = 0
+		}
+		if used > highest {
+			highest = used
+		}
+	}
+	if n == 0 {
+		n = highest + 1
+	}
+	fields[column] = n
+	return n
+}
+
+// protoPackageName returns the Go package name of the code generated from the
+// proto file: the last element of its go_package option.
+func protoPackageName() string {
+	goPackage := config.ProtoGoPackage
+	if i := strings.Index(goPackage, ";"); i >= 0 {
+		return goPackage[i+1:]
+	}
+	return path.Base(goPackage)
+}
+
+// protoGoName returns the Go name protoc-gen-go gives the field of a message:
+// unlike formatName it keeps initialisms as written (user_id is UserId) and
+// underscores before digits (street_1 is Street_1).
+func protoGoName(name string) string {
+	isLower := func(c byte) bool { return 'a' <= c && c <= 'z' }
+	b := []byte{}
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		switch {
+		case c == '_' && i == 0:
+			b = append(b, 'X')
+		case c == '_' && i+1 < len(name) && isLower(name[i+1]):
+		case '0' <= c && c <= '9':
+			b = append(b, c)
+		default:
+			if isLower(c) {
+				c -= 'a' - 'A'
+			}
+			b = append(b, c)
+			for ; i+1 < len(name) && isLower(name[i+1]); i++ {
+				b = append(b, name[i+1])
+			}
+		}
+	}
+	return string(b)
+}
+
+// writeProto writes a proto3 file with one message per table and a service
+// with Get, Create, Update and Delete methods. Fields are numbered after the
+// column positions the first time, the numbers being kept in a sidecar file
+// for the next runs. Columns without a protobuf mapping are left out.
+func writeProto(file string, schemas []ColumnSchema) error {
+	numbers, err := readProtoNumbers(file)
+	if err != nil {
+		return err
+	}
+	body := &bytes.Buffer{}
+	imports := map[string]bool{}
+	for _, table := range groupTables(schemas) {
+		message := tableGoName(table.Name)
+
+		body.WriteString("\nmessage " + message + " {\n")
+		present := map[string]bool{}
+		for i, cs := range table.Columns {
+			gt, _, err := goType(&cs)
+			if err != nil {
+				return err
+			}
+			pt, _, _, ok := protoColumn(cs)
+			if !ok {
+				body.WriteString("  // " + cs.ColumnName + " has no protobuf mapping for " + gt + "\n")
+				continue
+			}
+			present[cs.ColumnName] = true
+			switch {
+			case pt == "google.protobuf.Timestamp":
+				imports["google/protobuf/timestamp.proto"] = true
+			case strings.HasPrefix(pt, "google.protobuf."):
+				imports["google/protobuf/wrappers.proto"] = true
+			}
+			body.WriteString("  " + pt + " " + cs.ColumnName + " = " + strconv.Itoa(numbers.number(table.Name, cs.ColumnName, i)) + ";\n")
+		}
+		dropped := map[string]bool{}
+		for column := range numbers[table.Name] {
+			if !present[column] {
+				dropped[column] = true
+			}
+		}
+		for _, column := range sortedSet(dropped) {
+			body.WriteString("  reserved " + strconv.Itoa(numbers[table.Name][column]) + ";\n")
+			body.WriteString("  reserved " + strconv.Quote(column) + ";\n")
+		}
+		body.WriteString("}\n")
+
+		pk := primaryKey(table)
+		if len(pk) > 0 {
+			fields := ""
+			for i, cs := range pk {
+				pt, _, _, _ := protoColumn(cs)
+				fields += "  " + pt + " " + cs.ColumnName + " = " + strconv.Itoa(i+1) + ";\n"
+			}
+			body.WriteString("\nmessage Get" + message + "Request {\n" + fields + "}\n")
+			body.WriteString("\nmessage Delete" + message + "Request {\n" + fields + "}\n")
+		}
+
+		body.WriteString("\nservice " + message + "Service {\n")
+		if len(pk) > 0 {
+			body.WriteString("  rpc Get" + message + "(Get" + message + "Request) returns (" + message + ");\n")
+		}
+		body.WriteString("  rpc Create" + message + "(" + message + ") returns (" + message + ");\n")
+		if len(pk) > 0 {
+			imports["google/protobuf/empty.proto"] = true
+			body.WriteString("  rpc Update" + message + "(" + message + ") returns (" + message + ");\n")
+			body.WriteString("  rpc Delete" + message + "(Delete" + message + "Request) returns (google.protobuf.Empty);\n")
+		}
+		body.WriteString("}\n")
+	}
+
+	buffer := bytes.NewBufferString("syntax = \"proto3\";\n\n")
+	buffer.WriteString("package " + config.PkgName + ";\n")
+	if config.ProtoGoPackage != "" {
+		buffer.WriteString("\noption go_package = " + strconv.Quote(config.ProtoGoPackage) + ";\n")
+	}
+	if len(imports) > 0 {
+		buffer.WriteString("\n")
+	}
+	for _, imp := range []string{"google/protobuf/empty.proto", "google/protobuf/timestamp.proto", "google/protobuf/wrappers.proto"} {
+		if imports[imp] {
+			buffer.WriteString("import \"" + imp + "\";\n")
+		}
+	}
+	buffer.Write(body.Bytes())
+
+	sidecar, err := json.MarshalIndent(numbers, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeOutput(protoNumbersPath(file), append(sidecar, '\n')); err != nil {
+		return err
+	}
+	return writeOutput(file, buffer.Bytes())
+}
+
+// writeProtoConverters emits a ToProto method and an XFromProto function
+// converting between the struct and the message generated from -proto.
+func writeProtoConverters(buffer *bytes.Buffer, structName string, table Table, neededImports map[string]bool) {
+	r := receiverName(structName)
+	pb := protoPackageName()
+	message := pb + "." + tableGoName(table.Name)
+
+	to := &bytes.Buffer{}
+	from := &bytes.Buffer{}
+	for _, cs := range table.Columns {
+		pt, sqlType, decimal, ok := protoColumn(cs)
+		if !ok {
+			continue
+		}
+		field, protoName := r+"."+fieldName(cs), protoGoName(cs.ColumnName)
+		switch {
+		case decimal && sqlType != "":
+			neededImports["strconv"] = true
+			neededImports["google.golang.org/protobuf/types/known/wrapperspb"] = true
+			to.WriteString("\tif " + field + ".Valid {\n")
+			to.WriteString("\t\tmsg." + protoName + " = wrapperspb.String(strconv.FormatFloat(" + field + ".Float64, 'f', -1, 64))\n\t}\n")
+			from.WriteString("\tif msg." + protoName + " != nil {\n")
+			from.WriteString("\t\t" + field + ".Float64, _ = strconv.ParseFloat(msg." + protoName + ".GetValue(), 64)\n")
+			from.WriteString("\t\t" + field + ".Valid = true\n\t}\n")
+		case decimal:
+			neededImports["strconv"] = true
+			to.WriteString("\tmsg." + protoName + " = strconv.FormatFloat(" + field + ", 'f', -1, 64)\n")
+			from.WriteString("\t" + field + ", _ = strconv.ParseFloat(msg.Get" + protoName + "(), 64)\n")
+		case sqlType != "":
+			value := nullWrapperFields[strings.TrimPrefix(sqlType, "sql.")]
+			constructor := protoWrappers[sqlType][1]
+			neededImports["google.golang.org/protobuf/types/known/"+constructor[:strings.Index(constructor, ".")]] = true
+			to.WriteString("\tif " + field + ".Valid {\n")
+			to.WriteString("\t\tmsg." + protoName + " = " + constructor + "(" + field + "." + value + ")\n\t}\n")
+			from.WriteString("\tif msg." + protoName + " != nil {\n")
+			if sqlType == "sql.NullTime" {
+				from.WriteString("\t\t" + field + ".Time, " + field + ".Valid = msg." + protoName + ".AsTime(), true\n\t}\n")
+			} else {
+				from.WriteString("\t\t" + field + "." + value + ", " + field + ".Valid = msg." + protoName + ".GetValue(), true\n\t}\n")
+			}
+		case pt == "google.protobuf.Timestamp":
+			neededImports["google.golang.org/protobuf/types/known/timestamppb"] = true
+			to.WriteString("\tmsg." + protoName + " = timestamppb.New(" + field + ")\n")
+			from.WriteString("\t" + field + " = msg.Get" + protoName + "().AsTime()\n")
+		default:
+			to.WriteString("\tmsg." + protoName + " = " + field + "\n")
+			from.WriteString("\t" + field + " = msg.Get" + protoName + "()\n")
+		}
+	}
+
+	buffer.WriteString("// ToProto converts " + r + " to its protobuf message.\n")
+	buffer.WriteString("func (" + r + " *" + structName + ") ToProto() *" + message + " {\n")
+	buffer.WriteString("\tmsg := &" + message + "{}\n")
+	buffer.Write(to.Bytes())
+	buffer.WriteString("\treturn msg\n}\n\n")
+
+	buffer.WriteString("func " + structName + "FromProto(msg *" + message + ") *" + structName + " {\n")
+	buffer.WriteString("\t" + r + " := &" + structName + "{}\n")
+	buffer.Write(from.Bytes())
+	buffer.WriteString("\treturn " + r + "\n}")
+}
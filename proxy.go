@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+
+	"github.com/go-sql-driver/mysql"
+	"golang.org/x/net/proxy"
+)
+
+// httpProxy dials through an HTTP proxy with the CONNECT method.
+type httpProxy struct {
+	url *url.URL
+}
+
+// bufferedConn reads what the proxy sent past its response before the
+// connection itself.
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c bufferedConn) Read(p []byte) (int, error) {
+	return c.r.Read(p)
+}
+
+func (p httpProxy) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", p.url.Host)
+	if err != nil {
+		return nil, err
+	}
+	req := "CONNECT " + addr + " HTTP/1.1\r\nHost: " + addr + "\r\n"
+	if p.url.User != nil {
+		password, _ := p.url.User.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(p.url.User.Username() + ":" + password))
+		req += "Proxy-Authorization: Basic " + credentials + "\r\n"
+	}
+	if _, err := conn.Write([]byte(req + "\r\n")); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	r := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(r, nil)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("proxy answered %s", resp.Status)
+	}
+	return bufferedConn{conn, r}, nil
+}
+
+// configureProxy routes the connection through the proxy setting when set, a
+// "socks5://[user:password@]host:port" or "http://[user:password@]host:port"
+// URL, registering a dialer with the driver that opens the TCP connection to
+// host and port from there.
+func configureProxy(cfg *mysql.Config) error {
+	if config.Proxy == "" {
+		return nil
+	}
+	if config.SSHHost != "" {
+		return configError("Use either proxy or ssh_host")
+	}
+	if cfg.Net != "" && cfg.Net != "tcp" {
+		return configError("proxy needs host and port")
+	}
+	u, err := url.Parse(config.Proxy)
+	if err != nil {
+		return configError("Invalid proxy " + config.Proxy + ": " + err.Error())
+	}
+
+	var dialer proxy.ContextDialer
+	switch u.Scheme {
+	case "socks5", "socks5h":
+		d, err := proxy.FromURL(u, proxy.Direct)
+		if err != nil {
+			return configError("Invalid proxy " + config.Proxy + ": " + err.Error())
+		}
+		dialer = d.(proxy.ContextDialer)
+	case "http":
+		dialer = httpProxy{u}
+	default:
+		return configError("Invalid proxy " + config.Proxy + ", expected a socks5:// or http:// URL")
+	}
+
+	addr := cfg.Addr
+	if addr == "" {
+		addr = "127.0.0.1:3306"
+	}
+	mysql.RegisterDialContext("proxy", func(ctx context.Context, _ string) (net.Conn, error) {
+		return dialer.DialContext(ctx, "tcp", addr)
+	})
+	cfg.Net = "proxy"
+	cfg.Addr = addr
+	return nil
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/feature/rds/auth"
+	"github.com/go-sql-driver/mysql"
+)
+
+// rdsBundleURL serves the CA bundle signing the certificates of every RDS
+// and Aurora region.
+const rdsBundleURL = "https://truststore.pki.rds.amazonaws.com/global/global-bundle.pem"
+
+// loadAWSConfig loads the AWS credentials and region from the environment,
+// shared config files or instance role, taking the region from aws_region
+// when set.
+func loadAWSConfig(ctx context.Context) (aws.Config, error) {
+	var opts []func(*awsconfig.LoadOptions) error
+	if config.AwsRegion != "" {
+		opts = append(opts, awsconfig.WithRegion(config.AwsRegion))
+	}
+	awsConfig, err := awsconfig.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return aws.Config{}, fmt.Errorf("Can't load the AWS configuration: %v", err)
+	}
+	return awsConfig, nil
+}
+
+// rdsBundle returns the path of the RDS CA bundle, downloading it to the
+// cache directory of the user the first time.
+func rdsBundle(ctx context.Context) (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, "struct-create", "rds-global-bundle.pem")
+	if _, err := os.Stat(path); err == nil {
+		return path, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", rdsBundleURL, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("Can't download the RDS CA bundle: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("Can't download the RDS CA bundle: %s", resp.Status)
+	}
+	pem, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("Can't download the RDS CA bundle: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, pem, 0644); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// configureAuth sets up the authentication of the connection from the auth
+// setting: "password", the default, uses db_password, while "rds-iam" logs
+// in with an RDS IAM token generated from the AWS credentials and region of
+// the environment (or aws_region) over TLS verified with the RDS CA bundle,
+// unless tls and tls_ca say otherwise.
+func configureAuth(ctx context.Context, cfg *mysql.Config) error {
+	switch config.Auth {
+	case "", "password":
+		return nil
+	case "rds-iam":
+	default:
+		return configError("Invalid auth " + config.Auth + ", expected password or rds-iam")
+	}
+	if cfg.Net != "tcp" {
+		return configError("auth rds-iam needs host and port")
+	}
+
+	awsConfig, err := loadAWSConfig(ctx)
+	if err != nil {
+		return classify(exitConnection, err)
+	}
+	if awsConfig.Region == "" {
+		return configError("auth rds-iam needs aws_region or AWS_REGION")
+	}
+	token, err := auth.BuildAuthToken(ctx, cfg.Addr, awsConfig.Region, cfg.User, awsConfig.Credentials)
+	if err != nil {
+		return classify(exitConnection, fmt.Errorf("Can't generate the RDS IAM token: %v", err))
+	}
+	cfg.Passwd = token
+	cfg.AllowCleartextPasswords = true
+
+	if config.TLS == "" {
+		config.TLS = "true"
+	}
+	if config.TLSCA == "" {
+		if config.TLSCA, err = rdsBundle(ctx); err != nil {
+			return classify(exitConnection, err)
+		}
+	}
+	return nil
+}
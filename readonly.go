@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// writePrivileges are the privileges letting an account change data or
+// schema objects.
+var writePrivileges = map[string]bool{
+	"ALL": true, "ALL PRIVILEGES": true, "INSERT": true, "UPDATE": true, "DELETE": true,
+	"CREATE": true, "DROP": true, "ALTER": true, "INDEX": true, "TRIGGER": true, "EVENT": true,
+	"CREATE VIEW": true, "CREATE ROUTINE": true, "ALTER ROUTINE": true,
+}
+
+// grantPattern matches the privileges and object of a SHOW GRANTS line,
+// e.g. "GRANT SELECT, INSERT ON `shop`.* TO `app`@`%`".
+var grantPattern = regexp.MustCompile(`^GRANT (.+?) ON (.+?) TO `)
+
+// configureReadOnly makes every transaction of the connection read only when
+// the read_only setting is true, so that the server refuses any write whatever
+// the grants of the account.
+func configureReadOnly(cfg *mysql.Config) {
+	if !config.ReadOnly {
+		return
+	}
+	if cfg.Params == nil {
+		cfg.Params = map[string]string{}
+	}
+	cfg.Params["transaction_read_only"] = "1"
+}
+
+// checkWriteGrants fails when the read_only_user setting is true and
+// SHOW GRANTS lists a privilege to write for the account, as a safety net
+// against generating from production with a read-write account.
+func checkWriteGrants(ctx context.Context, conn *sql.DB) error {
+	if !config.ReadOnlyUser {
+		return nil
+	}
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+	rows, err := conn.QueryContext(ctx, "SHOW GRANTS")
+	if err != nil {
+		return classify(exitConnection, err)
+	}
+	defer rows.Close()
+	writes := []string{}
+	for rows.Next() {
+		var grant string
+		if err := rows.Scan(&grant); err != nil {
+			return classify(exitConnection, err)
+		}
+		m := grantPattern.FindStringSubmatch(grant)
+		if m == nil {
+			continue
+		}
+		for _, privilege := range strings.Split(m[1], ",") {
+			if i := strings.Index(privilege, "("); i >= 0 {
+				privilege = privilege[:i]
+			}
+			if privilege = strings.ToUpper(strings.TrimSpace(privilege)); writePrivileges[privilege] {
+				writes = append(writes, privilege+" ON "+m[2])
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return classify(exitConnection, err)
+	}
+	if len(writes) > 0 {
+		return configError("read_only_user is set but " + config.DbUser + " has write privileges: " + strings.Join(writes, ", "))
+	}
+	return nil
+}
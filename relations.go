@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+)
+
+// Relation is a field pointing to the rows of another table through a
+// single column foreign key.
+type Relation struct {
+	Name       string
+	Type       string
+	ForeignKey ForeignKey
+}
+
+// gormTag returns the gorm tag of a column's field.
+func gormTag(cs ColumnSchema) string {
+	tag := "column:" + cs.ColumnName
+	if cs.ColumnKey == "PRI" {
+		tag += ";primaryKey"
+	}
+	if hasExtra(cs, "auto_increment") {
+		tag += ";autoIncrement"
+	}
+	if isReadOnly(cs) {
+		tag += ";->"
+	}
+	return "gorm:" + strconv.Quote(tag)
+}
+
+// relations returns the relation fields of a table: a pointer to the parent
+// row for every foreign key of the table, and a slice of the child rows for
+// every foreign key referencing it. Composite foreign keys and keys to tables
+// that aren't generated are skipped.
+func relations(table Table, tables []Table, foreignKeys []ForeignKey) []Relation {
+	generated := map[string]bool{}
+	for _, t := range tables {
+		generated[t.Name] = true
+	}
+	columns := map[string]int{}
+	for _, fk := range foreignKeys {
+		columns[fk.TableName+"."+fk.ConstraintName]++
+	}
+
+	taken := map[string]bool{}
+	for _, cs := range table.Columns {
+		taken[fieldName(cs)] = true
+	}
+
+	rels := []Relation{}
+	add := func(name, fallback, typ string, fk ForeignKey) {
+		if taken[name] {
+			name = fallback
+		}
+		if taken[name] {
+			return
+		}
+		taken[name] = true
+		rels = append(rels, Relation{Name: name, Type: typ, ForeignKey: fk})
+	}
+
+	for _, fk := range foreignKeys {
+		if columns[fk.TableName+"."+fk.ConstraintName] != 1 || !generated[fk.TableName] || !generated[fk.ReferencedTableName] {
+			continue
+		}
+		if fk.TableName == table.Name {
+			name := formatName(strings.TrimSuffix(fk.ColumnName, "_id"))
+			add(name, name+"Ref", "*"+typeName(fk.ReferencedTableName), fk)
+		}
+		if fk.ReferencedTableName == table.Name {
+			name := pluralName(typeName(fk.TableName))
+			add(name, name+"By"+columnGoName(fk.TableName, fk.ColumnName), "[]"+typeName(fk.TableName), fk)
+		}
+	}
+	return rels
+}
+
+// referencedIdent quotes the table referenced by a foreign key, qualified by
+// its schema when it is in another one.
+func referencedIdent(fk ForeignKey) string {
+	if fk.ReferencedSchema != "" && !strings.Contains(fk.ReferencedTableName, ".") {
+		return quoteIdent(fk.ReferencedSchema) + "." + quoteIdent(fk.ReferencedTableName)
+	}
+	return tableIdent(fk.ReferencedTableName)
+}
+
+// crossSchemaReferences returns the foreign keys of the table, grouped by
+// constraint, referencing a table of another schema that isn't generated
+// alongside it.
+func crossSchemaReferences(table Table, tables []Table, foreignKeys []ForeignKey) [][]ForeignKey {
+	generated := map[string]bool{}
+	for _, t := range tables {
+		generated[t.Name] = true
+	}
+	constraints := map[string]int{}
+	references := [][]ForeignKey{}
+	for _, fk := range foreignKeys {
+		if fk.TableName != table.Name || fk.ReferencedSchema == "" || generated[fk.ReferencedTableName] {
+			continue
+		}
+		i, ok := constraints[fk.ConstraintName]
+		if !ok {
+			i = len(references)
+			constraints[fk.ConstraintName] = i
+			references = append(references, nil)
+		}
+		references[i] = append(references[i], fk)
+	}
+	return references
+}
+
+// referentialActions returns the ON UPDATE and ON DELETE clauses of a foreign
+// key that don't use the default NO ACTION (RESTRICT in InnoDB).
+func referentialActions(fk ForeignKey) string {
+	actions := []string{}
+	if fk.UpdateRule != "" && fk.UpdateRule != "NO ACTION" && fk.UpdateRule != "RESTRICT" {
+		actions = append(actions, "ON UPDATE "+fk.UpdateRule)
+	}
+	if fk.DeleteRule != "" && fk.DeleteRule != "NO ACTION" && fk.DeleteRule != "RESTRICT" {
+		actions = append(actions, "ON DELETE "+fk.DeleteRule)
+	}
+	return strings.Join(actions, " ")
+}
+
+// writeRelations emits the relation fields of a table, with the gorm tags
+// wiring them to their foreign key when -gorm is set and tags excluding them
+// from the column mapping otherwise. Non default referential actions are
+// noted in a comment. Foreign keys into schemas generated in another package,
+// or not at all, get a comment in place of a field.
+func writeRelations(buffer *bytes.Buffer, table Table, tables []Table, foreignKeys []ForeignKey) {
+	for _, fks := range crossSchemaReferences(table, tables, foreignKeys) {
+		columns, referenced := []string{}, []string{}
+		for _, fk := range fks {
+			columns = append(columns, fk.ColumnName)
+			referenced = append(referenced, fk.ReferencedColumnName)
+		}
+		table := fks[0].ReferencedTableName
+		if !strings.Contains(table, ".") {
+			table = fks[0].ReferencedSchema + "." + table
+		}
+		buffer.WriteString("	// " + fks[0].ConstraintName + ": " + strings.Join(columns, ", ") + " references " +
+			table + " (" + strings.Join(referenced, ", ") + ") in another schema, without a relation field.\n")
+	}
+
+	for _, rel := range relations(table, tables, foreignKeys) {
+		fk := rel.ForeignKey
+		buffer.WriteString("\t" + rel.Name + " " + rel.Type)
+		if *gorm {
+			foreignKey := columnGoName(fk.TableName, fk.ColumnName)
+			references := columnGoName(fk.ReferencedTableName, fk.ReferencedColumnName)
+			buffer.WriteString("\t`gorm:\"foreignKey:" + foreignKey + ";references:" + references + "\"`")
+		} else if len(config.TagLabel) > 0 {
+			buffer.WriteString("\t`" + config.TagLabel + ":\"-\"`")
+		}
+		if actions := referentialActions(fk); actions != "" {
+			buffer.WriteString(" // " + fk.ColumnName + " " + actions)
+		}
+		buffer.WriteString("\n")
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"log/slog"
+	"syscall"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// transient reports whether err may go away by itself, as when the database
+// isn't up yet (connection refused), is out of connections (1040) or hit a
+// lock wait timeout (1205) or a deadlock (1213).
+func transient(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return mysqlErr.Number == 1040 || mysqlErr.Number == 1205 || mysqlErr.Number == 1213
+	}
+	return errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, driver.ErrBadConn) || errors.Is(err, mysql.ErrInvalidConn)
+}
+
+// retry runs fn until it succeeds, fails with an error that isn't transient
+// or has been retried as many times as the retries setting allows, waiting
+// retry_backoff (1s by default) before the first retry and twice as long
+// before each next one.
+func retry(ctx context.Context, what string, fn func() error) error {
+	backoff, err := parseTimeout("retry_backoff", config.RetryBackoff)
+	if err != nil {
+		return err
+	}
+	if backoff == 0 {
+		backoff = time.Second
+	}
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt > config.Retries || !transient(err) {
+			return err
+		}
+		slog.Warn("Transient error, retrying", "while", what, "attempt", attempt, "backoff", backoff, "error", err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// ping connects to the database, retrying while it isn't ready.
+func ping(ctx context.Context, conn *sql.DB) error {
+	return retry(ctx, "connecting to the database", func() error {
+		ctx, cancel := queryContext(ctx)
+		defer cancel()
+		return conn.PingContext(ctx)
+	})
+}
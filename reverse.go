@@ -0,0 +1,290 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"log/slog"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// The flags of the reverse command.
+var (
+	reverseTag = new(string)
+	reverseOut = new(string)
+)
+
+// reverseFlags returns the flags of the reverse command.
+func reverseFlags() *flag.FlagSet {
+	flags := flag.NewFlagSet("reverse", flag.ExitOnError)
+	addLogFlags(flags)
+	flags.StringVar(reverseTag, "tag", "db", "Struct tag naming the columns, fields without it being named after the field")
+	flags.StringVar(reverseOut, "out", "-", "Output, \"-\" for stdout")
+	return flags
+}
+
+// sqlTypes maps the Go types of fields to MySQL column types, the null types
+// and pointers making the column nullable.
+var sqlTypes = map[string]string{
+	"int8":            "tinyint",
+	"int16":           "smallint",
+	"int32":           "int",
+	"int":             "bigint",
+	"int64":           "bigint",
+	"uint8":           "tinyint unsigned",
+	"uint16":          "smallint unsigned",
+	"uint32":          "int unsigned",
+	"uint":            "bigint unsigned",
+	"uint64":          "bigint unsigned",
+	"float32":         "float",
+	"float64":         "double",
+	"bool":            "tinyint(1)",
+	"string":          "varchar(255)",
+	"[]byte":          "blob",
+	"time.Time":       "datetime",
+	"json.RawMessage": "json",
+	"sql.NullByte":    "tinyint unsigned",
+	"sql.NullInt16":   "smallint",
+	"sql.NullInt32":   "int",
+	"sql.NullInt64":   "bigint",
+	"sql.NullFloat64": "double",
+	"sql.NullBool":    "tinyint(1)",
+	"sql.NullString":  "varchar(255)",
+	"sql.NullTime":    "datetime",
+}
+
+// reverseCommand implements the reverse command, writing a CREATE TABLE
+// statement for every struct with tagged fields of the Go files given.
+func reverseCommand(args []string) error {
+	flags := reverseFlags()
+	flags.Parse(args)
+	commandFlags = flags
+	if err := setupLogging(); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		return configError("Usage: struct-create reverse [-tag db] [-out schema.sql] file.go...")
+	}
+
+	var ddl strings.Builder
+	for _, path := range flags.Args() {
+		statements, err := reverseFile(path)
+		if err != nil {
+			return err
+		}
+		for _, statement := range statements {
+			if ddl.Len() > 0 {
+				ddl.WriteString("\n")
+			}
+			ddl.WriteString(statement)
+		}
+	}
+	if *reverseOut == "-" {
+		_, err := fmt.Print(ddl.String())
+		return classify(exitWrite, err)
+	}
+	if err := os.WriteFile(*reverseOut, []byte(ddl.String()), 0644); err != nil {
+		return classify(exitWrite, err)
+	}
+	slog.Info("Wrote the DDL", "file", *reverseOut)
+	return nil
+}
+
+// reverseFile returns the CREATE TABLE statements of the structs of a Go
+// file having fields tagged with -tag, in the order of the file. Embedded
+// structs of the file contribute their fields and get no table of their own.
+// The table is named by the TableName method of the struct when it returns a
+// string literal, else after the struct in snake_case.
+func reverseFile(path string) ([]string, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, path, nil, parser.ParseComments)
+	if err != nil {
+		return nil, configError("Invalid Go file " + path + ": " + err.Error())
+	}
+
+	structs := map[string]*ast.StructType{}
+	names := []string{}
+	tableNames := map[string]string{}
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				if ts, ok := spec.(*ast.TypeSpec); ok {
+					if st, ok := ts.Type.(*ast.StructType); ok {
+						structs[ts.Name.Name] = st
+						names = append(names, ts.Name.Name)
+					}
+				}
+			}
+		case *ast.FuncDecl:
+			if d.Recv == nil || d.Name.Name != "TableName" || d.Body == nil || len(d.Body.List) != 1 {
+				continue
+			}
+			ret, ok := d.Body.List[0].(*ast.ReturnStmt)
+			if !ok || len(ret.Results) != 1 {
+				continue
+			}
+			if lit, ok := ret.Results[0].(*ast.BasicLit); ok && lit.Kind == token.STRING {
+				name, _ := strconv.Unquote(lit.Value)
+				tableNames[strings.TrimPrefix(exprString(d.Recv.List[0].Type), "*")] = name
+			}
+		}
+	}
+
+	embedded := map[string]bool{}
+	for _, st := range structs {
+		for _, f := range st.Fields.List {
+			if ident, ok := f.Type.(*ast.Ident); ok && len(f.Names) == 0 && structs[ident.Name] != nil {
+				embedded[ident.Name] = true
+			}
+		}
+	}
+
+	statements := []string{}
+	for _, name := range names {
+		if embedded[name] {
+			continue
+		}
+		columns, err := reverseColumns(structs, structs[name])
+		if err != nil {
+			return nil, fmt.Errorf("%s in %s: %w", name, path, err)
+		}
+		if len(columns) == 0 {
+			continue
+		}
+		table := tableNames[name]
+		if table == "" {
+			table = snakeCase(name)
+		}
+		statements = append(statements, createTable(table, columns))
+	}
+	return statements, nil
+}
+
+// reverseColumn is a column read from a struct field.
+type reverseColumn struct {
+	name, columnType string
+	nullable         bool
+	primaryKey       bool
+	autoIncrement    bool
+}
+
+// reverseColumns returns the columns of the fields of a struct tagged with
+// -tag, and of the structs it embeds. The columns are nullable for null types
+// and pointers, in the primary key when their gorm tag says so or, without
+// one, when named id, and auto increment when their gorm tag or the comment
+// of generated structs says so. VARCHAR columns take their length from a
+// validate:"max=N" tag.
+func reverseColumns(structs map[string]*ast.StructType, st *ast.StructType) ([]reverseColumn, error) {
+	columns := []reverseColumn{}
+	for _, f := range st.Fields.List {
+		if len(f.Names) == 0 {
+			if ident, ok := f.Type.(*ast.Ident); ok && structs[ident.Name] != nil {
+				embedded, err := reverseColumns(structs, structs[ident.Name])
+				if err != nil {
+					return nil, err
+				}
+				columns = append(columns, embedded...)
+			}
+			continue
+		}
+		if f.Tag == nil || !f.Names[0].IsExported() {
+			continue
+		}
+		tag, _ := strconv.Unquote(f.Tag.Value)
+		tags := reflect.StructTag(tag)
+		name, _, _ := strings.Cut(tags.Get(*reverseTag), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			if _, ok := tags.Lookup(*reverseTag); !ok {
+				continue
+			}
+			name = snakeCase(f.Names[0].Name)
+		}
+
+		goType := exprString(f.Type)
+		column := reverseColumn{name: name}
+		if strings.HasPrefix(goType, "*") {
+			goType, column.nullable = goType[1:], true
+		}
+		if strings.HasPrefix(goType, "sql.Null") {
+			column.nullable = true
+		}
+		column.columnType = sqlTypes[goType]
+		if column.columnType == "" {
+			return nil, &exitError{exitUnknownType, fmt.Errorf("No MySQL type for field %s of type %s", f.Names[0].Name, exprString(f.Type))}
+		}
+		if length, ok := strings.CutPrefix(tags.Get("validate"), "max="); ok && strings.HasPrefix(column.columnType, "varchar") {
+			column.columnType = "varchar(" + length + ")"
+		}
+
+		gormTag, hasGorm := tags.Lookup("gorm")
+		for _, setting := range strings.Split(gormTag, ";") {
+			switch setting {
+			case "primaryKey":
+				column.primaryKey = true
+			case "autoIncrement":
+				column.autoIncrement = true
+			}
+		}
+		if !hasGorm && name == "id" {
+			column.primaryKey = true
+		}
+		if f.Doc != nil && strings.Contains(f.Doc.Text(), "Auto increment") {
+			column.autoIncrement = true
+		}
+		columns = append(columns, column)
+	}
+	return columns, nil
+}
+
+// createTable returns the CREATE TABLE statement of a table.
+func createTable(table string, columns []reverseColumn) string {
+	lines := []string{}
+	primaryKey := []string{}
+	for _, column := range columns {
+		line := "  " + quoteIdent(column.name) + " " + strings.ToUpper(column.columnType)
+		if column.nullable {
+			line += " NULL"
+		} else {
+			line += " NOT NULL"
+		}
+		if column.autoIncrement {
+			line += " AUTO_INCREMENT"
+		}
+		lines = append(lines, line)
+		if column.primaryKey {
+			primaryKey = append(primaryKey, quoteIdent(column.name))
+		}
+	}
+	if len(primaryKey) > 0 {
+		lines = append(lines, "  PRIMARY KEY ("+strings.Join(primaryKey, ", ")+")")
+	}
+	return "CREATE TABLE " + quoteIdent(table) + " (\n" + strings.Join(lines, ",\n") + "\n);\n"
+}
+
+// snakeCase turns a Go name into a snake_case one, keeping initialisms
+// together: UserID becomes user_id and HTTPRequest http_request.
+func snakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
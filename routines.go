@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+)
+
+// routineArg returns the Go name of a routine parameter, renamed when it
+// would shadow the wrapper's own variables.
+func routineArg(name string) string {
+	arg := unexport(formatName(name))
+	switch arg {
+	case "ctx", "db", "conn", "err":
+		arg += "Arg"
+	}
+	return arg
+}
+
+// writeRoutine emits a CallX wrapper for a stored routine. Functions return
+// their value; procedures return their result sets as *sql.Rows, or, when
+// they have OUT or INOUT parameters, the values of those read back from
+// session variables on a single connection.
+func writeRoutine(buffer *bytes.Buffer, routine Routine) error {
+	name := funcName("Call", formatName(routine.Name))
+	params, args, placeholders, outputs, sets := []string{}, []string{}, []string{}, []Parameter{}, []Parameter{}
+	var result *Parameter
+	for i, p := range routine.Parameters {
+		switch p.Mode {
+		case "":
+			result = &routine.Parameters[i]
+			continue
+		case "IN", "INOUT":
+			cs := p.ColumnSchema
+			cs.IsNullable = "NO"
+			gt, _, err := goType(&cs)
+			if err != nil {
+				return err
+			}
+			params = append(params, routineArg(p.ColumnName)+" "+gt)
+		}
+		switch p.Mode {
+		case "IN":
+			args = append(args, routineArg(p.ColumnName))
+			placeholders = append(placeholders, "?")
+		case "INOUT":
+			sets = append(sets, p)
+			fallthrough
+		case "OUT":
+			outputs = append(outputs, p)
+			placeholders = append(placeholders, "@"+quoteIdent(p.ColumnName))
+		}
+	}
+	signature := "(ctx context.Context, " + dbParam() + strings.Join(append([]string{""}, params...), ", ") + ")"
+	call := tableIdent(routine.Name) + "(" + strings.Join(placeholders, ", ") + ")"
+	callArgs := strings.Join(append([]string{""}, args...), ", ")
+
+	if result != nil {
+		cs := result.ColumnSchema
+		cs.IsNullable = "YES"
+		gt, _, err := goType(&cs)
+		if err != nil {
+			return err
+		}
+		buffer.WriteString("// " + name + " returns the result of the " + routine.Name + " function.\n")
+		buffer.WriteString("func " + name + signature + " (" + gt + ", error) {\n")
+		writeQueryLabel(buffer, name, "")
+		buffer.WriteString("\tvar result " + gt + "\n")
+		buffer.WriteString("\terr := db.QueryRowContext(ctx, " + strconv.Quote("SELECT "+call) + callArgs + ").Scan(&result)\n")
+		buffer.WriteString("\treturn result, err\n}")
+		return nil
+	}
+
+	if len(outputs) == 0 {
+		buffer.WriteString("// " + name + " calls the " + routine.Name + " procedure and returns its result sets.\n")
+		buffer.WriteString("func " + name + signature + " (*sql.Rows, error) {\n")
+		writeQueryLabel(buffer, name, "")
+		buffer.WriteString("\treturn db.QueryContext(ctx, " + strconv.Quote("CALL "+call) + callArgs + ")\n}")
+		return nil
+	}
+
+	results, variables, targets := []string{}, []string{}, []string{}
+	for _, p := range outputs {
+		cs := p.ColumnSchema
+		cs.IsNullable = "YES"
+		gt, _, err := goType(&cs)
+		if err != nil {
+			return err
+		}
+		result := routineArg(p.ColumnName)
+		if p.Mode == "INOUT" {
+			result += "Out"
+		}
+		results = append(results, result+" "+gt)
+		variables = append(variables, "@"+quoteIdent(p.ColumnName))
+		targets = append(targets, "&"+result)
+	}
+
+	// session variables only live on one connection, so take one from the pool
+	signature = strings.Replace(signature, dbParam(), "db *sql.DB", 1)
+	buffer.WriteString("// " + name + " calls the " + routine.Name + " procedure and returns its OUT parameters.\n")
+	buffer.WriteString("func " + name + signature + " (" + strings.Join(results, ", ") + ", err error) {\n")
+	buffer.WriteString("\tconn, err := db.Conn(ctx)\n")
+	buffer.WriteString("\tif err != nil {\n\t\treturn\n\t}\n")
+	buffer.WriteString("\tdefer conn.Close()\n\n")
+	for _, p := range sets {
+		set := "SET @" + quoteIdent(p.ColumnName) + " = ?"
+		buffer.WriteString("\tif _, err = conn.ExecContext(ctx, " + strconv.Quote(set) + ", " + routineArg(p.ColumnName) + "); err != nil {\n")
+		buffer.WriteString("\t\treturn\n\t}\n")
+	}
+	buffer.WriteString("\tif _, err = conn.ExecContext(ctx, " + strconv.Quote("CALL "+call) + callArgs + "); err != nil {\n")
+	buffer.WriteString("\t\treturn\n\t}\n")
+	buffer.WriteString("\terr = conn.QueryRowContext(ctx, " + strconv.Quote("SELECT "+strings.Join(variables, ", ")) + ").Scan(" + strings.Join(targets, ", ") + ")\n")
+	buffer.WriteString("\treturn\n}")
+	return nil
+}
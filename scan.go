@@ -0,0 +1,40 @@
+package main
+
+import (
+	"bytes"
+	"strconv"
+)
+
+// writeRowScanner emits the RowScanner interface used by the ScanX helpers.
+func writeRowScanner(buffer *bytes.Buffer) {
+	buffer.WriteString("// RowScanner is implemented by *sql.Row and *sql.Rows.\n")
+	buffer.WriteString("type RowScanner interface {\n")
+	buffer.WriteString("\tScan(dest ...interface{}) error\n}")
+}
+
+// writeScanners emits an XColumns constant holding the select list, and
+// ScanX and ScanXs functions scanning rows selected with it.
+func writeScanners(buffer *bytes.Buffer, structName string, table Table) {
+	r := receiverName(structName)
+	columnsConst := structName + "Columns"
+	scan := funcName("Scan", structName)
+
+	buffer.WriteString("// " + columnsConst + " is the select list " + scan + " expects.\n")
+	buffer.WriteString("const " + columnsConst + " = " + strconv.Quote(selectList(table.Columns)) + "\n\n")
+
+	buffer.WriteString("func " + scan + "(row RowScanner) (" + structName + ", error) {\n")
+	buffer.WriteString("\tvar " + r + " " + structName + "\n")
+	buffer.WriteString("\terr := row.Scan(" + fieldRefs(r, table.Columns, true) + ")\n")
+	buffer.WriteString("\treturn " + r + ", err\n}\n\n")
+
+	buffer.WriteString("// " + collectionName(scan) + " scans and closes rows.\n")
+	buffer.WriteString("func " + collectionName(scan) + "(rows *sql.Rows) ([]" + structName + ", error) {\n")
+	buffer.WriteString("\tdefer rows.Close()\n")
+	buffer.WriteString("\tresult := []" + structName + "{}\n")
+	buffer.WriteString("\tfor rows.Next() {\n")
+	buffer.WriteString("\t\t" + r + ", err := " + scan + "(rows)\n")
+	buffer.WriteString("\t\tif err != nil {\n\t\t\treturn nil, err\n\t\t}\n")
+	buffer.WriteString("\t\tresult = append(result, " + r + ")\n")
+	buffer.WriteString("\t}\n")
+	buffer.WriteString("\treturn result, rows.Err()\n}")
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// schemaNames returns the schemas to introspect: the entries of db_names,
+// which may be glob patterns matched against the schemas of the server, or
+// db_name alone.
+func schemaNames(ctx context.Context, conn *sql.DB) ([]string, error) {
+	if len(config.DbNames) == 0 {
+		return []string{config.DbName}, nil
+	}
+
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+	rows, err := conn.QueryContext(ctx, "SELECT SCHEMA_NAME FROM SCHEMATA ORDER BY SCHEMA_NAME")
+	if err != nil {
+		return nil, classify(exitConnection, err)
+	}
+	available := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, classify(exitConnection, err)
+		}
+		available = append(available, name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, classify(exitConnection, err)
+	}
+
+	names := []string{}
+	seen := map[string]bool{}
+	for _, pattern := range config.DbNames {
+		matched := false
+		for _, name := range available {
+			ok, err := path.Match(pattern, name)
+			if err != nil {
+				return nil, configError("Invalid db_names pattern " + pattern + ": " + err.Error())
+			}
+			if !ok {
+				continue
+			}
+			matched = true
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+		if !matched {
+			return nil, configError("No schema matches db_names entry " + pattern)
+		}
+	}
+	return names, nil
+}
+
+// qualifySchema prefixes every table and routine name with the schema name,
+// as "schema.table", so that tables of several schemas can share a package.
+func qualifySchema(schema Schema, dbName string) Schema {
+	prefix := dbName + "."
+	for i := range schema.Columns {
+		schema.Columns[i].TableName = prefix + schema.Columns[i].TableName
+	}
+	for i := range schema.ForeignKeys {
+		schema.ForeignKeys[i].TableName = prefix + schema.ForeignKeys[i].TableName
+		referenced := prefix
+		if schema.ForeignKeys[i].ReferencedSchema != "" {
+			referenced = schema.ForeignKeys[i].ReferencedSchema + "."
+		}
+		schema.ForeignKeys[i].ReferencedTableName = referenced + schema.ForeignKeys[i].ReferencedTableName
+	}
+	for i := range schema.Indexes {
+		schema.Indexes[i].TableName = prefix + schema.Indexes[i].TableName
+	}
+	for i := range schema.Routines {
+		schema.Routines[i].Name = prefix + schema.Routines[i].Name
+	}
+	for i := range schema.Checks {
+		schema.Checks[i].TableName = prefix + schema.Checks[i].TableName
+	}
+	for i := range schema.Partitions {
+		schema.Partitions[i].TableName = prefix + schema.Partitions[i].TableName
+	}
+	for i := range schema.Stats {
+		schema.Stats[i].TableName = prefix + schema.Stats[i].TableName
+	}
+	for i := range schema.Versioned {
+		schema.Versioned[i] = prefix + schema.Versioned[i]
+	}
+	return schema
+}
+
+// mergeSchemas appends everything introspected in other to schema.
+func mergeSchemas(schema, other Schema) Schema {
+	schema.Columns = append(schema.Columns, other.Columns...)
+	schema.ForeignKeys = append(schema.ForeignKeys, other.ForeignKeys...)
+	schema.Indexes = append(schema.Indexes, other.Indexes...)
+	schema.Routines = append(schema.Routines, other.Routines...)
+	schema.Checks = append(schema.Checks, other.Checks...)
+	schema.Partitions = append(schema.Partitions, other.Partitions...)
+	schema.Stats = append(schema.Stats, other.Stats...)
+	schema.Versioned = append(schema.Versioned, other.Versioned...)
+	return schema
+}
+
+// tableIdent quotes a table or routine name, qualified by its schema when it
+// was introspected with a "package" schema_layout.
+func tableIdent(name string) string {
+	if i := strings.Index(name, "."); i >= 0 {
+		return quoteIdent(name[:i]) + "." + quoteIdent(name[i+1:])
+	}
+	return quoteIdent(name)
+}
+
+// schemaPath places an output path of the "packages" schema_layout in a
+// directory named after the schema, e.g. "models/shop/models.go" for
+// "models/models.go".
+func schemaPath(p, dbName string) string {
+	if p == "" {
+		return ""
+	}
+	return filepath.Join(filepath.Dir(p), schemaPackage(dbName), filepath.Base(p))
+}
+
+// inPackageDir runs fn with every output path moved to a directory named
+// after the package, as by schemaPath, and pkg_name set to it.
+func inPackageDir(name string, fn func() error) error {
+	paths := []*string{output, sqlc, doc, example, benchmark, factory, protoFile, seed}
+	bases := make([]string, len(paths))
+	for i, p := range paths {
+		bases[i] = *p
+		*p = schemaPath(*p, name)
+	}
+	pkgName := config.PkgName
+	config.PkgName = schemaPackage(name)
+	defer func() {
+		for i, p := range paths {
+			*p = bases[i]
+		}
+		config.PkgName = pkgName
+	}()
+
+	for _, file := range []string{*output, *doc, *example, *benchmark, *protoFile} {
+		if file == "" || *dryRun || checkOutputs {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			return classify(exitWrite, err)
+		}
+	}
+	return fn()
+}
+
+// tablePackage returns the sub-package the type_names entry of a table
+// places it in, "auth" for "auth.Token", or "" for the main package.
+func tablePackage(tableName string) string {
+	name := config.TypeNames[tableName]
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		return name[:i]
+	}
+	return ""
+}
+
+// schemaPackage turns a schema name into a package name, lowercased and
+// keeping letters and digits only.
+func schemaPackage(dbName string) string {
+	name := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, dbName)
+	if name == "" || unicode.IsDigit(rune(name[0])) {
+		name = "db" + name
+	}
+	return name
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+)
+
+// resolveSecret returns the value of a setting that may reference a secret
+// instead of holding it: "vault:<path>#<key>" reads the key of a Vault secret
+// and "aws-sm:<id>[#<key>]" an AWS Secrets Manager secret, or the key of its
+// JSON value. Any other value is returned as is.
+func resolveSecret(ctx context.Context, name, value string) (string, error) {
+	var secret string
+	var err error
+	switch {
+	case strings.HasPrefix(value, "vault:"):
+		secret, err = vaultSecret(ctx, strings.TrimPrefix(value, "vault:"))
+	case strings.HasPrefix(value, "aws-sm:"):
+		secret, err = awsSecret(ctx, strings.TrimPrefix(value, "aws-sm:"))
+	default:
+		return value, nil
+	}
+	if err != nil {
+		return "", classify(exitConnection, fmt.Errorf("Can't resolve %s %s: %v", name, value, err))
+	}
+	return secret, nil
+}
+
+// vaultSecret reads the key of the secret at path from the Vault server at
+// VAULT_ADDR with VAULT_TOKEN, e.g. "secret/data/db#password", unwrapping
+// the data of KV version 2 secrets.
+func vaultSecret(ctx context.Context, ref string) (string, error) {
+	path, key, ok := strings.Cut(ref, "#")
+	if !ok || key == "" {
+		return "", fmt.Errorf("expected vault:<path>#<key>")
+	}
+	addr := os.Getenv("VAULT_ADDR")
+	if addr == "" {
+		return "", fmt.Errorf("VAULT_ADDR is not set")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", strings.TrimRight(addr, "/")+"/v1/"+strings.TrimLeft(path, "/"), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", os.Getenv("VAULT_TOKEN"))
+	if namespace := os.Getenv("VAULT_NAMESPACE"); namespace != "" {
+		req.Header.Set("X-Vault-Namespace", namespace)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("vault answered %s", resp.Status)
+	}
+
+	var body struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", err
+	}
+	data := body.Data
+	if inner, ok := data["data"].(map[string]interface{}); ok {
+		data = inner
+	}
+	value, ok := data[key].(string)
+	if !ok {
+		return "", fmt.Errorf("no string %s in the secret", key)
+	}
+	return value, nil
+}
+
+// awsSecret reads an AWS Secrets Manager secret, e.g. "my-secret", or the
+// key of its JSON value, e.g. "my-secret#password".
+func awsSecret(ctx context.Context, ref string) (string, error) {
+	id, key, _ := strings.Cut(ref, "#")
+	awsConfig, err := loadAWSConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+	out, err := secretsmanager.NewFromConfig(awsConfig).GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(id),
+	})
+	if err != nil {
+		return "", err
+	}
+	if out.SecretString == nil {
+		return "", fmt.Errorf("the secret has no string value")
+	}
+	if key == "" {
+		return *out.SecretString, nil
+	}
+
+	var values map[string]interface{}
+	if err := json.Unmarshal([]byte(*out.SecretString), &values); err != nil {
+		return "", fmt.Errorf("the secret is not a JSON object: %v", err)
+	}
+	value, ok := values[key].(string)
+	if !ok {
+		return "", fmt.Errorf("no string %s in the secret", key)
+	}
+	return value, nil
+}
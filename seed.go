@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// dependencyOrder sorts tables so every table comes after the tables its
+// foreign keys reference, keeping the original order otherwise. Self
+// references are ignored; other cycles are an error.
+func dependencyOrder(tables []Table, foreignKeys []ForeignKey) ([]Table, error) {
+	parents := map[string]map[string]bool{}
+	for _, fk := range foreignKeys {
+		if fk.TableName == fk.ReferencedTableName {
+			continue
+		}
+		if parents[fk.TableName] == nil {
+			parents[fk.TableName] = map[string]bool{}
+		}
+		parents[fk.TableName][fk.ReferencedTableName] = true
+	}
+	known := map[string]bool{}
+	for _, table := range tables {
+		known[table.Name] = true
+	}
+
+	sorted := []Table{}
+	done := map[string]bool{}
+	for len(sorted) < len(tables) {
+		progress := false
+		for _, table := range tables {
+			if done[table.Name] {
+				continue
+			}
+			ready := true
+			for parent := range parents[table.Name] {
+				if known[parent] && !done[parent] {
+					ready = false
+				}
+			}
+			if ready {
+				sorted = append(sorted, table)
+				done[table.Name] = true
+				progress = true
+			}
+		}
+		if !progress {
+			cycle := []string{}
+			for _, table := range tables {
+				if !done[table.Name] {
+					cycle = append(cycle, table.Name)
+				}
+			}
+			return nil, errors.New("Foreign key cycle between " + strings.Join(cycle, ", "))
+		}
+	}
+	return sorted, nil
+}
+
+// seedValue returns a Go expression producing a value for the column, filling
+// nullable columns too, and the import it requires.
+func seedValue(cs ColumnSchema) (string, string, bool) {
+	if _, overridden := config.TypeOverrides[cs.TableName+"."+cs.ColumnName]; overridden {
+		return "", "", false
+	}
+	cs.IsNullable = "NO"
+	gt, _, err := goType(&cs)
+	if err != nil {
+		return "", "", false
+	}
+	return factoryValue(cs, gt)
+}
+
+// writeSeed writes a seed package to dir whose Seed function inserts n rows
+// into every table, parents first. Foreign key columns reference the rows
+// already in the parent table; auto increment and generated columns, the
+// soft_delete_column, so that the rows aren't deleted, and columns without a
+// generated value are left to the database.
+func writeSeed(dir string, schema Schema) error {
+	tables, err := dependencyOrder(groupTables(schema.Columns), schema.ForeignKeys)
+	if err != nil {
+		return err
+	}
+
+	body := &bytes.Buffer{}
+	seeders := []string{}
+	for _, table := range tables {
+		seeder := "seed" + tableGoName(table.Name)
+		seeders = append(seeders, seeder)
+
+		// group the foreign key columns by constraint
+		constraints := []string{}
+		references := map[string][]ForeignKey{}
+		for _, fk := range schema.ForeignKeys {
+			if fk.TableName != table.Name {
+				continue
+			}
+			if references[fk.ConstraintName] == nil {
+				constraints = append(constraints, fk.ConstraintName)
+			}
+			references[fk.ConstraintName] = append(references[fk.ConstraintName], fk)
+		}
+
+		body.WriteString("\nfunc " + seeder + "(ctx context.Context, db *sql.DB, n int) error {\n")
+		args := map[string]string{}
+		for i, constraint := range constraints {
+			parentKeys := "parents" + strconv.Itoa(i)
+			columns := []string{}
+			for j, fk := range references[constraint] {
+				columns = append(columns, quoteIdent(fk.ReferencedColumnName))
+				args[fk.ColumnName] = "pick(" + parentKeys + ", i, " + strconv.Itoa(j) + ")"
+			}
+			query := "SELECT " + strings.Join(columns, ", ") + " FROM " + referencedIdent(references[constraint][0])
+			body.WriteString("\t" + parentKeys + ", err := keys(ctx, db, " + strconv.Quote(query) + ")\n")
+			body.WriteString("\tif err != nil {\n\t\treturn err\n\t}\n")
+		}
+
+		deleted, softDelete := softDeleteColumn(table)
+		columns := []ColumnSchema{}
+		values := []string{}
+		for _, cs := range table.Columns {
+			if hasExtra(cs, "auto_increment") || isReadOnly(cs) || softDelete && cs.ColumnName == deleted.ColumnName {
+				continue
+			}
+			value, ok := args[cs.ColumnName]
+			if !ok {
+				value, _, ok = seedValue(cs)
+			}
+			if ok {
+				columns = append(columns, cs)
+				values = append(values, value)
+			}
+		}
+		if len(columns) == 0 {
+			body.WriteString("\treturn nil\n}\n")
+			continue
+		}
+
+		insert := "INSERT INTO " + tableIdent(table.Name) + " (" + columnList(columns) + ") VALUES (" + valueList(columns) + ")"
+		body.WriteString("\tfor i := 0; i < n; i++ {\n")
+		body.WriteString("\t\t_, err := db.ExecContext(ctx, " + strconv.Quote(insert) + ",\n")
+		body.WriteString("\t\t\t" + strings.Join(values, ", ") + ")\n")
+		body.WriteString("\t\tif err != nil {\n\t\t\treturn err\n\t\t}\n")
+		body.WriteString("\t}\n")
+		body.WriteString("\treturn nil\n}\n")
+	}
+
+	buffer := bytes.NewBufferString(buildConstraint())
+	buffer.WriteString("// Package seed fills the " + config.DbName + " database with generated rows.\n")
+	buffer.WriteString("package seed\n\n")
+	buffer.WriteString("import (\n\t\"context\"\n\t\"database/sql\"\n\t\"strconv\"\n\t\"sync/atomic\"\n\t\"time\"\n)\n\n")
+
+	buffer.WriteString("// start the sequence from the clock so the unique values of separate runs\n")
+	buffer.WriteString("// don't collide\n")
+	buffer.WriteString("func init() {\n\tsequence = time.Now().UnixNano()\n}\n\n")
+
+	buffer.WriteString("// Seed inserts n rows into every table, parents before children.\n")
+	buffer.WriteString("func Seed(ctx context.Context, db *sql.DB, n int) error {\n")
+	buffer.WriteString("\tfor _, seed := range []func(context.Context, *sql.DB, int) error{\n")
+	for _, seeder := range seeders {
+		buffer.WriteString("\t\t" + seeder + ",\n")
+	}
+	buffer.WriteString("\t} {\n")
+	buffer.WriteString("\t\tif err := seed(ctx, db, n); err != nil {\n\t\t\treturn err\n\t\t}\n")
+	buffer.WriteString("\t}\n")
+	buffer.WriteString("\treturn nil\n}\n\n")
+
+	buffer.WriteString("// keys returns the rows selected by query.\n")
+	buffer.WriteString("func keys(ctx context.Context, db *sql.DB, query string) ([][]interface{}, error) {\n")
+	buffer.WriteString("\trows, err := db.QueryContext(ctx, query)\n")
+	buffer.WriteString("\tif err != nil {\n\t\treturn nil, err\n\t}\n")
+	buffer.WriteString("\tdefer rows.Close()\n\n")
+	buffer.WriteString("\tcolumns, err := rows.Columns()\n")
+	buffer.WriteString("\tif err != nil {\n\t\treturn nil, err\n\t}\n")
+	buffer.WriteString("\tresult := [][]interface{}{}\n")
+	buffer.WriteString("\tfor rows.Next() {\n")
+	buffer.WriteString("\t\trow := make([]interface{}, len(columns))\n")
+	buffer.WriteString("\t\tdest := make([]interface{}, len(columns))\n")
+	buffer.WriteString("\t\tfor i := range row {\n\t\t\tdest[i] = &row[i]\n\t\t}\n")
+	buffer.WriteString("\t\tif err := rows.Scan(dest...); err != nil {\n\t\t\treturn nil, err\n\t\t}\n")
+	buffer.WriteString("\t\tresult = append(result, row)\n")
+	buffer.WriteString("\t}\n")
+	buffer.WriteString("\treturn result, rows.Err()\n}\n\n")
+
+	buffer.WriteString("// pick returns the column of the i-th row, cycling through rows, or NULL\n")
+	buffer.WriteString("// when there are none.\n")
+	buffer.WriteString("func pick(rows [][]interface{}, i, column int) interface{} {\n")
+	buffer.WriteString("\tif len(rows) == 0 {\n\t\treturn nil\n\t}\n")
+	buffer.WriteString("\treturn rows[i%len(rows)][column]\n}\n\n")
+
+	writeValueHelpers(buffer)
+	buffer.Write(body.Bytes())
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	return writeOutput(filepath.Join(dir, "seed.go"), buffer.Bytes())
+}
@@ -0,0 +1,410 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/format"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"golang.org/x/term"
+	"gopkg.in/yaml.v3"
+)
+
+// selectFlags returns the flags of the select command.
+func selectFlags() *flag.FlagSet {
+	flags := flag.NewFlagSet("select", flag.ExitOnError)
+	addSettingsFlags(flags)
+	addLogFlags(flags)
+	return flags
+}
+
+// selectCommand implements the select command, listing the tables of the
+// schema with checkboxes on the terminal to pick the tables and columns to
+// generate, previewing the struct of a table, and saving the selection into
+// the config file as its tables and exclude_columns settings.
+func selectCommand(ctx context.Context, args []string) error {
+	flags := selectFlags()
+	flags.Parse(args)
+	commandFlags = flags
+	if err := setupLogging(); err != nil {
+		return err
+	}
+	path := *configFile
+	if path == "" {
+		path = *configAny
+	}
+	if path == "" {
+		return configError("select saves the selection into the config file and needs -json or -config")
+	}
+	if !isTerminal(os.Stdin) || !isTerminal(os.Stdout) {
+		return configError("select is interactive and needs a terminal")
+	}
+
+	if err := readConfig(); err != nil {
+		return err
+	}
+	if err := applySettings(); err != nil {
+		return err
+	}
+	if err := checkPatterns(); err != nil {
+		return err
+	}
+	if err := compileRenameRules(); err != nil {
+		return err
+	}
+	conn, err := connect(ctx)
+	if err != nil {
+		return err
+	}
+	columns, err := getColumns(ctx, conn, config.DbName)
+	conn.Close()
+	if err != nil {
+		return classify(exitConnection, err)
+	}
+	tables := groupTables(columns)
+	if len(tables) == 0 {
+		return configError("No tables in " + config.DbName)
+	}
+
+	s := newSelection(tables)
+	saved, err := s.run()
+	if err != nil || !saved {
+		return err
+	}
+	if err := saveSelection(path, s); err != nil {
+		return classify(exitWrite, err)
+	}
+	slog.Info("Saved the selection", "file", path, "tables", len(s.selectedTables()))
+	return nil
+}
+
+// selection is the state of the select command: the tables and columns
+// picked, starting from the ones the config file generates, and what the
+// terminal shows.
+type selection struct {
+	tables []Table
+	// picked holds the picked tables, and dropped the columns left out of
+	// them as "table.column".
+	picked  map[string]bool
+	dropped map[string]bool
+
+	// table is the table whose columns are listed, -1 for the list of
+	// tables, and cursor the highlighted row.
+	table   int
+	cursor  int
+	preview bool
+}
+
+// newSelection starts a selection from the tables and exclude_columns
+// settings.
+func newSelection(tables []Table) *selection {
+	s := &selection{tables: tables, picked: map[string]bool{}, dropped: map[string]bool{}, table: -1}
+	for _, t := range tables {
+		s.picked[t.Name] = includeTable(t.Name)
+		for _, cs := range t.Columns {
+			if excludeColumn(t.Name, cs.ColumnName) {
+				s.dropped[t.Name+"."+cs.ColumnName] = true
+			}
+		}
+	}
+	return s
+}
+
+// run lets the selection be edited on the terminal until it is saved or
+// given up on, reporting whether it was saved. Ctrl-C gives up on it as an
+// interruption.
+func (s *selection) run() (bool, error) {
+	fd := int(os.Stdin.Fd())
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		term.Restore(fd, state)
+		fmt.Print("\x1b[2J\x1b[H")
+	}()
+
+	key := make([]byte, 8)
+	for {
+		s.draw()
+		n, err := os.Stdin.Read(key)
+		if err != nil {
+			return false, err
+		}
+		switch string(key[:n]) {
+		case "\x1b[A", "k":
+			s.cursor = max(s.cursor-1, 0)
+		case "\x1b[B", "j":
+			s.cursor = min(s.cursor+1, s.rows()-1)
+		case " ":
+			s.toggle(s.cursor)
+		case "a":
+			for row := 0; row < s.rows(); row++ {
+				s.toggle(row)
+			}
+		case "\r", "\x1b[C", "l":
+			if s.table < 0 {
+				s.table, s.cursor = s.cursor, 0
+			}
+		case "\x1b", "\x1b[D", "h":
+			if s.table >= 0 {
+				s.table, s.cursor = -1, s.table
+			}
+		case "p":
+			s.preview = !s.preview
+		case "s":
+			return true, nil
+		case "q":
+			return false, nil
+		case "\x03":
+			return false, context.Canceled
+		}
+	}
+}
+
+// rows returns the number of rows listed: tables, or columns of a table.
+func (s *selection) rows() int {
+	if s.table < 0 {
+		return len(s.tables)
+	}
+	return len(s.tables[s.table].Columns)
+}
+
+// toggle picks or leaves out the table or column of a row.
+func (s *selection) toggle(row int) {
+	if s.table < 0 {
+		name := s.tables[row].Name
+		s.picked[name] = !s.picked[name]
+		return
+	}
+	key := s.tables[s.table].Name + "." + s.tables[s.table].Columns[row].ColumnName
+	s.dropped[key] = !s.dropped[key]
+}
+
+// draw redraws the screen: the tables, or the columns of a table, around the
+// cursor and the preview of the struct of the highlighted table when turned
+// on.
+func (s *selection) draw() {
+	_, height, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil || height == 0 {
+		height = 24
+	}
+	lines := []string{}
+	if s.table < 0 {
+		lines = append(lines, "Tables of "+config.DbName+"  space pick  a all  enter columns  p preview  s save  q quit", "")
+		for i, t := range s.tables {
+			picked := 0
+			for _, cs := range t.Columns {
+				if !s.dropped[t.Name+"."+cs.ColumnName] {
+					picked++
+				}
+			}
+			lines = append(lines, fmt.Sprintf("%s %s (%d/%d columns)", s.checkbox(i, s.picked[t.Name]), t.Name, picked, len(t.Columns)))
+		}
+	} else {
+		t := s.tables[s.table]
+		lines = append(lines, "Columns of "+t.Name+"  space pick  a all  esc tables  p preview  s save  q quit", "")
+		for i, cs := range t.Columns {
+			lines = append(lines, fmt.Sprintf("%s %s %s", s.checkbox(i, !s.dropped[t.Name+"."+cs.ColumnName]), cs.ColumnName, cs.ColumnType))
+		}
+	}
+
+	var preview []string
+	if s.preview {
+		table := s.table
+		if table < 0 {
+			table = s.cursor
+		}
+		preview = append([]string{""}, splitLines(s.previewStruct(s.tables[table]))...)
+	}
+
+	// Scroll the rows so that the cursor stays on screen above the preview.
+	visible := max(height-2-len(preview), 3)
+	rows := lines[2:]
+	first := 0
+	if s.cursor >= visible {
+		first = s.cursor - visible + 1
+	}
+	rows = rows[first:min(first+visible, len(rows))]
+
+	var out strings.Builder
+	out.WriteString("\x1b[2J\x1b[H")
+	for _, line := range append(append(lines[:2:2], rows...), preview...) {
+		out.WriteString(line + "\r\n")
+	}
+	fmt.Print(out.String())
+}
+
+// checkbox returns the checkbox of a row, marked with the cursor.
+func (s *selection) checkbox(row int, checked bool) string {
+	box := "[ ]"
+	if checked {
+		box = "[x]"
+	}
+	if row == s.cursor {
+		return "> " + box
+	}
+	return "  " + box
+}
+
+// previewStruct returns the struct generated for the picked columns of a
+// table, or why it can't be generated.
+func (s *selection) previewStruct(t Table) string {
+	if !s.picked[t.Name] {
+		return t.Name + " is left out"
+	}
+	columns := []ColumnSchema{}
+	for _, cs := range t.Columns {
+		if !s.dropped[t.Name+"."+cs.ColumnName] {
+			columns = append(columns, cs)
+		}
+	}
+	if err := resolveCollisions(columns); err != nil {
+		return err.Error()
+	}
+	var buffer bytes.Buffer
+	buffer.WriteString("type " + typeName(t.Name) + " struct{\n")
+	if err := writeFields(&buffer, orderColumns(columns), map[string]bool{}); err != nil {
+		return err.Error()
+	}
+	buffer.WriteString("}\n")
+	if formatted, err := format.Source(buffer.Bytes()); err == nil {
+		return string(formatted)
+	}
+	return buffer.String()
+}
+
+// selectedTables returns the picked tables, in schema order.
+func (s *selection) selectedTables() []string {
+	tables := []string{}
+	for _, t := range s.tables {
+		if s.picked[t.Name] {
+			tables = append(tables, t.Name)
+		}
+	}
+	return tables
+}
+
+// saveSelection writes the selection into the config file: tables lists the
+// picked tables, exclude_tables keeps the entries matching none of them, and
+// exclude_columns keeps the entries matching no picked column of a picked
+// table, adding the columns left out as "table.column".
+func saveSelection(path string, s *selection) error {
+	tables := s.selectedTables()
+	excludeTables := []string{}
+	for _, pattern := range config.ExcludeTables {
+		if !matchesAnyName(pattern, tables) {
+			excludeTables = append(excludeTables, pattern)
+		}
+	}
+
+	excludeColumns := []string{}
+	for _, pattern := range config.ExcludeColumns {
+		kept := true
+		for _, t := range s.tables {
+			for _, cs := range t.Columns {
+				if s.picked[t.Name] && !s.dropped[t.Name+"."+cs.ColumnName] && (matchName(pattern, cs.ColumnName) || matchName(pattern, t.Name+"."+cs.ColumnName)) {
+					kept = false
+				}
+			}
+		}
+		if kept {
+			excludeColumns = append(excludeColumns, pattern)
+		}
+	}
+	for _, t := range s.tables {
+		for _, cs := range t.Columns {
+			if s.picked[t.Name] && s.dropped[t.Name+"."+cs.ColumnName] && !matchAny(excludeColumns, cs.ColumnName) && !matchAny(excludeColumns, t.Name+"."+cs.ColumnName) {
+				excludeColumns = append(excludeColumns, t.Name+"."+cs.ColumnName)
+			}
+		}
+	}
+
+	return updateSettings(path, []string{"tables", "exclude_tables", "exclude_columns"}, []interface{}{tables, excludeTables, excludeColumns})
+}
+
+// matchesAnyName reports whether pattern matches one of names.
+func matchesAnyName(pattern string, names []string) bool {
+	for _, name := range names {
+		if matchName(pattern, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// updateSettings sets settings of a config file, keeping the others. YAML
+// files are edited in place, keeping their comments, while JSON and TOML ones
+// are rewritten with their keys in order.
+func updateSettings(path string, keys []string, values []interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var out []byte
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		var doc yaml.Node
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return err
+		}
+		if doc.Kind == 0 {
+			doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+		}
+		settings := doc.Content[0]
+		for i, key := range keys {
+			value := &yaml.Node{}
+			if err := value.Encode(values[i]); err != nil {
+				return err
+			}
+			found := false
+			for j := 0; j+1 < len(settings.Content); j += 2 {
+				if settings.Content[j].Value == key {
+					settings.Content[j+1], found = value, true
+				}
+			}
+			if !found {
+				settings.Content = append(settings.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, value)
+			}
+		}
+		if out, err = yaml.Marshal(&doc); err != nil {
+			return err
+		}
+	case ".toml":
+		settings := map[string]interface{}{}
+		if err := toml.Unmarshal(data, &settings); err != nil {
+			return err
+		}
+		for i, key := range keys {
+			settings[key] = values[i]
+		}
+		var buffer bytes.Buffer
+		if err := toml.NewEncoder(&buffer).Encode(settings); err != nil {
+			return err
+		}
+		out = buffer.Bytes()
+	default:
+		settings := map[string]json.RawMessage{}
+		if err := json.Unmarshal(data, &settings); err != nil {
+			return err
+		}
+		for i, key := range keys {
+			if settings[key], err = json.Marshal(values[i]); err != nil {
+				return err
+			}
+		}
+		if out, err = json.MarshalIndent(settings, "", "  "); err != nil {
+			return err
+		}
+		out = append(out, '\n')
+	}
+	return os.WriteFile(path, out, 0644)
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"html/template"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+)
+
+// structsOut is where the structs go with -out "-": stdout, or the preview
+// of serve.
+var structsOut io.Writer = os.Stdout
+
+// serveAddr is the -addr flag of serve.
+var serveAddr = new(string)
+
+// serveFlags returns the flags of the serve command.
+func serveFlags() *flag.FlagSet {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	addSettingsFlags(flags)
+	addLogFlags(flags)
+	flags.StringVar(serveAddr, "addr", "localhost:8080", "Address to serve the preview page on")
+	return flags
+}
+
+// unpreviewedFlags are the boolean flags of generate not offered by the
+// preview page, as they don't change the structs.
+var unpreviewedFlags = map[string]bool{"all": true, "cache": true, "dry-run": true, "go-generate": true, "managed": true, "merge": true, "progress": true, "q": true, "v": true}
+
+// previewOptions returns the boolean flags of generate the preview page
+// offers, in name order.
+func previewOptions() []*flag.Flag {
+	options := []*flag.Flag{}
+	flag.CommandLine.VisitAll(func(f *flag.Flag) {
+		if !unpreviewedFlags[f.Name] && isBoolFlag(f) {
+			options = append(options, f)
+		}
+	})
+	return options
+}
+
+// serveCommand implements the serve command, serving a local page to pick
+// tables and options of generate and preview the structs they give, read
+// live from the database.
+func serveCommand(ctx context.Context, args []string) error {
+	flags := serveFlags()
+	flags.Parse(args)
+	commandFlags = flags
+	if err := setupLogging(); err != nil {
+		return err
+	}
+	if err := readConfig(); err != nil {
+		return err
+	}
+	if err := applySettings(); err != nil {
+		return err
+	}
+	if err := checkPatterns(); err != nil {
+		return err
+	}
+	if err := compileRenameRules(); err != nil {
+		return err
+	}
+	conn, err := connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	columns, err := getColumns(ctx, conn, config.DbName)
+	if err != nil {
+		return classify(exitConnection, err)
+	}
+
+	tables := []string{}
+	for _, table := range groupTables(columns) {
+		tables = append(tables, table.Name)
+	}
+	var page bytes.Buffer
+	err = servePage.Execute(&page, map[string]interface{}{
+		"DbName":     config.DbName,
+		"Tables":     tables,
+		"Options":    previewOptions(),
+		"TagLabel":   config.TagLabel,
+		"Naming":     config.Naming,
+		"FieldOrder": config.FieldOrder,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Previews change the global settings, so they run one at a time and
+	// restore them.
+	var mu sync.Mutex
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(page.Bytes())
+	})
+	mux.HandleFunc("/preview", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		code, err := preview(r.Context(), conn, r.Form)
+		mu.Unlock()
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		if err != nil {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			io.WriteString(w, err.Error())
+			return
+		}
+		w.Write(code)
+	})
+
+	server := &http.Server{Addr: *serveAddr, Handler: mux}
+	go func() {
+		<-ctx.Done()
+		server.Shutdown(context.Background())
+	}()
+	slog.Info("Serving the preview", "url", "http://"+*serveAddr)
+	if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return classify(exitConfig, err)
+	}
+	return nil
+}
+
+// preview returns the structs generated for the tables and options of a
+// form of the page, restoring the settings afterwards.
+func preview(ctx context.Context, conn *sql.DB, form url.Values) ([]byte, error) {
+	savedConfig, savedOutput, savedOut := config, *output, structsOut
+	savedFlags := map[string]string{}
+	for _, f := range previewOptions() {
+		savedFlags[f.Name] = f.Value.String()
+	}
+	defer func() {
+		config, *output, structsOut = savedConfig, savedOutput, savedOut
+		for name, value := range savedFlags {
+			flag.CommandLine.Set(name, value)
+		}
+	}()
+
+	options := map[string]bool{}
+	for _, name := range form["option"] {
+		options[name] = true
+	}
+	for name := range savedFlags {
+		flag.CommandLine.Set(name, strconv.FormatBool(options[name]))
+	}
+	config.TagLabel = form.Get("tag")
+	config.Naming = form.Get("naming")
+	config.FieldOrder = form.Get("field_order")
+	if config.BaseStruct == "" {
+		config.BaseStruct = defaults.BaseStruct
+	}
+	if config.DeprecationMarker == "" {
+		config.DeprecationMarker = defaults.DeprecationMarker
+	}
+	if config.ClassificationMarker == "" {
+		config.ClassificationMarker = defaults.ClassificationMarker
+	}
+
+	schema, err := getSchema(ctx, conn, config.DbName)
+	if err != nil {
+		return nil, err
+	}
+	tables := map[string]bool{}
+	for _, name := range form["table"] {
+		tables[name] = true
+	}
+	schema = selectTables(schema, func(name string) bool { return tables[name] })
+	if err := resolveCollisions(schema.Columns); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	*output, structsOut = "-", &buf
+	if _, err := writeStructs(schema); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// servePage is the preview page: the tables and options on the left, the
+// structs on the right, refreshed on every change along with the command
+// line generating them.
+var servePage = template.Must(template.New("serve").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>struct-create: {{.DbName}}</title>
+<style>
+body { display: flex; margin: 0; font-family: sans-serif; font-size: 14px; }
+form { width: 22em; padding: 1em; overflow-y: auto; height: 100vh; box-sizing: border-box; border-right: 1px solid #ccc; }
+fieldset { margin-bottom: 1em; }
+label { display: block; }
+main { flex: 1; padding: 1em; overflow: auto; height: 100vh; box-sizing: border-box; }
+pre { margin: 0; }
+#command { background: #f4f4f4; padding: .5em; white-space: pre-wrap; }
+.error { color: #b00; }
+</style>
+</head>
+<body>
+<form id="options">
+<fieldset><legend>Tables of {{.DbName}}</legend>
+{{range .Tables}}<label><input type="checkbox" name="table" value="{{.}}" checked> {{.}}</label>
+{{end}}</fieldset>
+<fieldset><legend>Naming</legend>
+<label>Tag <input name="tag" value="{{.TagLabel}}" size="8"></label>
+<label>Fields <select name="naming">
+<option value="go"{{if ne .Naming "verbatim"}} selected{{end}}>go</option>
+<option value="verbatim"{{if eq .Naming "verbatim"}} selected{{end}}>verbatim</option>
+</select></label>
+<label>Order <select name="field_order">
+<option value="ordinal"{{if or (eq .FieldOrder "") (eq .FieldOrder "ordinal")}} selected{{end}}>ordinal</option>
+<option value="alphabetical"{{if eq .FieldOrder "alphabetical"}} selected{{end}}>alphabetical</option>
+<option value="primary_key_first"{{if eq .FieldOrder "primary_key_first"}} selected{{end}}>primary_key_first</option>
+</select></label>
+</fieldset>
+<fieldset><legend>Options</legend>
+{{range .Options}}<label title="{{.Usage}}"><input type="checkbox" name="option" value="{{.Name}}"{{if eq .Value.String "true"}} checked{{end}}> -{{.Name}}</label>
+{{end}}</fieldset>
+</form>
+<main>
+<pre id="command"></pre>
+<pre id="code"></pre>
+</main>
+<script>
+const form = document.getElementById("options");
+async function refresh() {
+  const data = new FormData(form);
+  const tables = data.getAll("table");
+  const command = ["struct-create", "-tables", tables.join(","), "-tag", data.get("tag")];
+  for (const option of data.getAll("option")) command.push("-" + option);
+  document.getElementById("command").textContent = command.join(" ") +
+    "\n(naming " + data.get("naming") + ", field_order " + data.get("field_order") + " in the config file)";
+  const response = await fetch("/preview", {method: "POST", body: new URLSearchParams(data)});
+  const code = document.getElementById("code");
+  code.className = response.ok ? "" : "error";
+  code.textContent = await response.text();
+}
+form.addEventListener("change", refresh);
+form.addEventListener("input", refresh);
+refresh();
+</script>
+</body>
+</html>
+`))
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"log/slog"
+	"strconv"
+	"strings"
+)
+
+// spatialTypes are the geometry data types, read and written as WKB.
+var spatialTypes = map[string]bool{
+	"geometry": true, "point": true, "linestring": true, "polygon": true, "multipoint": true,
+	"multilinestring": true, "multipolygon": true, "geometrycollection": true, "geomcollection": true,
+}
+
+// isSpatial reports whether the column holds geometries.
+func isSpatial(cs ColumnSchema) bool {
+	return spatialTypes[cs.DataType]
+}
+
+// spatialComment returns the field comment of a geometry column, e.g.
+// "Geometry as WKB, SRID 4326.".
+func spatialComment(cs ColumnSchema) string {
+	if cs.SrsID.Valid {
+		return "Geometry as WKB, SRID " + strconv.FormatInt(cs.SrsID.Int64, 10) + "."
+	}
+	return "Geometry as WKB."
+}
+
+// getSRIDs reads the spatial reference system of the geometry columns from
+// COLUMNS.SRS_ID, which only MySQL 8 has, into the columns.
+func getSRIDs(ctx context.Context, conn *sql.DB, dbName string, columns []ColumnSchema) error {
+	spatial := false
+	for _, cs := range columns {
+		spatial = spatial || isSpatial(cs)
+	}
+	if !spatial {
+		return nil
+	}
+
+	q := "SELECT TABLE_NAME, COLUMN_NAME, SRS_ID FROM COLUMNS WHERE TABLE_SCHEMA = ? AND SRS_ID IS NOT NULL"
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+	rows, err := conn.QueryContext(ctx, q, dbName)
+	if err != nil {
+		slog.Warn("No SRS_ID in information_schema.COLUMNS, geometry columns get no SRID", "error", err)
+		return nil
+	}
+	defer rows.Close()
+	srids := map[string]int64{}
+	for rows.Next() {
+		var tableName, columnName string
+		var srid int64
+		if err := rows.Scan(&tableName, &columnName, &srid); err != nil {
+			return err
+		}
+		srids[tableName+"."+columnName] = srid
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for i, cs := range columns {
+		if srid, ok := srids[cs.TableName+"."+cs.ColumnName]; ok {
+			columns[i].SrsID = sql.NullInt64{Int64: srid, Valid: true}
+		}
+	}
+	return nil
+}
+
+// spatialValue returns a Go expression producing the WKB of POINT(0 0), valid
+// in every spatial reference system, for POINT and GEOMETRY columns; other
+// geometry types get no value.
+func spatialValue(cs ColumnSchema) (string, string, bool) {
+	if cs.DataType != "point" && cs.DataType != "geometry" {
+		return "", "", false
+	}
+	// little endian, type 1 (point), x and y float64 zeros
+	wkb := "\x01\x01\x00\x00\x00" + strings.Repeat("\x00", 16)
+	return "[]byte(" + strconv.Quote(wkb) + ")", "", true
+}
+
+// writeSRIDs emits an XYSRID constant per geometry column of the table
+// restricted to a spatial reference system.
+func writeSRIDs(buffer *bytes.Buffer, structName string, table Table) {
+	for _, cs := range table.Columns {
+		if isSpatial(cs) && cs.SrsID.Valid {
+			name := structName + columnGoName(cs.TableName, cs.ColumnName) + "SRID"
+			buffer.WriteString("\n\n// " + name + " is the spatial reference system of " + table.Name + "." + cs.ColumnName + ".\n")
+			buffer.WriteString("const " + name + " = " + strconv.FormatInt(cs.SrsID.Int64, 10))
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// writeSqlc writes a starter sqlc project to dir: queries.sql with Get, List
+// and Create queries per table, schema.sql holding the CREATE TABLE
+// statements and a sqlc.yaml tying them together. Without a connection, with
+// -from-schema, the statements only hold the columns and primary key.
+func writeSqlc(ctx context.Context, dir string, conn *sql.DB, schemas []ColumnSchema) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	var queries, schema bytes.Buffer
+	for i, table := range groupTables(schemas) {
+		if i > 0 {
+			queries.WriteString("\n")
+			schema.WriteString("\n")
+		}
+		structName := tableGoName(table.Name)
+		tableName := tableIdent(table.Name)
+		columns := selectList(table.Columns)
+
+		pk := primaryKey(table)
+		if len(pk) > 0 {
+			wheres := []string{}
+			for _, cs := range pk {
+				wheres = append(wheres, quoteIdent(cs.ColumnName)+" = ?")
+			}
+			queries.WriteString("-- name: Get" + structName + " :one\n")
+			queries.WriteString("SELECT " + columns + " FROM " + tableName + "\nWHERE " + strings.Join(wheres, " AND ") + " LIMIT 1;\n\n")
+		}
+
+		queries.WriteString("-- name: List" + pluralName(structName) + " :many\n")
+		queries.WriteString("SELECT " + columns + " FROM " + tableName)
+		if len(pk) > 0 {
+			queries.WriteString("\nORDER BY " + columnList(pk))
+		}
+		queries.WriteString(";\n\n")
+
+		queries.WriteString("-- name: Create" + structName + " :exec\n")
+		inserted := insertColumns(table)
+		queries.WriteString("INSERT INTO " + tableName + " (" + columnList(inserted) + ")\nVALUES (" + valueList(inserted) + ");\n")
+
+		if conn == nil {
+			// Generating from a snapshot, which has no CREATE TABLE.
+			schema.WriteString(createTableFromColumns(table) + ";\n")
+			continue
+		}
+		qualified := tableName
+		if !strings.Contains(table.Name, ".") {
+			qualified = quoteIdent(config.DbName) + "." + tableName
+		}
+		var name, ddl string
+		ctx, cancel := queryContext(ctx)
+		err := conn.QueryRowContext(ctx, "SHOW CREATE TABLE "+qualified).Scan(&name, &ddl)
+		cancel()
+		if err != nil {
+			return err
+		}
+		schema.WriteString(ddl + ";\n")
+	}
+
+	yaml := "version: \"2\"\n" +
+		"sql:\n" +
+		"  - engine: \"mysql\"\n" +
+		"    queries: \"queries.sql\"\n" +
+		"    schema: \"schema.sql\"\n" +
+		"    gen:\n" +
+		"      go:\n" +
+		"        package: \"" + config.PkgName + "\"\n" +
+		"        out: \"" + config.PkgName + "\"\n"
+
+	files := map[string][]byte{
+		"queries.sql": queries.Bytes(),
+		"schema.sql":  schema.Bytes(),
+		"sqlc.yaml":   []byte(yaml),
+	}
+	for file, content := range files {
+		if err := writeOutput(filepath.Join(dir, file), content); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+)
+
+// writeSqlx emits named INSERT and UPDATE statements for the table plus
+// jmoiron/sqlx based helpers; sqlx maps the columns through the db tags.
+func writeSqlx(buffer *bytes.Buffer, structName string, table Table) {
+	r := receiverName(structName)
+	tableName := tableIdent(table.Name)
+
+	names := []string{}
+	sets := []string{}
+	wheres := []string{}
+	for _, cs := range table.Columns {
+		if cs.ColumnKey == "PRI" {
+			wheres = append(wheres, quoteIdent(cs.ColumnName)+" = :"+cs.ColumnName)
+		} else if !isReadOnly(cs) {
+			sets = append(sets, quoteIdent(cs.ColumnName)+" = "+valueExpr(cs, ":"+cs.ColumnName))
+		}
+	}
+	columns := insertColumns(table)
+	for _, cs := range columns {
+		names = append(names, valueExpr(cs, ":"+cs.ColumnName))
+	}
+
+	insertConst := funcName("Insert", structName) + "Query"
+	insert := "INSERT INTO " + tableName + " (" + columnList(columns) + ") VALUES (" + strings.Join(names, ", ") + ")"
+	buffer.WriteString("const " + insertConst + " = " + strconv.Quote(insert) + "\n\n")
+
+	updateConst := ""
+	if len(wheres) > 0 && len(sets) > 0 {
+		updateConst = funcName("Update", structName) + "Query"
+		update := "UPDATE " + tableName + " SET " + strings.Join(sets, ", ") + " WHERE " + strings.Join(wheres, " AND ")
+		buffer.WriteString("const " + updateConst + " = " + strconv.Quote(update) + "\n\n")
+	}
+
+	// SELECT * leaves invisible columns out, so give those tables a select
+	// list naming every column.
+	for _, cs := range table.Columns {
+		if isInvisible(cs) {
+			selectQuery := "SELECT " + selectList(table.Columns) + " FROM " + tableName
+			buffer.WriteString("const " + funcName("Select", structName) + "Query = " + strconv.Quote(selectQuery) + "\n\n")
+			break
+		}
+	}
+
+	buffer.WriteString("func " + funcName("Get", structName) + "Context(ctx context.Context, db sqlx.QueryerContext, query string, args ...interface{}) (*" + structName + ", error) {\n")
+	buffer.WriteString("\t" + r + " := &" + structName + "{}\n")
+	buffer.WriteString("\tif err := sqlx.GetContext(ctx, db, " + r + ", query, args...); err != nil {\n")
+	buffer.WriteString("\t\treturn nil, err\n\t}\n")
+	buffer.WriteString("\treturn " + r + ", nil\n}\n\n")
+
+	buffer.WriteString("func " + funcName("Select", structName) + "Context(ctx context.Context, db sqlx.QueryerContext, query string, args ...interface{}) ([]" + structName + ", error) {\n")
+	buffer.WriteString("\trows := []" + structName + "{}\n")
+	buffer.WriteString("\tif err := sqlx.SelectContext(ctx, db, &rows, query, args...); err != nil {\n")
+	buffer.WriteString("\t\treturn nil, err\n\t}\n")
+	buffer.WriteString("\treturn rows, nil\n}\n\n")
+
+	buffer.WriteString("func " + funcName("Insert", structName) + "Named(ctx context.Context, db sqlx.ExtContext, " + r + " *" + structName + ") (sql.Result, error) {\n")
+	buffer.WriteString("\treturn sqlx.NamedExecContext(ctx, db, " + insertConst + ", " + r + ")\n}")
+
+	if updateConst != "" {
+		buffer.WriteString("\n\nfunc " + funcName("Update", structName) + "Named(ctx context.Context, db sqlx.ExtContext, " + r + " *" + structName + ") (sql.Result, error) {\n")
+		buffer.WriteString("\treturn sqlx.NamedExecContext(ctx, db, " + updateConst + ", " + r + ")\n}")
+	}
+}
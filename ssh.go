@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// homePath expands a leading "~/" to the home directory of the user.
+func homePath(path string) (string, error) {
+	if !strings.HasPrefix(path, "~/") {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, path[2:]), nil
+}
+
+// dialSSH connects to the ssh_host jump host as ssh_user, authenticating with
+// the ssh_key private key and checking the host key against ssh_known_hosts,
+// by default ~/.ssh/id_rsa and ~/.ssh/known_hosts.
+func dialSSH(ctx context.Context) (*ssh.Client, error) {
+	if config.SSHKey == "" {
+		config.SSHKey = "~/.ssh/id_rsa"
+	}
+	if config.SSHKnownHosts == "" {
+		config.SSHKnownHosts = "~/.ssh/known_hosts"
+	}
+	if config.SSHUser == "" {
+		return nil, configError("ssh_host needs ssh_user")
+	}
+
+	keyPath, err := homePath(config.SSHKey)
+	if err != nil {
+		return nil, classify(exitConfig, err)
+	}
+	key, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, classify(exitConfig, err)
+	}
+	signer, err := ssh.ParsePrivateKey(key)
+	if err != nil {
+		return nil, configError("Invalid ssh_key " + config.SSHKey + ": " + err.Error())
+	}
+	knownHostsPath, err := homePath(config.SSHKnownHosts)
+	if err != nil {
+		return nil, classify(exitConfig, err)
+	}
+	hostKeys, err := knownhosts.New(knownHostsPath)
+	if err != nil {
+		return nil, classify(exitConfig, err)
+	}
+
+	addr := config.SSHHost
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, "22")
+	}
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, classify(exitConnection, fmt.Errorf("Can't connect to ssh_host %s: %v", config.SSHHost, err))
+	}
+	sshConn, chans, reqs, err := ssh.NewClientConn(conn, addr, &ssh.ClientConfig{
+		User:            config.SSHUser,
+		Auth:            []ssh.AuthMethod{ssh.PublicKeys(signer)},
+		HostKeyCallback: hostKeys,
+	})
+	if err != nil {
+		conn.Close()
+		return nil, classify(exitConnection, fmt.Errorf("Can't connect to ssh_host %s: %v", config.SSHHost, err))
+	}
+	return ssh.NewClient(sshConn, chans, reqs), nil
+}
+
+// configureSSH routes the connection through the ssh_host jump host when
+// set, registering a dialer with the driver that opens the TCP or Unix socket
+// connection from there, so host, port and socket are as seen by the jump
+// host.
+func configureSSH(ctx context.Context, cfg *mysql.Config) error {
+	if config.SSHHost == "" {
+		return nil
+	}
+	network, addr := cfg.Net, cfg.Addr
+	if network == "" {
+		network, addr = "tcp", "127.0.0.1:3306"
+	}
+
+	client, err := dialSSH(ctx)
+	if err != nil {
+		return err
+	}
+	mysql.RegisterDialContext("ssh", func(ctx context.Context, _ string) (net.Conn, error) {
+		return client.DialContext(ctx, network, addr)
+	})
+	cfg.Net = "ssh"
+	cfg.Addr = addr
+	return nil
+}
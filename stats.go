@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+)
+
+// TableStats holds the approximate size of a table as estimated by
+// information_schema.TABLES.
+type TableStats struct {
+	TableName   string
+	Rows        int64
+	DataLength  int64
+	IndexLength int64
+}
+
+func getTableStats(ctx context.Context, conn *sql.DB, dbName string) ([]TableStats, error) {
+	q := "SELECT TABLE_NAME, TABLE_ROWS, DATA_LENGTH, INDEX_LENGTH FROM TABLES " +
+		"WHERE TABLE_SCHEMA = ? AND TABLE_TYPE = 'BASE TABLE' ORDER BY TABLE_NAME"
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+	rows, err := conn.QueryContext(ctx, q, dbName)
+	if err != nil {
+		return nil, err
+	}
+	stats := []TableStats{}
+	for rows.Next() {
+		var tableName string
+		var tableRows, dataLength, indexLength sql.NullInt64
+		if err := rows.Scan(&tableName, &tableRows, &dataLength, &indexLength); err != nil {
+			return nil, err
+		}
+		stats = append(stats, TableStats{tableName, tableRows.Int64, dataLength.Int64, indexLength.Int64})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// byteSize formats a size in bytes with a binary unit, e.g. "1.5 MiB".
+func byteSize(n int64) string {
+	units := []string{"B", "KiB", "MiB", "GiB", "TiB"}
+	size := float64(n)
+	unit := 0
+	for size >= 1024 && unit < len(units)-1 {
+		size /= 1024
+		unit++
+	}
+	if unit == 0 {
+		return strconv.FormatInt(n, 10) + " B"
+	}
+	return fmt.Sprintf("%.1f %s", size, units[unit])
+}
+
+// groupDigits formats n with thousands separators, e.g. "12,345".
+func groupDigits(n int64) string {
+	s := strconv.FormatInt(n, 10)
+	for i := len(s) - 3; i > 0 && s[i-1] != '-'; i -= 3 {
+		s = s[:i] + "," + s[i:]
+	}
+	return s
+}
+
+// writeStatsComment emits a comment with the approximate row count and sizes
+// of the table, placed above its struct.
+func writeStatsComment(buffer *bytes.Buffer, structName string, table Table, stats []TableStats) {
+	for _, s := range stats {
+		if s.TableName != table.Name {
+			continue
+		}
+		buffer.WriteString("// " + structName + " maps " + table.Name + ": about " + groupDigits(s.Rows) + " rows, " +
+			byteSize(s.DataLength) + " of data and " + byteSize(s.IndexLength) + " of indexes when generated.\n")
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import "bytes"
+
+// dbParam is the database handle parameter of the generated data access
+// functions: a DBTX with -store, -prepared, -metrics or -tracing so they
+// also run inside transactions, through prepared statements, MetricsDB or
+// TracingDB.
+func dbParam() string {
+	if *store || *prepared || *metrics || *tracing {
+		return "db DBTX"
+	}
+	return "db *sql.DB"
+}
+
+// writeDBTX emits the DBTX interface taken by the data access functions.
+func writeDBTX(buffer *bytes.Buffer) {
+	buffer.WriteString("// DBTX is implemented by *sql.DB and *sql.Tx.\n")
+	buffer.WriteString("type DBTX interface {\n")
+	buffer.WriteString("\tExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)\n")
+	buffer.WriteString("\tQueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)\n")
+	buffer.WriteString("\tQueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row\n}")
+}
+
+// writeStore emits a Store type handing out a *sql.DB or, inside WithTx, a
+// *sql.Tx to the generated functions.
+func writeStore(buffer *bytes.Buffer) {
+
+	buffer.WriteString("// Store runs queries against a database or, inside WithTx, a transaction.\n")
+	buffer.WriteString("type Store struct {\n\tdb *sql.DB\n\ttx *sql.Tx\n}\n\n")
+
+	buffer.WriteString("func NewStore(db *sql.DB) *Store {\n\treturn &Store{db: db}\n}\n\n")
+
+	buffer.WriteString("// DB returns the handle to pass to the generated functions.\n")
+	buffer.WriteString("func (s *Store) DB() DBTX {\n")
+	buffer.WriteString("\tif s.tx != nil {\n\t\treturn s.tx\n\t}\n")
+	buffer.WriteString("\treturn s.db\n}\n\n")
+
+	buffer.WriteString("// WithTx calls fn with a Store bound to a new transaction, committing it if\n")
+	buffer.WriteString("// fn returns nil and rolling it back otherwise. Nested calls reuse the\n")
+	buffer.WriteString("// enclosing transaction.\n")
+	buffer.WriteString("func (s *Store) WithTx(ctx context.Context, fn func(*Store) error) error {\n")
+	buffer.WriteString("\tif s.tx != nil {\n\t\treturn fn(s)\n\t}\n\n")
+	buffer.WriteString("\ttx, err := s.db.BeginTx(ctx, nil)\n")
+	buffer.WriteString("\tif err != nil {\n\t\treturn err\n\t}\n")
+	buffer.WriteString("\tdefer func() {\n")
+	buffer.WriteString("\t\tif p := recover(); p != nil {\n\t\t\ttx.Rollback()\n\t\t\tpanic(p)\n\t\t}\n\t}()\n\n")
+	buffer.WriteString("\tif err := fn(&Store{db: s.db, tx: tx}); err != nil {\n")
+	buffer.WriteString("\t\ttx.Rollback()\n\t\treturn err\n\t}\n")
+	buffer.WriteString("\treturn tx.Commit()\n}")
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"strconv"
+	"strings"
+)
+
+var thriftFile = flag.String("thrift", "", "Also write a Thrift IDL file with a struct per table")
+
+// thriftType returns the Thrift type of a column: DECIMAL, text and temporal
+// columns are strings (temporal ones in RFC 3339), blobs binary.
+func thriftType(cs ColumnSchema) string {
+	switch cs.DataType {
+	case "tinyint":
+		if cs.ColumnType == "tinyint(1)" {
+			return "bool"
+		}
+		if strings.Contains(cs.ColumnType, "unsigned") {
+			return "i16"
+		}
+		return "byte"
+	case "smallint":
+		if strings.Contains(cs.ColumnType, "unsigned") {
+			return "i32"
+		}
+		return "i16"
+	case "mediumint", "year":
+		return "i32"
+	case "int":
+		if strings.Contains(cs.ColumnType, "unsigned") {
+			return "i64"
+		}
+		return "i32"
+	case "bigint":
+		return "i64"
+	case "float", "double":
+		return "double"
+	}
+	if strings.Contains(cs.DataType, "blob") || strings.Contains(cs.DataType, "binary") || isSpatial(cs) {
+		return "binary"
+	}
+	return "string"
+}
+
+// writeThrift writes a Thrift IDL file with a struct per table, named as its
+// Go struct, the fields numbered after the column positions: NOT NULL
+// columns are required fields and nullable ones optional.
+func writeThrift(path string, schemas []ColumnSchema) error {
+	buffer := bytes.NewBufferString("namespace go " + config.PkgName + "\n")
+	for _, table := range groupTables(schemas) {
+		buffer.WriteString("\nstruct " + typeName(table.Name) + " {\n")
+		for i, cs := range table.Columns {
+			if cs.ColumnComment != "" {
+				buffer.WriteString("  // " + strings.Join(strings.Fields(cs.ColumnComment), " ") + "\n")
+			}
+			requiredness := "required"
+			if cs.IsNullable == "YES" {
+				requiredness = "optional"
+			}
+			buffer.WriteString("  " + strconv.Itoa(i+1) + ": " + requiredness + " " + thriftType(cs) + " " + plainIdent(cs.ColumnName) + "\n")
+		}
+		buffer.WriteString("}\n")
+	}
+	return writeOutput(path, buffer.Bytes())
+}
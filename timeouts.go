@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// queryTimeout bounds every query on information_schema, set from the
+// query_timeout setting; zero leaves them unbounded.
+var queryTimeout time.Duration
+
+// parseTimeout parses a timeout setting such as "10s", "" meaning none.
+func parseTimeout(name, value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, configError("Invalid " + name + " " + value + ", expected a duration such as 10s")
+	}
+	return d, nil
+}
+
+// configureTimeouts applies the dial_timeout and read_timeout settings to
+// the connection and keeps query_timeout for queryContext, so that a hung
+// database or VPN fails the run instead of blocking it forever.
+func configureTimeouts(cfg *mysql.Config) error {
+	d, err := parseTimeout("dial_timeout", config.DialTimeout)
+	if err != nil {
+		return err
+	}
+	if d > 0 {
+		cfg.Timeout = d
+	}
+	if d, err = parseTimeout("read_timeout", config.ReadTimeout); err != nil {
+		return err
+	}
+	if d > 0 {
+		cfg.ReadTimeout = d
+	}
+	queryTimeout, err = parseTimeout("query_timeout", config.QueryTimeout)
+	return err
+}
+
+// queryContext returns the context to run a query in, derived from ctx and
+// cancelled after query_timeout when set.
+func queryContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if queryTimeout == 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, queryTimeout)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"os"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// tlsModes are the tls settings the driver understands by name.
+var tlsModes = map[string]bool{"": true, "false": true, "true": true, "skip-verify": true, "preferred": true}
+
+// configureTLS sets up the TLS of the connection from the tls, tls_ca,
+// tls_cert and tls_key settings. A CA or a client certificate registers a
+// custom configuration with the driver, verifying the server against the CA
+// unless tls is "skip-verify".
+func configureTLS(cfg *mysql.Config) error {
+	if !tlsModes[config.TLS] {
+		return configError("Invalid tls " + config.TLS + ", expected true, false, skip-verify or preferred")
+	}
+	if config.TLSCA == "" && config.TLSCert == "" && config.TLSKey == "" {
+		if config.TLS != "" {
+			cfg.TLSConfig = config.TLS
+		}
+		return nil
+	}
+	if config.TLS == "false" {
+		return configError("tls_ca, tls_cert and tls_key need tls enabled")
+	}
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: config.TLS == "skip-verify"}
+	if host, _, err := net.SplitHostPort(cfg.Addr); err == nil {
+		tlsConfig.ServerName = host
+	}
+	if config.TLSCA != "" {
+		pem, err := os.ReadFile(config.TLSCA)
+		if err != nil {
+			return classify(exitConfig, err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return configError("Invalid tls_ca " + config.TLSCA + ": no PEM certificate found")
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if config.TLSCert != "" || config.TLSKey != "" {
+		cert, err := tls.LoadX509KeyPair(config.TLSCert, config.TLSKey)
+		if err != nil {
+			return configError("Invalid tls_cert or tls_key: " + err.Error())
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	if err := mysql.RegisterTLSConfig("struct-create", tlsConfig); err != nil {
+		return err
+	}
+	cfg.TLSConfig = "struct-create"
+	return nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"strconv"
+)
+
+var tracing = flag.Bool("tracing", false, "Generate a TracingDB starting an OpenTelemetry span for each query of the data access functions")
+
+// writeTracing emits TracingDB, a DBTX wrapper starting a client span from
+// the context of each query run through it, named after the generated
+// function found in the context, with the db.system, db.statement and
+// db.sql.table attributes and the error of failed queries. The tracer comes
+// from the TracerProvider given, named after the package.
+func writeTracing(buffer *bytes.Buffer) {
+	buffer.WriteString("// TracingDB is a DBTX starting an OpenTelemetry span for each query of the\n")
+	buffer.WriteString("// generated functions, named after the function. Queries returning rows are\n")
+	buffer.WriteString("// traced up to their first result.\n")
+	buffer.WriteString("type TracingDB struct {\n")
+	buffer.WriteString("\tdb     DBTX\n")
+	buffer.WriteString("\ttracer trace.Tracer\n}\n\n")
+
+	buffer.WriteString("// NewTracingDB returns a TracingDB running the queries on db and tracing them\n")
+	buffer.WriteString("// with a tracer of tp, the global TracerProvider when nil.\n")
+	buffer.WriteString("func NewTracingDB(db DBTX, tp trace.TracerProvider) *TracingDB {\n")
+	buffer.WriteString("\tif tp == nil {\n\t\ttp = otel.GetTracerProvider()\n\t}\n")
+	buffer.WriteString("\treturn &TracingDB{db: db, tracer: tp.Tracer(" + strconv.Quote(config.PkgName) + ")}\n}\n\n")
+
+	buffer.WriteString("func (t *TracingDB) start(ctx context.Context, query string) (context.Context, trace.Span) {\n")
+	buffer.WriteString("\tlabels, _ := ctx.Value(queryLabelsKey{}).(queryLabels)\n")
+	buffer.WriteString("\tname := labels.query\n")
+	buffer.WriteString("\tif name == \"\" {\n\t\tname = \"query\"\n\t}\n")
+	buffer.WriteString("\tattrs := []attribute.KeyValue{\n")
+	buffer.WriteString("\t\tattribute.String(\"db.system\", \"mysql\"),\n")
+	buffer.WriteString("\t\tattribute.String(\"db.statement\", query),\n")
+	buffer.WriteString("\t}\n")
+	buffer.WriteString("\tif labels.table != \"\" {\n")
+	buffer.WriteString("\t\tattrs = append(attrs, attribute.String(\"db.sql.table\", labels.table))\n")
+	buffer.WriteString("\t}\n")
+	buffer.WriteString("\treturn t.tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))\n}\n\n")
+
+	buffer.WriteString("func endSpan(span trace.Span, err error) {\n")
+	buffer.WriteString("\tif err != nil {\n")
+	buffer.WriteString("\t\tspan.RecordError(err)\n")
+	buffer.WriteString("\t\tspan.SetStatus(codes.Error, err.Error())\n")
+	buffer.WriteString("\t}\n")
+	buffer.WriteString("\tspan.End()\n}\n\n")
+
+	buffer.WriteString("func (t *TracingDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {\n")
+	buffer.WriteString("\tctx, span := t.start(ctx, query)\n")
+	buffer.WriteString("\tres, err := t.db.ExecContext(ctx, query, args...)\n")
+	buffer.WriteString("\tendSpan(span, err)\n")
+	buffer.WriteString("\treturn res, err\n}\n\n")
+
+	buffer.WriteString("func (t *TracingDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {\n")
+	buffer.WriteString("\tctx, span := t.start(ctx, query)\n")
+	buffer.WriteString("\trows, err := t.db.QueryContext(ctx, query, args...)\n")
+	buffer.WriteString("\tendSpan(span, err)\n")
+	buffer.WriteString("\treturn rows, err\n}\n\n")
+
+	buffer.WriteString("func (t *TracingDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {\n")
+	buffer.WriteString("\tctx, span := t.start(ctx, query)\n")
+	buffer.WriteString("\trow := t.db.QueryRowContext(ctx, query, args...)\n")
+	buffer.WriteString("\tendSpan(span, row.Err())\n")
+	buffer.WriteString("\treturn row\n}")
+}
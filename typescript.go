@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	typeScript     = flag.String("typescript", "", "Also write a TypeScript file with an interface per table")
+	typeScriptKeys = flag.String("typescript-keys", "column", "Keys of the TypeScript interfaces: column names, or camel for camelCase")
+)
+
+// typeScriptIdent matches the keys TypeScript takes without quotes.
+var typeScriptIdent = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+// typeScriptType returns the TypeScript type of a column's value in JSON:
+// numbers for numeric columns, strings for text, binary (base64) and
+// temporal ones, and a union of literals for enums. Nullable columns add
+// "| null".
+func typeScriptType(cs ColumnSchema) string {
+	var ts string
+	switch cs.DataType {
+	case "enum":
+		literals := []string{}
+		for _, v := range enumValues(cs) {
+			literals = append(literals, strconv.Quote(v))
+		}
+		ts = strings.Join(literals, " | ")
+	case "varchar", "char", "text", "tinytext", "mediumtext", "longtext", "set",
+		"blob", "tinyblob", "mediumblob", "longblob", "binary", "varbinary",
+		"date", "time", "datetime", "timestamp", "year":
+		ts = "string"
+	case "tinyint", "smallint", "int", "mediumint", "bigint", "float", "double", "decimal":
+		ts = "number"
+	case "json":
+		ts = "unknown"
+	default:
+		if isSpatial(cs) {
+			ts = "string"
+		} else {
+			ts = "unknown"
+		}
+	}
+	if ts == "unknown" || cs.IsNullable != "YES" {
+		return ts
+	}
+	return ts + " | null"
+}
+
+// typeScriptKey returns the key of a column in its interface, in camelCase
+// with -typescript-keys camel.
+func typeScriptKey(column string) string {
+	if *typeScriptKeys == "camel" {
+		parts := strings.Split(strings.ToLower(column), "_")
+		for i := 1; i < len(parts); i++ {
+			if parts[i] != "" {
+				parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+			}
+		}
+		column = strings.Join(parts, "")
+	}
+	if typeScriptIdent.MatchString(column) {
+		return column
+	}
+	return strconv.Quote(column)
+}
+
+// writeTypeScript writes a TypeScript file with an interface per table,
+// named as its struct, for clients to type the JSON of the structs.
+func writeTypeScript(path string, schemas []ColumnSchema) error {
+	buffer := bytes.NewBufferString("// Code generated by struct-create from the " + config.DbName + " schema. DO NOT EDIT.\n")
+	for _, table := range groupTables(schemas) {
+		buffer.WriteString("\nexport interface " + typeName(table.Name) + " {\n")
+		for _, cs := range orderColumns(table.Columns) {
+			if cs.ColumnComment != "" {
+				buffer.WriteString("  /** " + strings.Replace(cs.ColumnComment, "*/", "* /", -1) + " */\n")
+			}
+			buffer.WriteString("  " + typeScriptKey(cs.ColumnName) + ": " + typeScriptType(cs) + ";\n")
+		}
+		buffer.WriteString("}\n")
+	}
+	return writeOutput(path, buffer.Bytes())
+}
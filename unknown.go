@@ -0,0 +1,41 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"log/slog"
+	"strconv"
+	"strings"
+)
+
+var onUnknown = flag.String("on-unknown", "error", "Handle columns without a Go type: error listing them all, skip them with a warning, or interface for interface{} fields")
+
+// checkUnknownTypes looks for the columns without a Go type before anything
+// is generated. It fails listing them all, or with -on-unknown skip drops
+// them along with the indexes and foreign keys using them; with -on-unknown
+// interface goType maps them to interface{} and there are none.
+func checkUnknownTypes(schema Schema) (Schema, error) {
+	unknown := map[string]bool{}
+	listed := []string{}
+	for _, cs := range schema.Columns {
+		if _, _, err := goType(&cs); err != nil {
+			unknown[cs.TableName+"."+cs.ColumnName] = true
+			listed = append(listed, cs.TableName+"."+cs.ColumnName+" ("+cs.ColumnType+")")
+		}
+	}
+	if len(listed) == 0 {
+		return schema, nil
+	}
+	if *onUnknown != "skip" {
+		return schema, &exitError{exitUnknownType, errors.New("No Go type for " + strconv.Itoa(len(listed)) + " columns: " +
+			strings.Join(listed, ", ") + "; map them with type_overrides or set -on-unknown to skip or interface")}
+	}
+	for _, cs := range schema.Columns {
+		if unknown[cs.TableName+"."+cs.ColumnName] {
+			slog.Warn("Skipping a column without a Go type", "table", cs.TableName, "column", cs.ColumnName, "type", cs.ColumnType)
+		}
+	}
+	return dropColumns(schema, func(tableName, columnName string) bool {
+		return unknown[tableName+"."+columnName]
+	}), nil
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+)
+
+// columnCheck is a condition a field violates and the error it gives.
+type columnCheck struct {
+	violation string
+	message   string
+}
+
+// integerBits are the sizes of the integer column types.
+var integerBits = map[string]uint{"tinyint": 8, "smallint": 16, "mediumint": 24, "int": 32, "bigint": 64}
+
+// columnChecks returns the conditions under which the field expr, of type
+// goType, doesn't fit its column: a nil pointer or interface for a NOT NULL
+// column without a default, a string or slice beyond the column length, a
+// number out of the range of its type or precision, or a value not among
+// those of an enum. Fields of types it can't inspect, read-only columns and
+// NULLs pass.
+func columnChecks(cs ColumnSchema, expr, goType string) []columnCheck {
+	if isReadOnly(cs) {
+		return nil
+	}
+	prefix := cs.TableName + ": " + cs.ColumnName
+	result := []columnCheck{}
+
+	switch {
+	case !isRequired(cs):
+	case goType == "interface{}", strings.HasPrefix(goType, "*"):
+		// values, '' or a zero time included, are always present
+		result = append(result, columnCheck{zeroCheck(expr, goType), prefix + " is required"})
+	}
+
+	value, valid, kind, ok := checkOperand(expr, goType)
+	if !ok {
+		if chars, bytes, ok := maxLength(cs); ok && chars == 0 && goType == "[]byte" {
+			result = append(result, columnCheck{"len(" + expr + ") > " + strconv.FormatInt(bytes, 10),
+				prefix + " is longer than " + strconv.FormatInt(bytes, 10) + " bytes"})
+		}
+		return result
+	}
+	guard := func(violation string) string {
+		if valid == "" {
+			return violation
+		}
+		if strings.Contains(violation, "||") {
+			violation = "(" + violation + ")"
+		}
+		return valid + " && " + violation
+	}
+	unsigned := strings.Contains(cs.ColumnType, "unsigned")
+
+	switch {
+	case kind == "string" && cs.DataType == "enum":
+		differs, quoted := []string{}, []string{}
+		for _, v := range enumValues(cs) {
+			differs = append(differs, value+" != "+strconv.Quote(v))
+			quoted = append(quoted, "'"+v+"'")
+		}
+		if len(differs) > 0 {
+			result = append(result, columnCheck{guard(strings.Join(differs, " && ")), prefix + " must be one of " + strings.Join(quoted, ", ")})
+		}
+	case kind == "string":
+		chars, bytes, ok := maxLength(cs)
+		switch {
+		case !ok:
+		case chars > 0:
+			result = append(result, columnCheck{guard("utf8.RuneCountInString(" + value + ") > " + strconv.FormatInt(chars, 10)),
+				prefix + " is longer than " + strconv.FormatInt(chars, 10) + " characters"})
+		default:
+			result = append(result, columnCheck{guard("len(" + value + ") > " + strconv.FormatInt(bytes, 10)),
+				prefix + " is longer than " + strconv.FormatInt(bytes, 10) + " bytes"})
+		}
+	case kind == "int" && integerBits[cs.DataType] > 0:
+		bits := integerBits[cs.DataType]
+		var low, high string
+		switch {
+		case unsigned && bits == 64:
+			low = "0"
+		case unsigned:
+			low, high = "0", strconv.FormatUint(1<<bits-1, 10)
+		case bits < 64:
+			low, high = strconv.FormatInt(-1<<(bits-1), 10), strconv.FormatInt(1<<(bits-1)-1, 10)
+		}
+		switch {
+		case high != "":
+			result = append(result, columnCheck{guard(value + " < " + low + " || " + value + " > " + high),
+				prefix + " is out of the " + strings.ToUpper(cs.ColumnType) + " range [" + low + ", " + high + "]"})
+		case low != "":
+			result = append(result, columnCheck{guard(value + " < 0"), prefix + " is negative in an unsigned column"})
+		}
+	case kind == "float":
+		if cs.DataType == "decimal" && cs.NumericPrecision.Valid && cs.NumericScale.Valid {
+			bound := "1e" + strconv.FormatInt(cs.NumericPrecision.Int64-cs.NumericScale.Int64, 10)
+			result = append(result, columnCheck{guard(value + " <= -" + bound + " || " + value + " >= " + bound),
+				prefix + " has too many digits for " + strings.ToUpper(cs.ColumnType)})
+		}
+		if unsigned {
+			result = append(result, columnCheck{guard(value + " < 0"), prefix + " is negative in an unsigned column"})
+		}
+	}
+	return result
+}
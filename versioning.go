@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"strings"
+)
+
+// isRowPeriod reports whether the column is the ROW START or ROW END column
+// of a MariaDB system-versioned table, maintained by the database.
+func isRowPeriod(cs ColumnSchema) bool {
+	return hasExtra(cs, "row start") || hasExtra(cs, "row end")
+}
+
+// isReadOnly reports whether the database computes the column, leaving it out
+// of every INSERT and UPDATE.
+func isReadOnly(cs ColumnSchema) bool {
+	return isGenerated(cs) || isRowPeriod(cs)
+}
+
+// getVersionedTables returns the names of the MariaDB system-versioned
+// tables.
+func getVersionedTables(ctx context.Context, conn *sql.DB, dbName string) ([]string, error) {
+	q := "SELECT TABLE_NAME FROM TABLES WHERE TABLE_SCHEMA = ? AND TABLE_TYPE = 'SYSTEM VERSIONED' ORDER BY TABLE_NAME"
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+	rows, err := conn.QueryContext(ctx, q, dbName)
+	if err != nil {
+		return nil, err
+	}
+	tables := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		tables = append(tables, name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return tables, nil
+}
+
+// isVersioned reports whether the table is system-versioned.
+func isVersioned(table Table, versioned []string) bool {
+	for _, name := range versioned {
+		if name == table.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// writeAsOf emits ListXsAsOf and, for tables with a primary key, GetXAsOf
+// functions reading a system-versioned table as it was at a point in time.
+// Soft deleted rows are skipped as in the other lookups.
+func writeAsOf(buffer *bytes.Buffer, structName string, table Table) {
+	query := "SELECT " + selectList(table.Columns) + " FROM " + tableIdent(table.Name) + " FOR SYSTEM_TIME AS OF ?"
+
+	if pk := primaryKey(table); len(pk) > 0 {
+		params, args, wheres := []string{}, []string{"asOf"}, []string{}
+		for _, cs := range pk {
+			gt, _, err := goType(&cs)
+			if err != nil {
+				return
+			}
+			param := paramName(cs)
+			params = append(params, param+" "+gt)
+			args = append(args, param)
+			wheres = append(wheres, quoteIdent(cs.ColumnName)+" = ?")
+		}
+		params = append(params, "asOf time.Time")
+		writeQueryOne(buffer, funcName("Get", structName)+"AsOf", strings.Join(params, ", "), structName, table,
+			query+" WHERE "+strings.Join(wheres, " AND ")+notDeleted(table), strings.Join(args, ", "))
+	}
+
+	if cs, ok := softDeleteColumn(table); ok {
+		query += " WHERE " + quoteIdent(cs.ColumnName) + " IS NULL"
+	}
+	writeQueryMany(buffer, funcName("List", pluralName(structName))+"AsOf", "asOf time.Time", structName, table, query, "asOf")
+}
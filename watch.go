@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"log/slog"
+	"time"
+)
+
+var watch = flag.Duration("watch", 0, "Keep running, regenerating whenever the schema changed, checked at this interval, e.g. 5s")
+
+// watchSchema runs generate, then checks the schema every -watch interval
+// and runs it again when the columns of a schema changed, until interrupted.
+// Each run starts from the settings of the config file, and failures are
+// logged without ending the watch, as the schema may be halfway through a
+// migration.
+func watchSchema(ctx context.Context, generate func() error) error {
+	if checkOutputs || *dryRun {
+		return configError("-watch can't be used with check or -dry-run")
+	}
+	base := config
+	last := ""
+	for {
+		config = base
+		fingerprint, err := watchedFingerprint(ctx)
+		switch {
+		case errors.Is(err, context.Canceled):
+			return nil
+		case err != nil:
+			slog.Error("Can't read the schema", "error", err)
+		case fingerprint != last:
+			config = base
+			if err := finishRun(generate()); err != nil {
+				slog.Error("Can't regenerate", "error", err)
+			} else {
+				last = fingerprint
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(*watch):
+		}
+	}
+}
+
+// watchedFingerprint returns a fingerprint of the columns of the schemas to
+// generate, changing with any of them.
+func watchedFingerprint(ctx context.Context) (string, error) {
+	if err := applySettings(); err != nil {
+		return "", err
+	}
+	conn, err := connect(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	names, err := schemaNames(ctx, conn)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	for _, name := range names {
+		columns, err := getColumns(ctx, conn, name)
+		if err != nil {
+			return "", err
+		}
+		h.Write([]byte(name + "\t" + schemaFingerprint(columns) + "\n"))
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}